@@ -0,0 +1,121 @@
+// Package cli implements the gosourcemapper command-line tool.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Exit codes returned by Run.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	// exitPartial is returned by scan when the graph was written but some
+	// files or linkers failed, which strict mode turns into exitFailure.
+	exitPartial = 3
+)
+
+// env carries the output streams shared by every subcommand.
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+	// cmdUsage is the usage line of the command being run.
+	cmdUsage string
+}
+
+type command struct {
+	usage   string
+	summary string
+	run     func(e *env, args []string) int
+}
+
+var commands = map[string]command{
+	"scan": {
+		usage:   "scan [flags] <dir>",
+		summary: "scan a directory, or a git commit with -ref, and print the resulting graph",
+		run:     runScan,
+	},
+	"serve": {
+		usage:   "serve [flags]",
+		summary: "run the HTTP API server",
+		run:     runServe,
+	},
+	"export": {
+		usage:   "export [flags] <dir|graph.json>",
+		summary: "write a graph as JSON, DOT, Mermaid or CSV",
+		run:     runExport,
+	},
+	"diff": {
+		usage:   "diff [flags] <from> <to>",
+		summary: "list what changed between two graphs, or two git refs with -repo",
+		run:     runDiff,
+	},
+	"query": {
+		usage:   "query [flags] <dir|graph.json>",
+		summary: "list the nodes of a graph matching the given filters",
+		run:     runQuery,
+	},
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		e.usage()
+		return exitUsage
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		e.usage()
+		return exitOK
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "gosourcemapper: unknown command %q\n\n", name)
+		e.usage()
+		return exitUsage
+	}
+	e.cmdUsage = cmd.usage
+	return cmd.run(e, args[1:])
+}
+
+func (e *env) usage() {
+	fmt.Fprintln(e.stderr, "Usage: gosourcemapper <command> [flags] [args]")
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(e.stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, `Run "gosourcemapper <command> -h" for the flags of a command.`)
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, "Exit status is 0 on success, 1 on failure and 2 on bad usage; scan exits")
+	fmt.Fprintln(e.stderr, "with 3 when it wrote the graph but some files or linkers failed.")
+}
+
+// flagSet returns a FlagSet for the named command that reports errors instead of exiting.
+func (e *env) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: gosourcemapper %s\n\n", e.cmdUsage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// fail prints err prefixed with the command name and returns code.
+func (e *env) fail(name string, code int, err error) int {
+	fmt.Fprintf(e.stderr, "gosourcemapper %s: %v\n", name, err)
+	return code
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+)
+
+func runDiff(e *env, args []string) int {
+	fs := e.flagSet("diff")
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	output := fs.String("o", "", "write the diff to this file instead of stdout")
+	repo := fs.String("repo", "", "compare two branches, tags or commits of the git repository at this path")
+
+	cfg, err := config.LoadOffline(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return e.fail("diff", exitUsage, err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	var write func(io.Writer, *service.GraphDiff) error
+	switch strings.ToLower(*format) {
+	case "markdown", "md":
+		write = func(w io.Writer, d *service.GraphDiff) error { return d.WriteMarkdown(w) }
+	case "json":
+		write = func(w io.Writer, d *service.GraphDiff) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(d)
+		}
+	default:
+		return e.fail("diff", exitUsage, fmt.Errorf("unknown format %q", *format))
+	}
+
+	// Each side is a graph JSON file or a directory, or a ref with -repo
+	var docs [2]*graphDocument
+	for i, target := range fs.Args() {
+		if *repo != "" {
+			docs[i], err = scanGraph(cfg, *repo, target)
+		} else {
+			docs[i], err = loadGraph(cfg, target)
+		}
+		if err != nil {
+			return e.fail("diff", exitFailure, err)
+		}
+	}
+	diff := service.DiffGraphs(
+		&models.Graph{Nodes: docs[0].Nodes, Edges: docs[0].Edges},
+		&models.Graph{Nodes: docs[1].Nodes, Edges: docs[1].Edges},
+	)
+	diff.From, diff.To = fs.Arg(0), fs.Arg(1)
+	diff.FromCommit, diff.ToCommit = docs[0].Commit, docs[1].Commit
+
+	err = withOutput(e.stdout, *output, func(w io.Writer) error { return write(w, diff) })
+	if err != nil {
+		return e.fail("diff", exitFailure, err)
+	}
+	return exitOK
+}
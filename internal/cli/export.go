@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"io"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+)
+
+func runExport(e *env, args []string) int {
+	fs := e.flagSet("export")
+	format := fs.String("format", "dot", "output format: json, csv, dot or mermaid")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	groupBy := fs.String("group-by", "", "cluster nodes by service, module, file, language or type")
+
+	cfg, err := config.LoadOffline(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return e.fail("export", exitUsage, err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	write, err := formatWriter(*format)
+	if err != nil {
+		return e.fail("export", exitUsage, err)
+	}
+
+	doc, err := loadGraph(cfg, fs.Arg(0))
+	if err != nil {
+		return e.fail("export", exitFailure, err)
+	}
+	doc.report(e.stderr)
+	if err := doc.groupBy(*groupBy); err != nil {
+		return e.fail("export", exitUsage, err)
+	}
+
+	err = withOutput(e.stdout, *output, func(w io.Writer) error { return write(w, doc) })
+	if err != nil {
+		return e.fail("export", exitFailure, err)
+	}
+	return exitOK
+}
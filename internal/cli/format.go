@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// graphFormats lists the writers available to the scan, export and query commands.
+var graphFormats = map[string]func(io.Writer, *graphDocument) error{
+	"json":    writeJSON,
+	"text":    writeText,
+	"csv":     writeCSV,
+	"dot":     writeDOT,
+	"mermaid": writeMermaid,
+}
+
+func formatWriter(format string) (func(io.Writer, *graphDocument) error, error) {
+	write, ok := graphFormats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return write, nil
+}
+
+func writeJSON(w io.Writer, doc *graphDocument) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func writeText(w io.Writer, doc *graphDocument) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if doc.group != nil {
+		fmt.Fprint(tw, "GROUP\t")
+	}
+	fmt.Fprintln(tw, "TYPE\tNAME\tLANGUAGE\tLOCATION")
+	for _, n := range doc.Nodes {
+		if doc.group != nil {
+			fmt.Fprintf(tw, "%s\t", doc.group(n))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s:%d\n", n.Type, n.Name, n.Language, n.FilePath, n.LineNumber)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d nodes, %d edges\n", doc.Count, len(doc.Edges))
+	return err
+}
+
+func writeCSV(w io.Writer, doc *graphDocument) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "type", "name", "language", "file_path", "line_number", "signature"})
+	for _, n := range doc.Nodes {
+		cw.Write([]string{n.ID, string(n.Type), n.Name, n.Language, n.FilePath, strconv.Itoa(n.LineNumber), n.Signature})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeDOT renders the graph for Graphviz, clustering nodes by file or by
+// the group the document was arranged in.
+func writeDOT(w io.Writer, doc *graphDocument) error {
+	var b strings.Builder
+	b.WriteString("digraph gosourcemapper {\n")
+	b.WriteString("  rankdir=LR;\n  node [shape=box, fontname=\"Helvetica\"];\n")
+
+	cluster := 0
+	for i := 0; i < len(doc.Nodes); {
+		group := doc.groupOf(doc.Nodes[i])
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n    label=%s;\n", cluster, strconv.Quote(group))
+		for ; i < len(doc.Nodes) && doc.groupOf(doc.Nodes[i]) == group; i++ {
+			n := doc.Nodes[i]
+			fmt.Fprintf(&b, "    %s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(fmt.Sprintf("%s\n%s", n.Type, n.Name)))
+		}
+		b.WriteString("  }\n")
+		cluster++
+	}
+	for _, e := range doc.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", strconv.Quote(e.Source), strconv.Quote(e.Target), strconv.Quote(string(e.Kind)))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart.
+func writeMermaid(w io.Writer, doc *graphDocument) error {
+	ids := make(map[string]string, len(doc.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	indent := "  "
+	for i, n := range doc.Nodes {
+		// Grouped documents are sorted by group, so each group is one subgraph.
+		if doc.group != nil && (i == 0 || doc.group(doc.Nodes[i-1]) != doc.group(n)) {
+			if i > 0 {
+				b.WriteString("  end\n")
+			}
+			fmt.Fprintf(&b, "  subgraph g%d[\"%s\"]\n", i, strings.ReplaceAll(doc.group(n), `"`, "#quot;"))
+			indent = "    "
+		}
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		label := strings.ReplaceAll(fmt.Sprintf("%s %s", n.Type, n.Name), `"`, "#quot;")
+		fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, ids[n.ID], label)
+	}
+	if doc.group != nil && len(doc.Nodes) > 0 {
+		b.WriteString("  end\n")
+	}
+	for _, e := range doc.Edges {
+		source, ok := ids[e.Source]
+		target, ok2 := ids[e.Target]
+		if ok && ok2 {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", source, e.Kind, target)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
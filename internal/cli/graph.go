@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+)
+
+// graphDocument is the serialised form of a scanned graph. It matches the
+// body returned by POST /v1/scan/dir, so API responses can be fed back to the CLI.
+type graphDocument struct {
+	Nodes []*models.CodeNode `json:"nodes"`
+	Edges []*models.Edge     `json:"edges"`
+	Count int                `json:"count"`
+
+	// The account of a scan, set when the graph was scanned from a directory.
+	FilesScanned     int                 `json:"files_scanned,omitempty"`
+	FilesFailed      int                 `json:"files_failed,omitempty"`
+	FilesSkipped     int                 `json:"files_skipped,omitempty"`
+	FilesUnsupported int                 `json:"files_unsupported,omitempty"`
+	Errors           []service.ScanError `json:"errors,omitempty"`
+	Commit           string              `json:"commit,omitempty"` // scanned commit of a git repository
+	scanned          bool
+
+	// group, when set, clusters nodes in the text, dot and mermaid output.
+	group func(*models.CodeNode) string
+}
+
+func newGraphDocument(graph *models.Graph) *graphDocument {
+	sortNodes(graph.Nodes)
+	models.SortEdges(graph.Edges)
+	return &graphDocument{Nodes: graph.Nodes, Edges: graph.Edges, Count: len(graph.Nodes)}
+}
+
+// groupBy clusters the nodes by service, module, file, language or type.
+// An empty attribute keeps the default layout.
+func (doc *graphDocument) groupBy(by string) error {
+	if by == "" {
+		return nil
+	}
+	key, err := service.GroupKey(by)
+	if err != nil {
+		return err
+	}
+	doc.group = key
+	sort.SliceStable(doc.Nodes, func(i, j int) bool { return key(doc.Nodes[i]) < key(doc.Nodes[j]) })
+	return nil
+}
+
+// groupOf returns the cluster of n: its group when grouping, its file otherwise.
+func (doc *graphDocument) groupOf(n *models.CodeNode) string {
+	if doc.group != nil {
+		return doc.group(n)
+	}
+	return n.FilePath
+}
+
+// loadGraph scans target if it is a directory and decodes it as a graph JSON
+// file otherwise.
+func loadGraph(cfg *config.Config, target string) (*graphDocument, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return scanGraph(cfg, target, "")
+	}
+
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var doc graphDocument
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode graph %s: %w", target, err)
+	}
+	return newGraphDocument(&models.Graph{Nodes: doc.Nodes, Edges: doc.Edges}), nil
+}
+
+// scanGraph runs the scan service over dir, or over the commit named by ref
+// of the git repository at dir when ref is set, with a throwaway in-memory
+// repository. The error of a failed strict scan lists the files at fault.
+func scanGraph(cfg *config.Config, dir, ref string) (*graphDocument, error) {
+	svc := service.NewScanService(repository.NewInMemoryGraphRepository(), cfg.Scan)
+	opts := service.ScanOptions{Strict: cfg.Scan.Strict}
+	var result *service.ScanResult
+	var err error
+	if ref != "" {
+		result, err = svc.ScanGit(context.Background(), dir, ref, opts, nil)
+	} else {
+		result, err = svc.ScanDirectory(context.Background(), dir, opts, nil)
+	}
+	if err != nil {
+		if result != nil {
+			for _, e := range result.Errors {
+				err = fmt.Errorf("%w\n  %s", err, e)
+			}
+		}
+		return nil, err
+	}
+	doc := newGraphDocument(&models.Graph{Nodes: result.Nodes, Edges: result.Edges})
+	doc.FilesScanned, doc.FilesFailed = result.FilesScanned, result.FilesFailed
+	doc.FilesSkipped, doc.FilesUnsupported = result.FilesSkipped, result.FilesUnsupported
+	doc.Errors, doc.Commit, doc.scanned = result.Errors, result.Commit, true
+	return doc, nil
+}
+
+// report writes the account of the scan that produced doc to w, one line for
+// the file counts and one per error.
+func (doc *graphDocument) report(w io.Writer) {
+	if !doc.scanned {
+		return
+	}
+	fmt.Fprintf(w, "scanned %d files: %d failed, %d skipped, %d unsupported\n",
+		doc.FilesScanned, doc.FilesFailed, doc.FilesSkipped, doc.FilesUnsupported)
+	for _, e := range doc.Errors {
+		fmt.Fprintf(w, "  %s: %s\n", e.Kind, e)
+	}
+}
+
+// sortNodes orders nodes by location so output is stable between runs.
+func sortNodes(nodes []*models.CodeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.LineNumber != b.LineNumber {
+			return a.LineNumber < b.LineNumber
+		}
+		return a.Name < b.Name
+	})
+}
+
+// withOutput calls write with stdout, or with the file at path when one is given.
+func withOutput(stdout io.Writer, path string, write func(io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
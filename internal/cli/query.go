@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+)
+
+func runQuery(e *env, args []string) int {
+	fs := e.flagSet("query")
+	var filter service.NodeFilter
+	fs.StringVar(&filter.Type, "type", "", "only nodes of this type (e.g. FUNCTION, HTTP_CALL)")
+	fs.StringVar(&filter.Language, "language", "", "only nodes of this language (go, java, python)")
+	fs.StringVar(&filter.Name, "name", "", "only nodes whose name contains this text")
+	fs.StringVar(&filter.File, "file", "", "only nodes whose file path contains this text")
+	fs.StringVar(&filter.ParamType, "param-type", "", "only functions with a parameter whose type contains this text")
+	fs.StringVar(&filter.Service, "service", "", "only nodes of this service (e.g. order-service)")
+	fs.StringVar(&filter.Module, "module", "", "only nodes of this module path")
+	route := fs.String("route", "", `only routes serving this request and their handlers (e.g. "GET /v1/orders/42")`)
+	format := fs.String("format", "text", "output format: text, json or csv")
+	output := fs.String("o", "", "write results to this file instead of stdout")
+	groupBy := fs.String("group-by", "", "cluster results by service, module, file, language or type")
+
+	cfg, err := config.LoadOffline(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return e.fail("query", exitUsage, err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	write, err := formatWriter(*format)
+	if err != nil {
+		return e.fail("query", exitUsage, err)
+	}
+
+	doc, err := loadGraph(cfg, fs.Arg(0))
+	if err != nil {
+		return e.fail("query", exitFailure, err)
+	}
+	doc.report(e.stderr)
+
+	nodes := doc.Nodes
+	if *route != "" {
+		nodes = routeNodes(doc, *route)
+	}
+
+	// Keep the matching nodes and the edges between them.
+	matched := &models.Graph{}
+	ids := make(map[string]bool)
+	for _, n := range nodes {
+		if filter.Match(n) {
+			matched.Nodes = append(matched.Nodes, n)
+			ids[n.ID] = true
+		}
+	}
+	for _, edge := range doc.Edges {
+		if ids[edge.Source] && ids[edge.Target] {
+			matched.Edges = append(matched.Edges, edge)
+		}
+	}
+
+	result := newGraphDocument(matched)
+	if err := result.groupBy(*groupBy); err != nil {
+		return e.fail("query", exitUsage, err)
+	}
+	err = withOutput(e.stdout, *output, func(w io.Writer) error { return write(w, result) })
+	if err != nil {
+		return e.fail("query", exitFailure, err)
+	}
+	return exitOK
+}
+
+// routeNodes returns the routes serving a request given as "METHOD /path" or
+// "/path", followed by their handlers.
+func routeNodes(doc *graphDocument, request string) []*models.CodeNode {
+	method, path, found := strings.Cut(strings.TrimSpace(request), " ")
+	if !found {
+		method, path = "", method
+	}
+	var nodes []*models.CodeNode
+	for _, m := range service.MatchRoutes(&models.Graph{Nodes: doc.Nodes, Edges: doc.Edges}, method, strings.TrimSpace(path)) {
+		nodes = append(nodes, m.Route)
+		nodes = append(nodes, m.Handlers...)
+	}
+	return nodes
+}
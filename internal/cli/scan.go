@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+)
+
+func runScan(e *env, args []string) int {
+	fs := e.flagSet("scan")
+	format := fs.String("format", "json", "output format: json, text, csv, dot or mermaid")
+	output := fs.String("o", "", "write the graph to this file instead of stdout")
+	ref := fs.String("ref", "", "scan this branch, tag or commit of the git repository at <dir>, without checking it out")
+
+	cfg, err := config.LoadOffline(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return e.fail("scan", exitUsage, err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	write, err := formatWriter(*format)
+	if err != nil {
+		return e.fail("scan", exitUsage, err)
+	}
+
+	doc, err := scanGraph(cfg, fs.Arg(0), *ref)
+	if err != nil {
+		return e.fail("scan", exitFailure, err)
+	}
+	doc.report(e.stderr)
+
+	err = withOutput(e.stdout, *output, func(w io.Writer) error { return write(w, doc) })
+	if err != nil {
+		return e.fail("scan", exitFailure, err)
+	}
+	if *output != "" {
+		fmt.Fprintf(e.stderr, "wrote %d nodes to %s\n", doc.Count, *output)
+	}
+	if len(doc.Errors) > 0 {
+		return exitPartial
+	}
+	return exitOK
+}
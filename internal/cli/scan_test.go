@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanExitCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		flags []string
+		after []string // flags after the directory
+		want  int
+	}{
+		{"clean", map[string]string{"y.go": "package x\n\nfunc Y() {}\n"}, nil, nil, exitOK},
+		{"failed file", map[string]string{"x.go": "package x\n\nfunc (\n", "y.go": "package x\n\nfunc Y() {}\n"}, nil, nil, exitPartial},
+		{"failed file, strict", map[string]string{"x.go": "package x\n\nfunc (\n"}, []string{"-scan-strict", "true"}, nil, exitFailure},
+		{"failed file, strict after the directory", map[string]string{"x.go": "package x\n\nfunc (\n"}, nil, []string{"-scan-strict=true"}, exitFailure},
+		{"format after the directory", map[string]string{"y.go": "package x\n\nfunc Y() {}\n"}, nil, []string{"--format", "json"}, exitOK},
+		{"server flag", map[string]string{"y.go": "package x\n\nfunc Y() {}\n"}, []string{"-storage", "bolt"}, nil, exitUsage},
+		{"missing directory", nil, nil, nil, exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.files == nil {
+				dir = filepath.Join(dir, "missing")
+			}
+			args := append([]string{"scan", "-o", filepath.Join(t.TempDir(), "graph.json")}, tt.flags...)
+			args = append(append(args, dir), tt.after...)
+			if got := Run(args, io.Discard, io.Discard); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/server"
+)
+
+func runServe(e *env, args []string) int {
+	fs := e.flagSet("serve")
+	cfg, err := config.Load(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return e.fail("serve", exitUsage, err)
+	}
+	server.ConfigureLogging(cfg.Log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Run(ctx, cfg); err != nil {
+		return e.fail("serve", exitFailure, err)
+	}
+	return exitOK
+}
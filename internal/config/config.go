@@ -0,0 +1,392 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to every environment variable read by Load.
+const EnvPrefix = "GOSOURCEMAPPER_"
+
+// Config holds the server configuration.
+type Config struct {
+	Server  ServerConfig  `yaml:"server" toml:"server"`
+	Scan    ScanConfig    `yaml:"scan" toml:"scan"`
+	Log     LogConfig     `yaml:"log" toml:"log"`
+	Storage StorageConfig `yaml:"storage" toml:"storage"`
+	Jobs    JobsConfig    `yaml:"jobs" toml:"jobs"`
+	Watch   WatchConfig   `yaml:"watch" toml:"watch"`
+}
+
+// ServerConfig controls the HTTP listener.
+type ServerConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+}
+
+// ScanConfig controls where and how much the scan service may read.
+type ScanConfig struct {
+	// TempDir is where uploaded archives are stored and extracted.
+	TempDir string `yaml:"temp_dir" toml:"temp_dir"`
+	// AllowedRoots restricts directory scans to these trees. Empty allows any path.
+	AllowedRoots []string `yaml:"allowed_roots" toml:"allowed_roots"`
+	// MaxUploadSize caps the size of an uploaded zip archive.
+	MaxUploadSize ByteSize `yaml:"max_upload_size" toml:"max_upload_size"`
+	// MaxFileSize caps the size of a single source file; larger files are skipped.
+	MaxFileSize ByteSize `yaml:"max_file_size" toml:"max_file_size"`
+	// Concurrency is how many files of a directory are parsed at once. Zero
+	// uses one worker per CPU (GOMAXPROCS).
+	Concurrency int `yaml:"concurrency" toml:"concurrency"`
+	// Strict fails a scan when any file cannot be scanned, instead of skipping
+	// the file. The API's ?strict= parameter overrides it per request.
+	Strict bool `yaml:"strict" toml:"strict"`
+}
+
+// Storage drivers.
+const (
+	StorageMemory = "memory"
+	StorageBolt   = "bolt"
+)
+
+// StorageConfig selects where the code graph is kept.
+type StorageConfig struct {
+	// Driver is "memory" (lost on restart) or "bolt" (persisted to Path).
+	Driver string `yaml:"driver" toml:"driver"`
+	// Path is the database file used by the bolt driver.
+	Path string `yaml:"path" toml:"path"`
+	// CompactOnStart rewrites the database file on startup to reclaim free space.
+	CompactOnStart bool `yaml:"compact_on_start" toml:"compact_on_start"`
+}
+
+// JobsConfig controls background scan jobs.
+type JobsConfig struct {
+	// Workers is how many jobs run at once.
+	Workers int `yaml:"workers" toml:"workers"`
+	// QueueSize is how many jobs may wait for a worker; more are rejected.
+	QueueSize int `yaml:"queue_size" toml:"queue_size"`
+}
+
+// WatchConfig controls watch mode, which keeps the graph of a directory in
+// step with the files on disk while the server runs.
+type WatchConfig struct {
+	// Dir is the directory to watch; empty turns watch mode off.
+	Dir string `yaml:"dir" toml:"dir"`
+	// Project receives the graph of Dir; it is created when missing. Empty
+	// derives the project from the name of Dir.
+	Project string `yaml:"project" toml:"project"`
+	// Debounce is how long the files must be left alone before the changes
+	// made to them are rescanned together.
+	Debounce Duration `yaml:"debounce" toml:"debounce"`
+}
+
+// LogConfig controls application logging.
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr: ":8080",
+		},
+		Scan: ScanConfig{
+			TempDir:       ".temp",
+			MaxUploadSize: 100 * MB,
+			MaxFileSize:   2 * MB,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Storage: StorageConfig{
+			Driver: StorageMemory,
+			Path:   "data/graph.db",
+		},
+		Jobs: JobsConfig{
+			Workers:   2,
+			QueueSize: 16,
+		},
+		Watch: WatchConfig{
+			Debounce: Duration(300 * time.Millisecond),
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional config file,
+// environment variables and command-line flags, in increasing order of precedence.
+//
+// The config flags are registered on fs, so callers may add their own flags
+// beforehand and read fs.Args() afterwards. Flags may follow the positional
+// arguments, as in "scan ./services -format json".
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	return load(fs, args, true)
+}
+
+// LoadOffline is Load for the commands that run no server: the flags of the
+// listener, uploads, storage, jobs and watch mode are left out, as nothing
+// would read them. The file and the environment may still set them.
+func LoadOffline(fs *flag.FlagSet, args []string) (*Config, error) {
+	return load(fs, args, false)
+}
+
+func load(fs *flag.FlagSet, args []string, server bool) (*Config, error) {
+	cfg := Default()
+	configPath := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "path to a YAML or TOML config file")
+	flagValues := make(map[string]*string)
+	for _, o := range cfg.options() {
+		if server || !o.server {
+			flagValues[o.flag] = fs.String(o.flag, "", o.usage)
+		}
+	}
+	if err := fs.Parse(permute(fs, args)); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	apply := func(source, value string, set func(string) error) {
+		if value == "" {
+			return
+		}
+		if err := set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+
+	// Environment variables override the file...
+	for _, o := range cfg.options() {
+		apply(EnvPrefix+o.env, os.Getenv(EnvPrefix+o.env), o.set)
+	}
+
+	// ...and explicitly passed flags override both.
+	for _, o := range cfg.options() {
+		if value, ok := flagValues[o.flag]; ok {
+			apply("-"+o.flag, *value, o.set)
+		}
+	}
+
+	errs = append(errs, cfg.absPaths()...)
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// permute moves the flags of args, with their values, before the positional
+// arguments, which the flag package expects first. "--" still ends the flags.
+func permute(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case len(arg) > 1 && arg[0] == '-':
+			flags = append(flags, arg)
+			name := strings.TrimLeft(arg, "-")
+			if strings.Contains(name, "=") {
+				continue
+			}
+			f := fs.Lookup(name)
+			if f == nil {
+				continue
+			}
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				continue
+			}
+			if i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return append(append(flags, "--"), positional...)
+}
+
+// option binds a single setting to its environment variable and flag names.
+type option struct {
+	env   string
+	flag  string
+	usage string
+	// server marks the settings only the server reads.
+	server bool
+	set    func(string) error
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{env: "ADDR", flag: "addr", usage: "HTTP listen address (e.g. :8080)", server: true,
+			set: func(v string) error { c.Server.Addr = v; return nil }},
+		{env: "TEMP_DIR", flag: "temp-dir", usage: "directory for uploaded archives", server: true,
+			set: func(v string) error { c.Scan.TempDir = v; return nil }},
+		{env: "ALLOWED_ROOTS", flag: "allowed-roots", usage: "comma-separated list of directories that may be scanned",
+			set: func(v string) error { c.Scan.AllowedRoots = splitList(v); return nil }},
+		{env: "MAX_UPLOAD_SIZE", flag: "max-upload-size", usage: "maximum size of an uploaded archive (e.g. 100MB)", server: true,
+			set: c.Scan.MaxUploadSize.Set},
+		{env: "MAX_FILE_SIZE", flag: "max-file-size", usage: "maximum size of a scanned source file (e.g. 2MB)",
+			set: c.Scan.MaxFileSize.Set},
+		{env: "SCAN_CONCURRENCY", flag: "scan-concurrency", usage: "number of files parsed at once (0 for one per CPU)",
+			set: intSetter(&c.Scan.Concurrency)},
+		{env: "SCAN_STRICT", flag: "scan-strict", usage: "fail a scan when any file cannot be scanned (true or false)",
+			set: boolSetter(&c.Scan.Strict)},
+		{env: "LOG_LEVEL", flag: "log-level", usage: "log level: debug, info, warn or error",
+			set: func(v string) error { c.Log.Level = v; return nil }},
+		{env: "STORAGE_DRIVER", flag: "storage", usage: "graph storage driver: memory or bolt", server: true,
+			set: func(v string) error { c.Storage.Driver = v; return nil }},
+		{env: "STORAGE_PATH", flag: "storage-path", usage: "database file of the bolt storage driver", server: true,
+			set: func(v string) error { c.Storage.Path = v; return nil }},
+		{env: "STORAGE_COMPACT_ON_START", flag: "storage-compact-on-start", usage: "compact the graph database on startup (true or false)", server: true,
+			set: boolSetter(&c.Storage.CompactOnStart)},
+		{env: "JOB_WORKERS", flag: "job-workers", usage: "number of scan jobs run at once", server: true,
+			set: intSetter(&c.Jobs.Workers)},
+		{env: "JOB_QUEUE_SIZE", flag: "job-queue-size", usage: "number of scan jobs that may wait for a worker", server: true,
+			set: intSetter(&c.Jobs.QueueSize)},
+		{env: "WATCH_DIR", flag: "watch", usage: "directory whose graph is kept current as its files change", server: true,
+			set: func(v string) error { c.Watch.Dir = v; return nil }},
+		{env: "WATCH_PROJECT", flag: "watch-project", usage: "project receiving the graph of the watched directory", server: true,
+			set: func(v string) error { c.Watch.Project = v; return nil }},
+		{env: "WATCH_DEBOUNCE", flag: "watch-debounce", usage: "quiet time before changed files are rescanned (e.g. 300ms)", server: true,
+			set: c.Watch.Debounce.Set},
+	}
+}
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr %q: %w", c.Server.Addr, err))
+	}
+	if strings.TrimSpace(c.Scan.TempDir) == "" {
+		errs = append(errs, errors.New("scan.temp_dir must not be empty"))
+	}
+	for i, root := range c.Scan.AllowedRoots {
+		if err := checkDir(root); err != nil {
+			errs = append(errs, fmt.Errorf("scan.allowed_roots[%d]: %w", i, err))
+		}
+	}
+	if c.Scan.MaxUploadSize <= 0 {
+		errs = append(errs, errors.New("scan.max_upload_size must be positive"))
+	}
+	if c.Scan.MaxFileSize <= 0 {
+		errs = append(errs, errors.New("scan.max_file_size must be positive"))
+	}
+	if c.Scan.Concurrency < 0 {
+		errs = append(errs, errors.New("scan.concurrency must not be negative"))
+	}
+	if _, err := ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.Jobs.Workers <= 0 {
+		errs = append(errs, errors.New("jobs.workers must be positive"))
+	}
+	if c.Jobs.QueueSize < 0 {
+		errs = append(errs, errors.New("jobs.queue_size must not be negative"))
+	}
+	if c.Watch.Dir != "" {
+		if err := checkDir(c.Watch.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("watch.dir: %w", err))
+		}
+	}
+	if c.Watch.Debounce <= 0 {
+		errs = append(errs, errors.New("watch.debounce must be positive"))
+	}
+	switch c.Storage.Driver {
+	case StorageMemory:
+	case StorageBolt:
+		if strings.TrimSpace(c.Storage.Path) == "" {
+			errs = append(errs, errors.New("storage.path must not be empty for the bolt driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver %q: want %q or %q", c.Storage.Driver, StorageMemory, StorageBolt))
+	}
+	return errs
+}
+
+// absPaths makes the configured directories absolute, so that they keep
+// naming the same directories whatever the working directory.
+func (c *Config) absPaths() []error {
+	var errs []error
+	for i, root := range c.Scan.AllowedRoots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scan.allowed_roots[%d] %q: %w", i, root, err))
+			continue
+		}
+		c.Scan.AllowedRoots[i] = abs
+	}
+	if c.Watch.Dir != "" {
+		abs, err := filepath.Abs(c.Watch.Dir)
+		if err != nil {
+			return append(errs, fmt.Errorf("watch.dir %q: %w", c.Watch.Dir, err))
+		}
+		c.Watch.Dir = abs
+	}
+	return errs
+}
+
+// checkDir reports an error unless path is an existing directory.
+func checkDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", path)
+	}
+	return nil
+}
+
+func intSetter(dst *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func boolSetter(dst *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*dst = b
+		return nil
+	}
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
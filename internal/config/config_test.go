@@ -0,0 +1,283 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// writeFile writes content to name in a new temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", "server:\n  addr: \":1000\"\nscan:\n  max_file_size: 1MB\n  concurrency: 1\n")
+	tests := []struct {
+		name        string
+		file        bool
+		env         map[string]string
+		args        []string
+		addr        string
+		maxFileSize ByteSize
+		concurrency int
+	}{
+		{name: "defaults", addr: ":8080", maxFileSize: 2 * MB},
+		{name: "file", file: true, addr: ":1000", maxFileSize: MB, concurrency: 1},
+		{
+			name: "environment over file",
+			file: true,
+			env:  map[string]string{"ADDR": ":2000", "MAX_FILE_SIZE": "3MB"},
+			addr: ":2000", maxFileSize: 3 * MB, concurrency: 1,
+		},
+		{
+			name: "flags over environment",
+			file: true,
+			env:  map[string]string{"ADDR": ":2000", "MAX_FILE_SIZE": "3MB"},
+			args: []string{"-addr", ":3000", "-scan-concurrency=4"},
+			addr: ":3000", maxFileSize: 3 * MB, concurrency: 4,
+		},
+		{
+			name: "flags without a file",
+			env:  map[string]string{"SCAN_CONCURRENCY": "2"},
+			args: []string{"-max-file-size", "512KB"},
+			addr: ":8080", maxFileSize: 512 * KB, concurrency: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file {
+				t.Setenv(EnvPrefix+"CONFIG", file)
+			}
+			for name, value := range tt.env {
+				t.Setenv(EnvPrefix+name, value)
+			}
+			cfg, err := Load(newFlagSet(), tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Addr != tt.addr || cfg.Scan.MaxFileSize != tt.maxFileSize || cfg.Scan.Concurrency != tt.concurrency {
+				t.Errorf("addr, max file size, concurrency = %q, %s, %d, want %q, %s, %d",
+					cfg.Server.Addr, cfg.Scan.MaxFileSize, cfg.Scan.Concurrency, tt.addr, tt.maxFileSize, tt.concurrency)
+			}
+		})
+	}
+}
+
+func TestLoadArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		offline bool
+		args    []string
+		want    []string // positional arguments left
+		wantErr bool
+	}{
+		{name: "flags first", args: []string{"-log-level", "debug", "dir"}, want: []string{"dir"}},
+		{name: "flags after positionals", args: []string{"dir", "-log-level", "debug", "other"}, want: []string{"dir", "other"}},
+		{name: "flag with value", args: []string{"dir", "--log-level=debug"}, want: []string{"dir"}},
+		{name: "end of flags", args: []string{"-log-level", "debug", "--", "-dir"}, want: []string{"-dir"}},
+		{name: "stdin", args: []string{"-", "-log-level", "debug"}, want: []string{"-"}},
+		{name: "unknown flag", args: []string{"dir", "-verbose"}, wantErr: true},
+		{name: "server flag", args: []string{"-addr", ":9000"}, want: []string{}},
+		{name: "server flag offline", offline: true, args: []string{"dir", "-addr", ":9000"}, wantErr: true},
+		{name: "storage flag offline", offline: true, args: []string{"-storage", "bolt", "dir"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := Load
+			if tt.offline {
+				load = LoadOffline
+			}
+			fs := newFlagSet()
+			cfg, err := load(fs, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fs.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %q, want %q", got, tt.want)
+			}
+			if len(tt.args) > 2 && cfg.Log.Level != "debug" {
+				t.Errorf("log level = %q, want debug", cfg.Log.Level)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"yaml", "c.yaml", "scan:\n  max_upload_size: 1GB\nwatch:\n  debounce: 2s\n", ""},
+		{"yml", "c.yml", "log:\n  level: warn\n", ""},
+		{"toml", "c.toml", "[scan]\nmax_upload_size = \"1GB\"\n[watch]\ndebounce = \"2s\"\n", ""},
+		{"unknown yaml section", "c.yaml", "scanner:\n  concurrency: 2\n", "scanner"},
+		{"unknown yaml key", "c.yaml", "scan:\n  concurency: 2\n", "concurency"},
+		{"unknown toml key", "c.toml", "[scan]\nconcurency = 2\n", "concurency"},
+		{"bad size", "c.yaml", "scan:\n  max_upload_size: lots\n", "lots"},
+		{"unsupported format", "c.json", "{}", "unsupported config file format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			err := cfg.loadFile(writeFile(t, tt.file, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadFile error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.file != "c.yml" && (cfg.Scan.MaxUploadSize != GB || time.Duration(cfg.Watch.Debounce) != 2*time.Second) {
+				t.Errorf("max upload size, debounce = %s, %s, want 1GB, 2s", cfg.Scan.MaxUploadSize, cfg.Watch.Debounce)
+			}
+			if cfg.Scan.MaxFileSize != 2*MB {
+				t.Errorf("max file size = %s, want the default 2MB", cfg.Scan.MaxFileSize)
+			}
+		})
+	}
+
+	if _, err := Load(newFlagSet(), []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("Load of a missing config file succeeded")
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ByteSize
+		str  string
+	}{
+		{"0", 0, "0"},
+		{"100", 100, "100B"},
+		{"512KB", 512 * KB, "512KB"},
+		{" 10 mb ", 10 * MB, "10MB"},
+		{"1536KB", 1536 * KB, "1536KB"},
+		{"2GB", 2 * GB, "2GB"},
+		{"1025B", 1025, "1025B"},
+	}
+	for _, tt := range tests {
+		var b ByteSize
+		if err := b.Set(tt.in); err != nil {
+			t.Errorf("Set(%q): %v", tt.in, err)
+			continue
+		}
+		if b != tt.want || b.String() != tt.str {
+			t.Errorf("Set(%q) = %d (%s), want %d (%s)", tt.in, b, b, tt.want, tt.str)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1KB", "1.5MB", "10TB", "ten"} {
+		var b ByteSize
+		if err := b.Set(in); err == nil {
+			t.Errorf("Set(%q) = %s, want an error", in, b)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var d Duration
+	if err := d.Set("1m30s"); err != nil || time.Duration(d) != 90*time.Second || d.String() != "1m30s" {
+		t.Errorf("Set(1m30s) = %s, %v", d, err)
+	}
+	for _, in := range []string{"", "300", "soon"} {
+		if err := d.Set(in); err == nil {
+			t.Errorf("Set(%q) = %s, want an error", in, d)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	file := writeFile(t, "file", "")
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{"server.addr", func(c *Config) { c.Server.Addr = "8080" }, "server.addr"},
+		{"scan.temp_dir", func(c *Config) { c.Scan.TempDir = " " }, "scan.temp_dir"},
+		{"missing allowed root", func(c *Config) { c.Scan.AllowedRoots = []string{"/no/such/dir"} }, "scan.allowed_roots[0]"},
+		{"allowed root not a directory", func(c *Config) { c.Scan.AllowedRoots = []string{t.TempDir(), file} }, "scan.allowed_roots[1]"},
+		{"scan.max_upload_size", func(c *Config) { c.Scan.MaxUploadSize = 0 }, "scan.max_upload_size"},
+		{"scan.max_file_size", func(c *Config) { c.Scan.MaxFileSize = 0 }, "scan.max_file_size"},
+		{"scan.concurrency", func(c *Config) { c.Scan.Concurrency = -1 }, "scan.concurrency"},
+		{"log.level", func(c *Config) { c.Log.Level = "loud" }, "log.level"},
+		{"jobs.workers", func(c *Config) { c.Jobs.Workers = 0 }, "jobs.workers"},
+		{"jobs.queue_size", func(c *Config) { c.Jobs.QueueSize = -1 }, "jobs.queue_size"},
+		{"watch.dir", func(c *Config) { c.Watch.Dir = file }, "watch.dir"},
+		{"watch.debounce", func(c *Config) { c.Watch.Debounce = 0 }, "watch.debounce"},
+		{"storage.path", func(c *Config) { c.Storage.Driver, c.Storage.Path = StorageBolt, "" }, "storage.path"},
+		{"storage.driver", func(c *Config) { c.Storage.Driver = "sqlite" }, "storage.driver"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want an error mentioning %s", err, tt.wantErr)
+			}
+			if n := len(cfg.validate()); n != 1 {
+				t.Errorf("validate() reported %d errors, want 1", n)
+			}
+		})
+	}
+
+	cfg := Default()
+	cfg.Scan.AllowedRoots = []string{"."}
+	cfg.Watch.Dir = "."
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if cfg.Scan.AllowedRoots[0] != "." || cfg.Watch.Dir != "." {
+		t.Errorf("Validate() rewrote the paths to %q and %q", cfg.Scan.AllowedRoots[0], cfg.Watch.Dir)
+	}
+}
+
+func TestLoadAbsolutePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Skipf("no relative path to %s: %v", dir, err)
+	}
+	cfg, err := Load(newFlagSet(), []string{"-allowed-roots", rel + ", .", "-watch", rel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{dir, wd}; !reflect.DeepEqual(cfg.Scan.AllowedRoots, want) {
+		t.Errorf("allowed roots = %q, want %q", cfg.Scan.AllowedRoots, want)
+	}
+	if cfg.Watch.Dir != dir {
+		t.Errorf("watch dir = %q, want %q", cfg.Watch.Dir, dir)
+	}
+}
@@ -0,0 +1,220 @@
+// Package gitfs reads the files of a git commit straight from the object
+// database of a repository, without checking them out.
+package gitfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var (
+	// ErrNotRepository is returned when a path holds no git repository.
+	ErrNotRepository = errors.New("not a git repository")
+	// ErrUnknownRevision is returned when a ref names no commit.
+	ErrUnknownRevision = errors.New("unknown git revision")
+)
+
+// FS is the file tree of a commit. Symbolic links and submodules are left
+// out; every file has the commit time as its modification time. It is safe
+// for concurrent use: reads of the object database are serialised, and files
+// are read whole when opened.
+type FS struct {
+	commit *object.Commit
+
+	mu   sync.Mutex
+	tree *object.Tree
+}
+
+// Open opens the repository at repo, a path or a file:// URL, bare or not,
+// and returns the tree of the commit named by ref: a branch, a tag, a commit
+// SHA, possibly abbreviated, or an expression such as HEAD~2. An empty ref
+// means HEAD.
+func Open(repo, ref string) (*FS, error) {
+	dir, err := Dir(repo)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	r, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, repo)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", repo, err)
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q in %s", ErrUnknownRevision, ref, repo)
+	}
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("read tree of commit %s: %w", hash, err)
+	}
+	return &FS{commit: commit, tree: tree}, nil
+}
+
+// Dir returns the path of the repository at repo, a path or a file:// URL.
+func Dir(repo string) (string, error) {
+	if !strings.HasPrefix(repo, "file://") {
+		return repo, nil
+	}
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("%w: %s is not a local repository", ErrNotRepository, repo)
+	}
+	return u.Path, nil
+}
+
+// Commit returns the SHA of the commit.
+func (f *FS) Commit() string {
+	return f.commit.Hash.String()
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &dir{info: f.dirInfo("."), fsys: f, tree: f.tree}, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, err := f.tree.FindEntry(name)
+	if err != nil || !visible(entry.Mode) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Dir {
+		tree, err := f.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &dir{info: f.dirInfo(path.Base(name)), fsys: f, tree: tree}, nil
+	}
+
+	blob, err := f.tree.TreeEntryFile(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	content, err := blob.Contents()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{info: f.fileInfo(entry.Name, blob.Size), Reader: strings.NewReader(content)}, nil
+}
+
+// visible reports whether entries of mode are part of the FS.
+func visible(mode filemode.FileMode) bool {
+	return mode == filemode.Dir || mode == filemode.Regular || mode == filemode.Executable || mode == filemode.Deprecated
+}
+
+func (f *FS) dirInfo(name string) *fileInfo {
+	return &fileInfo{name: name, mode: fs.ModeDir | 0o555, modTime: f.commit.Committer.When}
+}
+
+func (f *FS) fileInfo(name string, size int64) *fileInfo {
+	return &fileInfo{name: name, size: size, mode: 0o444, modTime: f.commit.Committer.When}
+}
+
+type file struct {
+	info *fileInfo
+	*strings.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Close() error { return nil }
+
+type dir struct {
+	info   *fileInfo
+	fsys   *FS
+	tree   *object.Tree
+	offset int // entries of tree already returned by ReadDir
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error { return nil }
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	for ; d.offset < len(d.tree.Entries) && (n <= 0 || len(entries) < n); d.offset++ {
+		entry := d.tree.Entries[d.offset]
+		if visible(entry.Mode) {
+			entries = append(entries, &dirEntry{fsys: d.fsys, tree: d.tree, entry: entry})
+		}
+	}
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	return entries, nil
+}
+
+type dirEntry struct {
+	fsys  *FS
+	tree  *object.Tree
+	entry object.TreeEntry
+}
+
+func (e *dirEntry) Name() string { return e.entry.Name }
+
+func (e *dirEntry) IsDir() bool { return e.entry.Mode == filemode.Dir }
+
+func (e *dirEntry) Type() fs.FileMode {
+	if e.IsDir() {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	if e.IsDir() {
+		return e.fsys.dirInfo(e.entry.Name), nil
+	}
+	e.fsys.mu.Lock()
+	defer e.fsys.mu.Unlock()
+	blob, err := e.tree.TreeEntryFile(&e.entry)
+	if err != nil {
+		return nil, err
+	}
+	return e.fsys.fileInfo(e.entry.Name, blob.Size), nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() any           { return nil }
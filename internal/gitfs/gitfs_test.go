@@ -0,0 +1,166 @@
+package gitfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+var when = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// commit writes files into the worktree of r, relative to dir, and commits them.
+func commit(t *testing.T, r *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: when}
+	hash, err := w.Commit("change", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// testRepo returns a repository whose master branch holds two commits, the
+// first of which is also the branch "old", the lightweight tag "v1" and the
+// annotated tag "v1-annotated". A bare repository is committed to through a
+// worktree elsewhere.
+func testRepo(t *testing.T, bare bool) (dir string, first, second plumbing.Hash) {
+	dir = t.TempDir()
+	work := dir
+	var r *git.Repository
+	var err error
+	if bare {
+		work = t.TempDir()
+		storage := filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault())
+		r, err = git.Init(storage, osfs.New(work))
+	} else {
+		r, err = git.PlainInit(dir, false)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	first = commit(t, r, work, map[string]string{"main.go": "package main\n", "pkg/a.go": "package pkg\n"})
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("old"), first)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateTag("v1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	tagger := &object.Signature{Name: "Dev", Email: "dev@example.com", When: when}
+	if _, err := r.CreateTag("v1-annotated", first, &git.CreateTagOptions{Tagger: tagger, Message: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	second = commit(t, r, work, map[string]string{"main.go": "package main\n\nfunc main() {}\n", "pkg/b.go": "package pkg\n"})
+	return dir, first, second
+}
+
+func TestOpen(t *testing.T) {
+	dir, first, second := testRepo(t, false)
+	// Commits of the same files at the same time have the same SHA.
+	bare, _, _ := testRepo(t, true)
+
+	tests := []struct {
+		name string
+		repo string
+		ref  string
+		want plumbing.Hash
+	}{
+		{"HEAD", dir, "", second},
+		{"branch", dir, "old", first},
+		{"lightweight tag", dir, "v1", first},
+		{"annotated tag", dir, "v1-annotated", first},
+		{"SHA", dir, first.String(), first},
+		{"abbreviated SHA", dir, first.String()[:7], first},
+		{"expression", dir, "HEAD~1", first},
+		{"bare remote", "file://" + bare, "", second},
+		{"bare remote tag", "file://" + bare, "v1-annotated", first},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, err := Open(tt.repo, tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fsys.Commit(); got != tt.want.String() {
+				t.Errorf("Commit() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	dir, _, _ := testRepo(t, false)
+	tests := []struct {
+		name    string
+		repo    string
+		ref     string
+		wantErr error
+	}{
+		{"unknown ref", dir, "no-such-branch", ErrUnknownRevision},
+		{"unknown SHA", dir, "0123456789abcdef0123456789abcdef01234567", ErrUnknownRevision},
+		{"not a repository", t.TempDir(), "", ErrNotRepository},
+		{"remote host", "file://example.com/repo.git", "", ErrNotRepository},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.repo, tt.ref); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Open(%s, %q) error = %v, want %v", tt.repo, tt.ref, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFS(t *testing.T) {
+	dir, _, _ := testRepo(t, false)
+	tests := []struct {
+		ref   string
+		files []string
+	}{
+		{"v1", []string{"main.go", "pkg/a.go"}},
+		{"HEAD", []string{"main.go", "pkg/a.go", "pkg/b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			fsys, err := Open(dir, tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := fstest.TestFS(fsys, tt.files...); err != nil {
+				t.Fatal(err)
+			}
+			info, err := fs.Stat(fsys, "main.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(when) {
+				t.Errorf("ModTime() = %v, want the commit time %v", info.ModTime(), when)
+			}
+		})
+	}
+}
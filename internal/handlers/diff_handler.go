@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DiffHandler compares graphs.
+type DiffHandler struct {
+	diffs service.DiffService
+}
+
+func NewDiffHandler(diffs service.DiffService) *DiffHandler {
+	return &DiffHandler{diffs: diffs}
+}
+
+// GetDiff lists the nodes and edges added, removed and modified from one
+// graph to another, grouped by service: ?from=&to= name two projects, or two
+// git refs of the repository at ?repo=, which are scanned for the occasion;
+// to then defaults to HEAD. ?format=markdown answers with a Markdown report
+// instead of JSON.
+func (h *DiffHandler) GetDiff(c *gin.Context) {
+	from, to, repo := c.Query("from"), c.Query("to"), c.Query("repo")
+	if from == "" || (to == "" && repo == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	switch format {
+	case "json", "markdown", "md":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format %q", format)})
+		return
+	}
+
+	var diff *service.GraphDiff
+	var err error
+	if repo != "" {
+		diff, err = h.diffs.DiffRefs(c.Request.Context(), repo, from, to)
+	} else {
+		diff, err = h.diffs.DiffProjects(from, to)
+	}
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, diff)
+		return
+	}
+	var b bytes.Buffer
+	if err := diff.WriteMarkdown(&b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", b.Bytes())
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval is how often an idle event stream sends a comment, so
+// that proxies do not close it while a long phase such as linking runs.
+const heartbeatInterval = 15 * time.Second
+
+// resumeFrom returns the sequence number of the first event to stream: the
+// one after the Last-Event-ID of a reconnecting client, or 0.
+func resumeFrom(c *gin.Context) int {
+	if last, err := strconv.Atoi(c.GetHeader("Last-Event-ID")); err == nil {
+		return last + 1
+	}
+	return 0
+}
+
+// send hands e to the stream, reporting false once the client is gone.
+func send(ctx context.Context, events chan<- sse.Event, e sse.Event) bool {
+	select {
+	case events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// streamEvents writes events as Server-Sent Events until the channel is
+// closed or the client goes away.
+func streamEvents(c *gin.Context, events <-chan sse.Event) {
+	ctx := c.Request.Context()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.Render(-1, e)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	jobs service.JobManager
+}
+
+func NewJobHandler(jobs service.JobManager) *JobHandler {
+	return &JobHandler{jobs: jobs}
+}
+
+// GetJob reports the status and progress of a background scan.
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.jobs.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// CancelJob stops a queued or running scan. Whatever the scan had not yet
+// stored is discarded; the job reports status "cancelled" once it has stopped.
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, err := h.jobs.Cancel(c.Param("id"))
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error(), "job": job})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// StreamEvents streams the events of a job as Server-Sent Events: one
+// file_started, file_scanned or file_failed event per file, phase changes,
+// the nodes and edges each linker adds, and a final summary, after which the
+// stream ends. Each event's ID is its sequence number; a client reconnecting
+// with Last-Event-ID resumes after it.
+func (h *JobHandler) StreamEvents(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.jobs.Get(id); err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	events := make(chan sse.Event)
+	go func() {
+		defer close(events)
+		h.jobs.Events(ctx, id, resumeFrom(c), func(seq int, e service.ScanEvent) bool {
+			return send(ctx, events, sse.Event{Id: strconv.Itoa(seq), Event: e.Type, Data: e})
+		})
+	}()
+	streamEvents(c, events)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectHandler struct {
+	projects service.ProjectService
+}
+
+func NewProjectHandler(projects service.ProjectService) *ProjectHandler {
+	return &ProjectHandler{projects: projects}
+}
+
+type CreateProjectRequest struct {
+	ID          string `json:"id"` // optional; derived from the name when empty
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	var req CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projects.CreateProject(req.ID, req.Name, req.Description)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	projects := h.projects.ListProjects()
+	c.JSON(http.StatusOK, gin.H{"projects": projects, "count": len(projects)})
+}
+
+// GetProject returns a project with the size of its graph.
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	id := c.Param("project")
+	project, err := h.projects.GetProject(id)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	scans, err := h.projects.Scans(id)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"project": project,
+		"nodes":   len(scans.GetAllNodes()),
+		"edges":   len(scans.GetAllEdges()),
+	})
+}
+
+// DeleteProject removes a project and everything scanned into it.
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	if err := h.projects.DeleteProject(c.Param("project")); err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
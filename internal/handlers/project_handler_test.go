@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestProjectHandler(t *testing.T) {
+	s := newTestServer(t, config.ScanConfig{})
+
+	creates := []struct {
+		name string
+		body gin.H
+		want int
+	}{
+		{"derived ID", gin.H{"name": "Order Service"}, http.StatusCreated},
+		{"explicit ID", gin.H{"id": "users", "name": "Users", "description": "accounts"}, http.StatusCreated},
+		{"duplicate", gin.H{"id": "order-service", "name": "Orders again"}, http.StatusConflict},
+		{"no name", gin.H{"id": "x"}, http.StatusBadRequest},
+		{"invalid ID", gin.H{"id": "Bad ID", "name": "Bad"}, http.StatusBadRequest},
+	}
+	for _, tt := range creates {
+		var p models.Project
+		if code := s.do(http.MethodPost, "/v1/projects", tt.body, &p); code != tt.want {
+			t.Errorf("create %s: status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	var list struct {
+		Projects []models.Project `json:"projects"`
+		Count    int              `json:"count"`
+	}
+	if code := s.do(http.MethodGet, "/v1/projects", nil, &list); code != http.StatusOK || list.Count != 2 ||
+		list.Projects[0].ID != "order-service" || list.Projects[1].Description != "accounts" {
+		t.Errorf("list: status %d, %+v", code, list)
+	}
+
+	// A file scanned into one project is only seen there.
+	if code := s.scanFile("users", "", "/src/users.go", "package users\n\nfunc Get() {}\n", nil); code != http.StatusOK {
+		t.Fatalf("scan: status = %d", code)
+	}
+	counts := map[string]int{"users": 1, "order-service": 0}
+	for id, want := range counts {
+		var nodes struct {
+			Count int `json:"count"`
+		}
+		if code := s.do(http.MethodGet, "/v1/projects/"+id+"/nodes", nil, &nodes); code != http.StatusOK || nodes.Count != want {
+			t.Errorf("nodes of %s: status %d, count %d, want %d", id, code, nodes.Count, want)
+		}
+		var got struct {
+			Project models.Project `json:"project"`
+			Nodes   int            `json:"nodes"`
+		}
+		if code := s.do(http.MethodGet, "/v1/projects/"+id, nil, &got); code != http.StatusOK || got.Project.ID != id || got.Nodes != want {
+			t.Errorf("get %s: status %d, %+v", id, code, got)
+		}
+	}
+
+	if code := s.do(http.MethodDelete, "/v1/projects/users", nil, nil); code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := s.do(http.MethodDelete, "/v1/projects/users", nil, nil); code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := s.do(http.MethodGet, "/v1/projects/users", nil, nil); code != http.StatusNotFound {
+		t.Errorf("get deleted project: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestUnknownProject(t *testing.T) {
+	s := newTestServer(t, config.ScanConfig{})
+	if _, err := s.projects.CreateProject("users", "Users", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.projects.DeleteProject("users"); err != nil {
+		t.Fatal(err)
+	}
+	for _, project := range []string{"missing", "users"} {
+		requests := []struct {
+			method, path string
+			body         interface{}
+		}{
+			{http.MethodGet, "", nil},
+			{http.MethodDelete, "", nil},
+			{http.MethodPost, "/scan", ScanRequest{FilePath: "/src/a.go", Content: "cGFja2FnZSBhCg=="}},
+			{http.MethodPost, "/scan/dir", gin.H{"dir_path": t.TempDir()}},
+			{http.MethodGet, "/nodes", nil},
+			{http.MethodGet, "/edges", nil},
+			{http.MethodGet, "/routes", nil},
+		}
+		for _, r := range requests {
+			var resp struct {
+				Error string `json:"error"`
+			}
+			path := "/v1/projects/" + project + r.path
+			if code := s.do(r.method, path, r.body, &resp); code != http.StatusNotFound || resp.Error == "" {
+				t.Errorf("%s %s: status %d, error %q, want %d with an error", r.method, path, code, resp.Error, http.StatusNotFound)
+			}
+		}
+	}
+}
@@ -1,19 +1,44 @@
 package handlers
 
 import (
+	"cmp"
+	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
 	"github.com/chinmay-sawant/gosourcemapper/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+// ScanHandler serves the scan and query endpoints of a project, routed under
+// /v1/projects/:project.
 type ScanHandler struct {
-	service service.ScanService
+	projects service.ProjectService
+	jobs     service.JobManager
+	cfg      config.ScanConfig
 }
 
-func NewScanHandler(service service.ScanService) *ScanHandler {
-	return &ScanHandler{service: service}
+func NewScanHandler(projects service.ProjectService, jobs service.JobManager, cfg config.ScanConfig) *ScanHandler {
+	return &ScanHandler{projects: projects, jobs: jobs, cfg: cfg}
+}
+
+// scans returns the scan service of the project named in the path, writing
+// an error response when there is no such project.
+func (h *ScanHandler) scans(c *gin.Context) (service.ScanService, bool) {
+	scans, err := h.projects.Scans(c.Param("project"))
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return scans, true
 }
 
 type ScanRequest struct {
@@ -21,7 +46,17 @@ type ScanRequest struct {
 	Content  string `json:"content" binding:"required"` // Base64 encoded content
 }
 
+// ScanFile scans a single file. A file that does not parse is listed in the
+// result's errors, or fails the request with ?strict=true.
 func (h *ScanHandler) ScanFile(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	opts, ok := h.scanOptions(c)
+	if !ok {
+		return
+	}
 	var req ScanRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -34,34 +69,77 @@ func (h *ScanHandler) ScanFile(c *gin.Context) {
 		return
 	}
 
-	nodes, err := h.service.ScanFile(req.FilePath, decoded)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "count": len(nodes)})
+	result, err := scans.ScanFile(c.Request.Context(), req.FilePath, decoded, opts)
+	writeResult(c, result, err)
 }
 
+// UploadZip scans an uploaded zip archive. With ?async=true the archive is
+// extracted right away and scanned by a background job, whose ID is returned.
 func (h *ScanHandler) UploadZip(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	async, ok := boolParam(c, "async", false)
+	if !ok {
+		return
+	}
+	opts, ok := h.scanOptions(c)
+	if !ok {
+		return
+	}
+	limit := int64(h.cfg.MaxUploadSize)
+	if limit > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	}
+
 	file, err := c.FormFile("file")
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload exceeds %s", h.cfg.MaxUploadSize)})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
 		return
 	}
 
-	// Persist in .temp in root
-	destRoot := ".temp"
-	nodes, err := h.service.ProcessZipUpload(file, destRoot)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Persist in the project's directory under the configured temp dir
+	destRoot := filepath.Join(h.cfg.TempDir, c.Param("project"))
+	if async {
+		// The request's temporary files are gone once it returns, so the
+		// archive is extracted before the job is queued.
+		dir, err := scans.SaveUpload(file, destRoot)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		h.submit(c, service.JobUpload, file.Filename, func(ctx context.Context, events service.EventFunc) (*service.ScanResult, error) {
+			return scans.ScanUpload(ctx, dir, opts, events)
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "count": len(nodes)})
+	result, err := scans.ProcessZipUpload(c.Request.Context(), file, destRoot, opts, nil)
+	writeResult(c, result, err)
 }
 
+// ScanDirectory scans a directory on the server. A rescan only parses the
+// files that changed, unless ?full=true. With ?async=true the scan runs as a
+// background job, whose ID is returned.
 func (h *ScanHandler) ScanDirectory(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	async, ok := boolParam(c, "async", false)
+	if !ok {
+		return
+	}
+	opts, ok := h.scanOptions(c)
+	if !ok {
+		return
+	}
 	var req struct {
 		DirPath string `json:"dir_path" binding:"required"`
 	}
@@ -70,16 +148,230 @@ func (h *ScanHandler) ScanDirectory(c *gin.Context) {
 		return
 	}
 
-	nodes, err := h.service.ScanDirectory(req.DirPath)
+	if async {
+		h.submit(c, service.JobScanDir, req.DirPath, func(ctx context.Context, events service.EventFunc) (*service.ScanResult, error) {
+			return scans.ScanDirectory(ctx, req.DirPath, opts, events)
+		})
+		return
+	}
+
+	result, err := scans.ScanDirectory(c.Request.Context(), req.DirPath, opts, nil)
+	writeResult(c, result, err)
+}
+
+// ScanGit scans a git repository on the server at a branch, tag or commit,
+// without checking it out. The ref defaults to HEAD. Like directory scans,
+// it takes ?async, ?strict and ?full.
+func (h *ScanHandler) ScanGit(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	async, ok := boolParam(c, "async", false)
+	if !ok {
+		return
+	}
+	opts, ok := h.scanOptions(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Repo string `json:"repo" binding:"required"`
+		Ref  string `json:"ref"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if async {
+		target := req.Repo + "@" + cmp.Or(req.Ref, "HEAD")
+		h.submit(c, service.JobScanGit, target, func(ctx context.Context, events service.EventFunc) (*service.ScanResult, error) {
+			return scans.ScanGit(ctx, req.Repo, req.Ref, opts, events)
+		})
+		return
+	}
+
+	result, err := scans.ScanGit(c.Request.Context(), req.Repo, req.Ref, opts, nil)
+	writeResult(c, result, err)
+}
+
+// writeResult answers with the result of a scan, or with the error that
+// ended it; a strict scan that failed also lists the files at fault.
+func writeResult(c *gin.Context, result *service.ScanResult, err error) {
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if result != nil {
+			body["errors"] = result.Errors
+		}
+		c.JSON(statusFor(err), body)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// submit queues a scan of the project as a job and answers 202 Accepted,
+// pointing at the job's status.
+func (h *ScanHandler) submit(c *gin.Context, kind, target string, run service.ScanFunc) {
+	job, err := h.jobs.Submit(c.Param("project"), kind, target, run)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("Location", "/v1/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
 
-	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "count": len(nodes)})
+// scanOptions reads the options of a scan from the query, defaulting to the
+// configuration.
+func (h *ScanHandler) scanOptions(c *gin.Context) (service.ScanOptions, bool) {
+	strict, ok := boolParam(c, "strict", h.cfg.Strict)
+	if !ok {
+		return service.ScanOptions{}, false
+	}
+	full, ok := boolParam(c, "full", false)
+	return service.ScanOptions{Strict: strict, Full: full}, ok
 }
 
+// boolParam reads a boolean query parameter, writing an error response when
+// it is not a boolean.
+func boolParam(c *gin.Context, name string, def bool) (bool, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return def, true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s %q", name, v)})
+		return false, false
+	}
+	return b, true
+}
+
+// GetAllNodes returns every node, optionally narrowed by the query parameters
+// of service.NodeFilter (type, language, name, file, param_type, service,
+// module). With ?group_by=service (or module, file, language, type) the nodes
+// are returned as groups keyed by that attribute.
 func (h *ScanHandler) GetAllNodes(c *gin.Context) {
-	nodes := h.service.GetAllNodes()
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	var filter service.NodeFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes := filter.Filter(scans.GetAllNodes())
+	if by := c.Query("group_by"); by != "" {
+		groups, err := service.GroupNodes(nodes, by)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"groups": groups, "count": len(nodes)})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "count": len(nodes)})
 }
+
+// GetAllEdges returns every edge, optionally filtered with ?kind=CALLS,IMPLEMENTS.
+func (h *ScanHandler) GetAllEdges(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	kinds, err := edgeKinds(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	edges := scans.GetAllEdges(kinds...)
+	models.SortEdges(edges)
+	c.JSON(http.StatusOK, gin.H{"edges": edges, "count": len(edges)})
+}
+
+// GetNodeEdges returns the edges of one node. ?direction= is out, in or both
+// (the default) and ?kind= filters by edge kind.
+func (h *ScanHandler) GetNodeEdges(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	kinds, err := edgeKinds(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	direction := service.Direction(c.DefaultQuery("direction", string(service.DirectionBoth)))
+	switch direction {
+	case service.DirectionOut, service.DirectionIn, service.DirectionBoth:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid direction %q", direction)})
+		return
+	}
+
+	edges, err := scans.GetNodeEdges(c.Param("id"), direction, kinds...)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	models.SortEdges(edges)
+	c.JSON(http.StatusOK, gin.H{"edges": edges, "count": len(edges)})
+}
+
+// FindRoutes answers which functions serve a request: ?method=GET&path=/v1/orders/42.
+// Both parameters are optional; without them every route is listed.
+func (h *ScanHandler) FindRoutes(c *gin.Context) {
+	scans, ok := h.scans(c)
+	if !ok {
+		return
+	}
+	routes := scans.FindRoutes(c.Query("method"), c.Query("path"))
+	c.JSON(http.StatusOK, gin.H{"routes": routes, "count": len(routes)})
+}
+
+// edgeKinds reads the kind query parameter, which may be repeated or comma-separated.
+func edgeKinds(c *gin.Context) ([]models.EdgeKind, error) {
+	var kinds []models.EdgeKind
+	for _, value := range c.QueryArray("kind") {
+		for _, k := range strings.Split(value, ",") {
+			kind := models.EdgeKind(strings.ToUpper(strings.TrimSpace(k)))
+			if kind == "" {
+				continue
+			}
+			if !kind.Valid() {
+				return nil, fmt.Errorf("unknown edge kind %q", k)
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// statusFor maps service errors onto HTTP status codes.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPathNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrNodeNotFound), errors.Is(err, service.ErrProjectNotFound), errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotRepository), errors.Is(err, service.ErrUnknownRevision):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrProjectExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidProject), errors.Is(err, service.ErrNotUploaded), errors.Is(err, service.ErrUnsupportedFile):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrStrictScan):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrJobNotFound), errors.Is(err, service.ErrNotWatching):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrQueueFull), errors.Is(err, service.ErrJobsClosed):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
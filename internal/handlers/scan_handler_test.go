@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// testServer serves the project and scan endpoints over in-memory storage.
+type testServer struct {
+	t        *testing.T
+	engine   *gin.Engine
+	projects service.ProjectService
+}
+
+func newTestServer(t *testing.T, cfg config.ScanConfig) *testServer {
+	gin.SetMode(gin.TestMode)
+	projects := service.NewProjectService(repository.NewInMemoryProjectRepository(), cfg)
+	jobs := service.NewJobManager(config.JobsConfig{Workers: 1, QueueSize: 1})
+	t.Cleanup(jobs.Close)
+	projectHandler, scanHandler := NewProjectHandler(projects), NewScanHandler(projects, jobs, cfg)
+
+	r := gin.New()
+	r.POST("/v1/projects", projectHandler.CreateProject)
+	r.GET("/v1/projects", projectHandler.ListProjects)
+	r.GET("/v1/projects/:project", projectHandler.GetProject)
+	r.DELETE("/v1/projects/:project", projectHandler.DeleteProject)
+	project := r.Group("/v1/projects/:project")
+	project.POST("/scan", scanHandler.ScanFile)
+	project.POST("/scan/dir", scanHandler.ScanDirectory)
+	project.GET("/nodes", scanHandler.GetAllNodes)
+	project.GET("/edges", scanHandler.GetAllEdges)
+	project.GET("/routes", scanHandler.FindRoutes)
+	return &testServer{t: t, engine: r, projects: projects}
+}
+
+// do sends a request with body, marshalled to JSON unless nil, and decodes
+// the JSON response into out unless nil. It returns the status code.
+func (s *testServer) do(method, path string, body, out interface{}) int {
+	s.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode %s: %v", method, path, w.Body, err)
+		}
+	}
+	return w.Code
+}
+
+// scanFile posts a file to the scan endpoint of project.
+func (s *testServer) scanFile(project, query, path, content string, out interface{}) int {
+	s.t.Helper()
+	req := ScanRequest{FilePath: path, Content: base64.StdEncoding.EncodeToString([]byte(content))}
+	return s.do(http.MethodPost, "/v1/projects/"+project+"/scan"+query, req, out)
+}
+
+func TestScanStrictStatus(t *testing.T) {
+	const bad = "package x\n\nfunc (\n"
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	type response struct {
+		Error       string              `json:"error"`
+		Errors      []service.ScanError `json:"errors"`
+		FilesFailed int                 `json:"files_failed"`
+	}
+	tests := []struct {
+		name   string
+		strict bool // the configured default
+		query  string
+		dir    bool // scan the directory rather than the file
+		want   int
+	}{
+		{name: "lenient", want: http.StatusOK},
+		{name: "strict query", query: "?strict=true", want: http.StatusUnprocessableEntity},
+		{name: "strict config", strict: true, want: http.StatusUnprocessableEntity},
+		{name: "lenient query over strict config", strict: true, query: "?strict=false", want: http.StatusOK},
+		{name: "bad query", query: "?strict=maybe", want: http.StatusBadRequest},
+		{name: "lenient directory", dir: true, want: http.StatusOK},
+		{name: "strict directory", dir: true, query: "?strict=1", want: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, config.ScanConfig{Strict: tt.strict})
+			if _, err := s.projects.CreateProject("p", "P", ""); err != nil {
+				t.Fatal(err)
+			}
+			var resp response
+			var code int
+			if tt.dir {
+				code = s.do(http.MethodPost, "/v1/projects/p/scan/dir"+tt.query, gin.H{"dir_path": dir}, &resp)
+			} else {
+				code = s.scanFile("p", tt.query, filepath.Join(dir, "bad.go"), bad, &resp)
+			}
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d (%+v)", code, tt.want, resp)
+			}
+			switch code {
+			case http.StatusOK:
+				if resp.FilesFailed != 1 || len(resp.Errors) != 1 {
+					t.Errorf("response = %+v, want one failed file", resp)
+				}
+			case http.StatusUnprocessableEntity:
+				if len(resp.Errors) != 1 || resp.Errors[0].Kind != service.ErrorKindParse || resp.Errors[0].Line != 3 {
+					t.Errorf("errors = %+v, want the parse error of line 3", resp.Errors)
+				}
+			}
+		})
+	}
+}
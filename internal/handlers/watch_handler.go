@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// WatchHandler serves the state and the events of watch mode.
+type WatchHandler struct {
+	watcher service.Watcher // nil when watch mode is off
+}
+
+func NewWatchHandler(watcher service.Watcher) *WatchHandler {
+	return &WatchHandler{watcher: watcher}
+}
+
+// GetWatch reports the directory and project kept current by watch mode.
+func (h *WatchHandler) GetWatch(c *gin.Context) {
+	if h.watcher == nil {
+		c.JSON(statusFor(service.ErrNotWatching), gin.H{"error": service.ErrNotWatching.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"watch": h.watcher.Status()})
+}
+
+// StreamEvents streams the changes watch mode applies as Server-Sent Events:
+// a file_scanned, file_failed or file_removed event per changed file, then a
+// dir_scanned event once relations across files are resolved again. The
+// stream stays open; a client reconnecting with Last-Event-ID resumes after
+// that event, as long as it is among the latest kept.
+func (h *WatchHandler) StreamEvents(c *gin.Context) {
+	if h.watcher == nil {
+		c.JSON(statusFor(service.ErrNotWatching), gin.H{"error": service.ErrNotWatching.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	events := make(chan sse.Event)
+	go func() {
+		defer close(events)
+		h.watcher.Events(ctx, resumeFrom(c), func(seq int, e service.WatchEvent) bool {
+			return send(ctx, events, sse.Event{Id: strconv.Itoa(seq), Event: e.Type, Data: e})
+		})
+	}()
+	streamEvents(c, events)
+}
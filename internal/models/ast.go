@@ -1,12 +1,22 @@
 package models
 
+import (
+	"maps"
+	"slices"
+)
+
 type NodeType string
 
 const (
 	NodeFunction  NodeType = "FUNCTION"
 	NodeInterface NodeType = "INTERFACE"
+	NodeStruct    NodeType = "STRUCT" // For Go
 	NodeHTTPCall  NodeType = "HTTP_CALL"
-	NodeClass     NodeType = "CLASS" // For Java/Python
+	NodeClass     NodeType = "CLASS"   // For Java/Python
+	NodeRoute     NodeType = "ROUTE"   // An HTTP endpoint served by a handler
+	NodeEnvVar    NodeType = "ENV_VAR" // An environment variable read by the code
+	NodeService   NodeType = "SERVICE" // A separately built unit, e.g. a go.mod at the top of a tree
+	NodeModule    NodeType = "MODULE"  // A Go module, Maven/Gradle project or Python package
 )
 
 // CodeNode represents a semantic unit of code
@@ -22,3 +32,20 @@ type CodeNode struct {
 	Metadata     map[string]interface{} `json:"metadata"`     // Language-specific details
 	Dependencies []string               `json:"dependencies"` // IDs of other nodes this node calls
 }
+
+// Clone returns a copy of n that can be modified, by linkers enriching it,
+// without affecting n.
+func (n *CodeNode) Clone() *CodeNode {
+	c := *n
+	c.Comments = slices.Clone(n.Comments)
+	c.Metadata = maps.Clone(n.Metadata)
+	c.Dependencies = slices.Clone(n.Dependencies)
+	return &c
+}
+
+// Param is a named, typed parameter, result or type parameter of a function,
+// stored in CodeNode.Metadata
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
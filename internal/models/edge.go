@@ -0,0 +1,105 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"sort"
+)
+
+type EdgeKind string
+
+const (
+	EdgeCalls        EdgeKind = "CALLS"         // function -> function it calls
+	EdgeImplements   EdgeKind = "IMPLEMENTS"    // type -> interface it satisfies
+	EdgeEmbeds       EdgeKind = "EMBEDS"        // type -> type embedded in it
+	EdgeImports      EdgeKind = "IMPORTS"       // module -> module it imports
+	EdgeHandlesRoute EdgeKind = "HANDLES_ROUTE" // handler function -> route it serves
+	EdgeCallsService EdgeKind = "CALLS_SERVICE" // outbound HTTP call -> route of another service
+	EdgeReadsEnv     EdgeKind = "READS_ENV"     // function -> environment variable it reads
+	EdgeDefinedIn    EdgeKind = "DEFINED_IN"    // node -> module or service that owns it
+)
+
+// Valid reports whether k is one of the known edge kinds.
+func (k EdgeKind) Valid() bool {
+	switch k {
+	case EdgeCalls, EdgeImplements, EdgeEmbeds, EdgeImports,
+		EdgeHandlesRoute, EdgeCallsService, EdgeReadsEnv, EdgeDefinedIn:
+		return true
+	}
+	return false
+}
+
+// Edge is a typed, directed relation between two nodes
+type Edge struct {
+	ID         string                 `json:"id"`
+	Source     string                 `json:"source"` // ID of the node the edge starts at
+	Target     string                 `json:"target"` // ID of the node the edge points to
+	Kind       EdgeKind               `json:"kind"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // Kind-specific details
+}
+
+// NewEdge returns an edge whose ID is derived from its endpoints and kind, so
+// relinking the same relation yields the same edge.
+func NewEdge(source, target string, kind EdgeKind) *Edge {
+	h := sha256.New()
+	for _, part := range []string{source, string(kind), target} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return &Edge{
+		ID:     hex.EncodeToString(h.Sum(nil)[:16]),
+		Source: source,
+		Target: target,
+		Kind:   kind,
+	}
+}
+
+// Clone returns a copy of e that does not share its attributes.
+func (e *Edge) Clone() *Edge {
+	c := *e
+	c.Attributes = maps.Clone(e.Attributes)
+	return &c
+}
+
+// HasKind reports whether the edge is of one of kinds; no kinds matches every edge.
+func (e *Edge) HasKind(kinds ...EdgeKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if e.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Graph is a set of nodes together with the edges between them
+type Graph struct {
+	Nodes []*CodeNode `json:"nodes"`
+	Edges []*Edge     `json:"edges"`
+}
+
+// Merge appends the nodes and edges of other to g.
+func (g *Graph) Merge(other *Graph) {
+	if other == nil {
+		return
+	}
+	g.Nodes = append(g.Nodes, other.Nodes...)
+	g.Edges = append(g.Edges, other.Edges...)
+}
+
+// SortEdges orders edges by source, kind and target so output is stable.
+func SortEdges(edges []*Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Target < b.Target
+	})
+}
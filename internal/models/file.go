@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// FileRecord is the entry of a scanned file in a project's manifest: enough to
+// tell on the next scan whether the file changed, and which nodes it produced.
+type FileRecord struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"` // hex SHA-256 of the content
+	// Root is the directory of the scan that recorded the file, which the
+	// IDs of its nodes are derived relative to.
+	Root string `json:"root,omitempty"`
+	// Nodes are the IDs of the nodes the file's scanner found, leaving out
+	// those derived from it by linkers.
+	Nodes []string `json:"nodes,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+)
+
+// NewNodeID derives a stable, content-addressed node ID from the identity of
+// a symbol. Scanning the same symbol again always yields the same ID, so
+// rescans replace nodes instead of duplicating them and IDs can be linked to
+// from outside the tool. relPath is the path of the file within the scanned
+// tree, as returned by IDPath, so that the ID does not depend on where the
+// tree is checked out.
+func NewNodeID(language, module, relPath, name string, kind NodeType) string {
+	h := sha256.New()
+	for _, part := range []string{language, module, relPath, name, string(kind)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0}) // separator, so ("ab","c") and ("a","bc") differ
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// IDPath returns the slash-separated path of filePath relative to root, the
+// directory of the scanned tree, for use in node IDs. filePath is used as it
+// is when root is empty or does not hold it.
+func IDPath(root, filePath string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, filePath); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filePath)
+}
+
+// IDAllocator hands out node IDs for the symbols of a single file.
+// Symbols that share a name and kind, such as repeated call sites, are told
+// apart by their order of appearance in the file. Scanners add what else
+// identifies a symbol, like the parameter types of an overload, to its name.
+type IDAllocator struct {
+	language string
+	module   string
+	relPath  string
+	seen     map[string]int
+}
+
+// NewIDAllocator returns the allocator of the file at relPath, the result of
+// IDPath.
+func NewIDAllocator(language, module, relPath string) *IDAllocator {
+	return &IDAllocator{
+		language: language,
+		module:   module,
+		relPath:  relPath,
+		seen:     make(map[string]int),
+	}
+}
+
+// SetModule changes the module used for IDs allocated from now on. Scanners
+// call it once the package declaration of the file has been read.
+func (a *IDAllocator) SetModule(module string) {
+	a.module = module
+}
+
+// ID returns the ID of the next symbol called name of the given kind.
+func (a *IDAllocator) ID(name string, kind NodeType) string {
+	key := string(kind) + "\x00" + name
+	a.seen[key]++
+	if n := a.seen[key]; n > 1 {
+		name = fmt.Sprintf("%s#%d", name, n)
+	}
+	return NewNodeID(a.language, a.module, a.relPath, name, kind)
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIDPath(t *testing.T) {
+	root := filepath.FromSlash("/src/checkout")
+	tests := []struct {
+		name     string
+		root     string
+		filePath string
+		want     string
+	}{
+		{"in root", root, filepath.Join(root, "svc", "main.go"), "svc/main.go"},
+		{"no root", "", filepath.FromSlash("svc/main.go"), "svc/main.go"},
+		{"outside root", root, filepath.FromSlash("/elsewhere/main.go"), "/elsewhere/main.go"},
+		{"sibling with root as prefix", root, filepath.FromSlash("/src/checkout2/main.go"), "/src/checkout2/main.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IDPath(tt.root, tt.filePath); got != tt.want {
+				t.Errorf("IDPath(%q, %q) = %q, want %q", tt.root, tt.filePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeIDStability(t *testing.T) {
+	id := NewNodeID("go", "main", "svc/main.go", "main", NodeFunction)
+	if id != NewNodeID("go", "main", "svc/main.go", "main", NodeFunction) {
+		t.Fatal("same symbol got two IDs")
+	}
+	if len(id) != 32 {
+		t.Errorf("ID %q has %d characters, want 32", id, len(id))
+	}
+
+	// Each part of the identity of a symbol makes the ID differ.
+	for name, other := range map[string]string{
+		"language": NewNodeID("python", "main", "svc/main.go", "main", NodeFunction),
+		"module":   NewNodeID("go", "svc", "svc/main.go", "main", NodeFunction),
+		"path":     NewNodeID("go", "main", "svc/other.go", "main", NodeFunction),
+		"name":     NewNodeID("go", "main", "svc/main.go", "run", NodeFunction),
+		"kind":     NewNodeID("go", "main", "svc/main.go", "main", NodeStruct),
+		"boundary": NewNodeID("go", "mai", "nsvc/main.go", "main", NodeFunction),
+	} {
+		if other == id {
+			t.Errorf("changing the %s kept the ID", name)
+		}
+	}
+
+	// Two checkouts of a tree yield the same IDs.
+	a := NewIDAllocator("go", "main", IDPath(filepath.FromSlash("/a"), filepath.FromSlash("/a/svc/main.go")))
+	b := NewIDAllocator("go", "main", IDPath(filepath.FromSlash("/b/c"), filepath.FromSlash("/b/c/svc/main.go")))
+	if a.ID("main", NodeFunction) != b.ID("main", NodeFunction) {
+		t.Error("IDs depend on where the tree is checked out")
+	}
+}
+
+func TestIDAllocator(t *testing.T) {
+	ids := NewIDAllocator("java", "com.example", "Service.java")
+	first := ids.ID("process", NodeFunction)
+	second := ids.ID("process", NodeFunction)
+	class := ids.ID("process", NodeStruct)
+	if first == second {
+		t.Error("overloads got the same ID")
+	}
+	if first != NewNodeID("java", "com.example", "Service.java", "process", NodeFunction) {
+		t.Error("first symbol of a name is not numbered like a lone one")
+	}
+	if second != NewNodeID("java", "com.example", "Service.java", "process#2", NodeFunction) {
+		t.Error("second overload not told apart by its order")
+	}
+	if class != NewNodeID("java", "com.example", "Service.java", "process", NodeStruct) {
+		t.Error("symbols of another kind share the count of a name")
+	}
+
+	ids.SetModule("com.other")
+	if got := ids.ID("run", NodeFunction); got != NewNodeID("java", "com.other", "Service.java", "run", NodeFunction) {
+		t.Error("SetModule not applied to later IDs")
+	}
+}
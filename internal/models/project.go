@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Project is a namespace for scans: each project has its own graph, so
+// unrelated repositories mapped by the same server never share nodes.
+type Project struct {
+	ID          string    `json:"id"` // URL-safe, e.g. "order-service"
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
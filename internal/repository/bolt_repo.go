@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// openTimeout bounds how long opening waits for another process to release
+// the database file.
+const openTimeout = 5 * time.Second
+
+// Layout of the database: the projects bucket maps project IDs to their JSON
+// record, and each project's graph lives in its own bucket holding a nodes and
+// an edges bucket, keyed by node and edge ID, and a files bucket holding the
+// manifest of scanned files, keyed by path.
+var (
+	bucketProjects = []byte("projects")
+	bucketNodes    = []byte("nodes")
+	bucketEdges    = []byte("edges")
+	bucketFiles    = []byte("files")
+)
+
+func graphBucket(projectID string) []byte { return []byte("graph:" + projectID) }
+
+// BoltProjectRepository is a ProjectRepository persisted in a bbolt database
+// file. All projects share the file; each has its own bucket.
+type BoltProjectRepository struct {
+	path string
+
+	// dbMu guards the database handle, which Compact swaps: transactions hold
+	// it for reading.
+	dbMu sync.RWMutex
+	db   *bolt.DB
+
+	mu       sync.RWMutex
+	projects map[string]*models.Project
+	graphs   map[string]*BoltGraphRepository
+}
+
+// NewBoltProjectRepository opens or creates the database at path and loads
+// every project stored in it.
+func NewBoltProjectRepository(path string) (*BoltProjectRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open graph store %s: %w", path, err)
+	}
+	r := &BoltProjectRepository{
+		path:     path,
+		db:       db,
+		projects: make(map[string]*models.Project),
+		graphs:   make(map[string]*BoltGraphRepository),
+	}
+	if err := r.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *BoltProjectRepository) load() error {
+	if err := r.update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketProjects)
+		return err
+	}); err != nil {
+		return fmt.Errorf("initialise graph store %s: %w", r.path, err)
+	}
+
+	err := r.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketProjects).ForEach(func(k, v []byte) error {
+			var project models.Project
+			if err := json.Unmarshal(v, &project); err != nil {
+				return fmt.Errorf("project %s: %w", k, err)
+			}
+			graph := r.newGraph(project.ID)
+			if err := graph.load(tx); err != nil {
+				return fmt.Errorf("project %s: %w", k, err)
+			}
+			r.projects[project.ID] = &project
+			r.graphs[project.ID] = graph
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("load graph store %s: %w", r.path, err)
+	}
+	slog.Info("graph store loaded", "path", r.path, "projects", len(r.projects))
+	return nil
+}
+
+func (r *BoltProjectRepository) update(fn func(*bolt.Tx) error) error {
+	r.dbMu.RLock()
+	defer r.dbMu.RUnlock()
+	if r.db == nil {
+		return errors.New("graph store is closed")
+	}
+	return r.db.Update(fn)
+}
+
+func (r *BoltProjectRepository) view(fn func(*bolt.Tx) error) error {
+	r.dbMu.RLock()
+	defer r.dbMu.RUnlock()
+	if r.db == nil {
+		return errors.New("graph store is closed")
+	}
+	return r.db.View(fn)
+}
+
+func (r *BoltProjectRepository) newGraph(projectID string) *BoltGraphRepository {
+	return &BoltGraphRepository{
+		InMemoryGraphRepository: NewInMemoryGraphRepository(),
+		store:                   r,
+		bucket:                  graphBucket(projectID),
+	}
+}
+
+func (r *BoltProjectRepository) CreateProject(project *models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[project.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrProjectExists, project.ID)
+	}
+	err := r.update(func(tx *bolt.Tx) error {
+		if err := put(tx.Bucket(bucketProjects), project.ID, project); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists(graphBucket(project.ID))
+		if err != nil {
+			return err
+		}
+		return createGraphBuckets(b)
+	})
+	if err != nil {
+		return fmt.Errorf("persist project %s: %w", project.ID, err)
+	}
+	r.projects[project.ID] = project
+	r.graphs[project.ID] = r.newGraph(project.ID)
+	return nil
+}
+
+func (r *BoltProjectRepository) GetProject(id string) (*models.Project, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	project, ok := r.projects[id]
+	return project, ok
+}
+
+func (r *BoltProjectRepository) ListProjects() []*models.Project {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedProjects(r.projects)
+}
+
+func (r *BoltProjectRepository) DeleteProject(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, id)
+	}
+	err := r.update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketProjects).Delete([]byte(id)); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(graphBucket(id)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete project %s: %w", id, err)
+	}
+	delete(r.projects, id)
+	delete(r.graphs, id)
+	return nil
+}
+
+func (r *BoltProjectRepository) Graph(id string) (GraphRepository, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	graph, ok := r.graphs[id]
+	return graph, ok
+}
+
+// Compact rewrites the database into a fresh file, reclaiming the space left
+// by deleted and overwritten records, and swaps it in place of the old one.
+func (r *BoltProjectRepository) Compact() error {
+	r.dbMu.Lock()
+	defer r.dbMu.Unlock()
+
+	tmp := r.path + ".compact"
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return fmt.Errorf("compact graph store: %w", err)
+	}
+	err = bolt.Compact(dst, r.db, 0)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact graph store: %w", err)
+	}
+
+	before := fileSize(r.path)
+	if err := r.db.Close(); err != nil {
+		return fmt.Errorf("compact graph store: %w", err)
+	}
+	renameErr := os.Rename(tmp, r.path)
+	db, err := bolt.Open(r.path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		r.db = nil
+		return fmt.Errorf("reopen graph store %s: %w", r.path, err)
+	}
+	r.db = db
+	if renameErr != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("compact graph store: %w", renameErr)
+	}
+	slog.Info("graph store compacted", "path", r.path, "bytes_before", before, "bytes_after", fileSize(r.path))
+	return nil
+}
+
+// Close releases the database file.
+func (r *BoltProjectRepository) Close() error {
+	r.dbMu.Lock()
+	defer r.dbMu.Unlock()
+	if r.db == nil {
+		return errors.New("graph store already closed")
+	}
+	err := r.db.Close()
+	r.db = nil
+	return err
+}
+
+// BoltGraphRepository is the graph of one project in a BoltProjectRepository.
+//
+// The graph is held in memory for reads, as in InMemoryGraphRepository, and
+// loaded from the file on open. Every write, and every Batch of writes, is
+// committed to the file in a single transaction before the call returns, so a
+// crash never leaves a partially applied change behind: the file reflects the
+// graph as of the last completed call.
+type BoltGraphRepository struct {
+	*InMemoryGraphRepository
+	store  *BoltProjectRepository
+	bucket []byte
+}
+
+// load replaces the in-memory graph with the one stored in the file. Nodes are
+// loaded before edges so that every edge finds its endpoints; edges whose
+// endpoints are missing are skipped.
+func (r *BoltGraphRepository) load(tx *bolt.Tx) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+
+	b := tx.Bucket(r.bucket)
+	if b == nil {
+		return nil
+	}
+	if err := b.Bucket(bucketNodes).ForEach(func(k, v []byte) error {
+		var node models.CodeNode
+		if err := json.Unmarshal(v, &node); err != nil {
+			return fmt.Errorf("node %s: %w", k, err)
+		}
+		r.saveNode(&node)
+		return nil
+	}); err != nil {
+		return err
+	}
+	var skipped int
+	if err := b.Bucket(bucketEdges).ForEach(func(k, v []byte) error {
+		var edge models.Edge
+		if err := json.Unmarshal(v, &edge); err != nil {
+			return fmt.Errorf("edge %s: %w", k, err)
+		}
+		if err := r.saveEdge(&edge); err != nil {
+			skipped++
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if skipped > 0 {
+		slog.Warn("dangling edges skipped", "bucket", string(r.bucket), "count", skipped)
+	}
+	// Stores written before the manifest existed have no files bucket.
+	if files := b.Bucket(bucketFiles); files != nil {
+		return files.ForEach(func(k, v []byte) error {
+			var file models.FileRecord
+			if err := json.Unmarshal(v, &file); err != nil {
+				return fmt.Errorf("file %s: %w", k, err)
+			}
+			r.saveFiles([]*models.FileRecord{&file})
+			return nil
+		})
+	}
+	return nil
+}
+
+// write applies fn to the in-memory graph and commits the changes it made to
+// the file, even when fn fails, so that the file follows the graph. It
+// returns the error of fn, or of the commit; the in-memory graph stays
+// authoritative until the next restart either way.
+func (r *BoltGraphRepository) write(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.journal = newJournal()
+	defer func() { r.journal = nil }()
+	err := fn()
+	if cerr := r.commit(r.journal); cerr != nil {
+		return fmt.Errorf("persist graph changes to %s: %w", r.store.path, cerr)
+	}
+	return err
+}
+
+// writeLogged is write for the methods that have no way to report a failed
+// commit, which is logged instead.
+func (r *BoltGraphRepository) writeLogged(fn func()) {
+	if err := r.write(func() error { fn(); return nil }); err != nil {
+		slog.Error("persist graph changes", "bucket", string(r.bucket), "err", err)
+	}
+}
+
+// commit writes the changes recorded in j in one transaction.
+func (r *BoltGraphRepository) commit(j *journal) error {
+	if !j.cleared && len(j.nodes) == 0 && len(j.edges) == 0 && len(j.files) == 0 {
+		return nil
+	}
+	return r.store.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(r.bucket)
+		if b == nil {
+			// The project was deleted while the change was being made.
+			return nil
+		}
+		if j.cleared {
+			for _, name := range [][]byte{bucketNodes, bucketEdges, bucketFiles} {
+				if err := b.DeleteBucket(name); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+					return err
+				}
+			}
+		}
+		if err := createGraphBuckets(b); err != nil {
+			return err
+		}
+		nodes, edges, files := b.Bucket(bucketNodes), b.Bucket(bucketEdges), b.Bucket(bucketFiles)
+		for id, node := range j.nodes {
+			if err := put(nodes, id, node); err != nil {
+				return err
+			}
+		}
+		for id, edge := range j.edges {
+			if err := put(edges, id, edge); err != nil {
+				return err
+			}
+		}
+		for path, file := range j.files {
+			if err := put(files, path, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *BoltGraphRepository) SaveNode(node *models.CodeNode) {
+	r.writeLogged(func() { r.saveNode(node) })
+}
+
+func (r *BoltGraphRepository) DeleteNode(id string) {
+	r.writeLogged(func() { r.deleteNode(id) })
+}
+
+func (r *BoltGraphRepository) ReplaceFileNodes(filePath string, nodes []*models.CodeNode) {
+	r.writeLogged(func() { r.replaceFileNodes(filePath, nodes) })
+}
+
+func (r *BoltGraphRepository) SaveEdge(edge *models.Edge) error {
+	return r.write(func() error { return r.saveEdge(edge) })
+}
+
+func (r *BoltGraphRepository) DeleteEdge(id string) {
+	r.writeLogged(func() { r.deleteEdge(id) })
+}
+
+func (r *BoltGraphRepository) SaveFiles(files ...*models.FileRecord) {
+	r.writeLogged(func() { r.saveFiles(files) })
+}
+
+func (r *BoltGraphRepository) DeleteFiles(paths ...string) {
+	r.writeLogged(func() { r.deleteFiles(paths) })
+}
+
+func (r *BoltGraphRepository) Clear() {
+	r.writeLogged(func() { r.reset() })
+}
+
+// Batch commits the changes of fn in a single transaction.
+func (r *BoltGraphRepository) Batch(fn func(w GraphWriter) error) error {
+	return r.write(func() error { return fn(graphWriter{r.InMemoryGraphRepository}) })
+}
+
+func createGraphBuckets(b *bolt.Bucket) error {
+	for _, name := range [][]byte{bucketNodes, bucketEdges, bucketFiles} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put stores v as JSON under id, or deletes id when v is a nil pointer.
+func put[T any](b *bolt.Bucket, id string, v *T) error {
+	if v == nil {
+		return b.Delete([]byte(id))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", id, err)
+	}
+	return b.Put([]byte(id), data)
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// openBolt opens the store at path, failing the test on error.
+func openBolt(t *testing.T, path string) *BoltProjectRepository {
+	t.Helper()
+	store, err := NewBoltProjectRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func boltGraph(t *testing.T, store *BoltProjectRepository, project string) GraphRepository {
+	t.Helper()
+	graph, ok := store.Graph(project)
+	if !ok {
+		t.Fatalf("project %s not loaded", project)
+	}
+	return graph
+}
+
+func TestBoltGraphRepository(t *testing.T) {
+	// Each case ends by reopening the file, which must hold what the
+	// repository held in memory.
+	testGraphRepository(t, func(t *testing.T) GraphRepository {
+		path := filepath.Join(t.TempDir(), "graph.db")
+		store := openBolt(t, path)
+		if err := store.CreateProject(&models.Project{ID: "p", Name: "p"}); err != nil {
+			t.Fatal(err)
+		}
+		graph := boltGraph(t, store, "p")
+		t.Cleanup(func() {
+			nodes, edges := describe(graph)
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			reopened := openBolt(t, path)
+			defer reopened.Close()
+			gotNodes, gotEdges := describe(boltGraph(t, reopened, "p"))
+			if gotNodes != nodes || gotEdges != edges {
+				t.Errorf("reopened graph = %q %q, want %q %q", gotNodes, gotEdges, nodes, edges)
+			}
+		})
+		return graph
+	})
+}
+
+func TestBoltRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.db")
+	store := openBolt(t, path)
+	for _, id := range []string{"orders", "users"} {
+		if err := store.CreateProject(&models.Project{ID: id, Name: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := &models.CodeNode{
+		ID:           "n1",
+		Type:         models.NodeRoute,
+		Name:         "GET /orders",
+		Language:     "go",
+		FilePath:     "/src/main.go",
+		LineNumber:   12,
+		Signature:    "func list()",
+		Comments:     []string{"// list orders"},
+		Metadata:     map[string]interface{}{"method": "GET", "path": "/orders"},
+		Dependencies: []string{"n2"},
+	}
+	edge := models.NewEdge("n2", "n1", models.EdgeHandlesRoute)
+	edge.Attributes = map[string]interface{}{"framework": "gin"}
+	record := &models.FileRecord{Path: "/src/main.go", Size: 42, Hash: "abc", Root: "/src", Nodes: []string{"n1", "n2"}}
+	err := boltGraph(t, store, "orders").Batch(func(w GraphWriter) error {
+		w.ReplaceFileNodes("/src/main.go", []*models.CodeNode{n, {ID: "n2", Type: models.NodeFunction, Name: "list", FilePath: "/src/main.go"}})
+		w.SaveFiles(record)
+		return w.SaveEdge(edge)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openBolt(t, path)
+	defer store.Close()
+	if got := len(store.ListProjects()); got != 2 {
+		t.Errorf("projects = %d, want 2", got)
+	}
+	orders := boltGraph(t, store, "orders")
+	// The metadata holds strings only, which JSON decodes as they were saved
+	if got, ok := orders.GetNode("n1"); !ok || !reflect.DeepEqual(got, n) {
+		t.Errorf("node = %+v, want %+v", got, n)
+	}
+	if got := orders.OutEdges("n2"); len(got) != 1 || !reflect.DeepEqual(got[0], edge) {
+		t.Errorf("edges of n2 = %+v, want %+v", got, edge)
+	}
+	if got, ok := orders.GetFile("/src/main.go"); !ok || !reflect.DeepEqual(got, record) {
+		t.Errorf("file record = %+v, want %+v", got, record)
+	}
+	if nodes := boltGraph(t, store, "users").GetAllNodes(); len(nodes) != 0 {
+		t.Errorf("nodes leaked into another project: %v", nodes)
+	}
+
+	if err := store.DeleteProject("orders"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store = openBolt(t, path)
+	if _, ok := store.Graph("orders"); ok {
+		t.Error("deleted project loaded again")
+	}
+}
+
+func TestBoltCommitError(t *testing.T) {
+	store := openBolt(t, filepath.Join(t.TempDir(), "graph.db"))
+	if err := store.CreateProject(&models.Project{ID: "p", Name: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	graph := boltGraph(t, store, "p")
+	graph.ReplaceFileNodes("a.go", []*models.CodeNode{node("a1", "a.go"), node("a2", "a.go")})
+	store.Close()
+
+	err := graph.Batch(func(w GraphWriter) error {
+		return w.SaveEdge(models.NewEdge("a1", "a2", models.EdgeCalls))
+	})
+	if err == nil {
+		t.Error("Batch on a closed store succeeded")
+	}
+	if err := graph.SaveEdge(models.NewEdge("a2", "a1", models.EdgeCalls)); err == nil {
+		t.Error("SaveEdge on a closed store succeeded")
+	}
+}
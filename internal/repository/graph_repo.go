@@ -1,33 +1,151 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/chinmay-sawant/gosourcemapper/internal/models"
 )
 
+// ErrDanglingEdge is returned when an edge refers to a node that is not stored.
+var ErrDanglingEdge = errors.New("edge endpoint not found")
+
+// GraphRepository stores nodes and the edges between them.
+//
+// Consistency rules:
+//   - an edge can only be saved once both of its endpoints are stored;
+//   - deleting a node deletes every edge that starts or ends at it;
+//   - replacing the nodes of a file drops the outgoing edges of its previous
+//     nodes (they are re-derived by linking) and the incoming edges of nodes
+//     that no longer exist, but keeps incoming edges of nodes whose ID survives.
 type GraphRepository interface {
 	SaveNode(node *models.CodeNode)
 	GetNode(id string) (*models.CodeNode, bool)
 	GetAllNodes() []*models.CodeNode
+	DeleteNode(id string)
+	// ReplaceFileNodes atomically swaps every node previously stored for
+	// filePath with nodes. Passing no nodes removes the file from the graph.
+	ReplaceFileNodes(filePath string, nodes []*models.CodeNode)
+
+	SaveEdge(edge *models.Edge) error
+	// GetAllEdges returns every edge of one of kinds, or all edges when no kind is given.
+	GetAllEdges(kinds ...models.EdgeKind) []*models.Edge
+	// OutEdges returns the edges starting at nodeID, optionally filtered by kind.
+	OutEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge
+	// InEdges returns the edges ending at nodeID, optionally filtered by kind.
+	InEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge
+	// DeleteEdge removes an edge; unknown IDs are ignored.
+	DeleteEdge(id string)
+
+	// Files returns the manifest of the scanned files, keyed by path.
+	Files() map[string]*models.FileRecord
+	// GetFile returns the manifest record of path.
+	GetFile(path string) (*models.FileRecord, bool)
+	// SaveFiles records files in the manifest, replacing records of the same path.
+	SaveFiles(files ...*models.FileRecord)
+	// DeleteFiles removes paths from the manifest; their nodes are left alone.
+	DeleteFiles(paths ...string)
+
+	// Clear removes every node, edge and file record.
 	Clear()
+
+	// Batch calls fn with the repository locked for writing, so that readers
+	// see the graph as it was before fn or after it, and applies the changes
+	// fn makes through w as one: a durable store commits them in a single
+	// transaction. It returns the error of fn, or of the commit.
+	Batch(fn func(w GraphWriter) error) error
+}
+
+// GraphWriter is the view of a GraphRepository handed to Batch. Its methods
+// are those of GraphRepository.
+type GraphWriter interface {
+	ReplaceFileNodes(filePath string, nodes []*models.CodeNode)
+	SaveEdge(edge *models.Edge) error
+	OutEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge
+	DeleteEdge(id string)
+	GetFile(path string) (*models.FileRecord, bool)
+	SaveFiles(files ...*models.FileRecord)
+	DeleteFiles(paths ...string)
 }
 
+// idSet is a set of node or edge IDs.
+type idSet map[string]struct{}
+
+func (s idSet) add(id string) { s[id] = struct{}{} }
+
 type InMemoryGraphRepository struct {
 	mu    sync.RWMutex
 	nodes map[string]*models.CodeNode
+	files map[string]idSet // file path -> node IDs
+	edges map[string]*models.Edge
+	out   map[string]idSet // node ID -> IDs of edges starting there
+	in    map[string]idSet // node ID -> IDs of edges ending there
+	// manifest records the scanned files, by path.
+	manifest map[string]*models.FileRecord
+
+	// journal, when set, records every change so that a durable store can
+	// persist exactly what a call modified.
+	journal *journal
 }
 
-func NewInMemoryGraphRepository() *InMemoryGraphRepository {
-	return &InMemoryGraphRepository{
+// journal is the set of changes made by one repository call. A nil value
+// marks a deletion.
+type journal struct {
+	cleared bool
+	nodes   map[string]*models.CodeNode
+	edges   map[string]*models.Edge
+	files   map[string]*models.FileRecord
+}
+
+func newJournal() *journal {
+	return &journal{
 		nodes: make(map[string]*models.CodeNode),
+		edges: make(map[string]*models.Edge),
+		files: make(map[string]*models.FileRecord),
+	}
+}
+
+func NewInMemoryGraphRepository() *InMemoryGraphRepository {
+	r := &InMemoryGraphRepository{}
+	r.reset()
+	return r
+}
+
+func (r *InMemoryGraphRepository) reset() {
+	if r.journal != nil {
+		*r.journal = *newJournal()
+		r.journal.cleared = true
 	}
+	r.nodes = make(map[string]*models.CodeNode)
+	r.files = make(map[string]idSet)
+	r.edges = make(map[string]*models.Edge)
+	r.out = make(map[string]idSet)
+	r.in = make(map[string]idSet)
+	r.manifest = make(map[string]*models.FileRecord)
 }
 
 func (r *InMemoryGraphRepository) SaveNode(node *models.CodeNode) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.saveNode(node)
+}
+
+func (r *InMemoryGraphRepository) saveNode(node *models.CodeNode) {
+	if old, ok := r.nodes[node.ID]; ok && old.FilePath != node.FilePath {
+		delete(r.files[old.FilePath], node.ID)
+	}
 	r.nodes[node.ID] = node
+	if r.journal != nil {
+		r.journal.nodes[node.ID] = node
+	}
+	ids, ok := r.files[node.FilePath]
+	if !ok {
+		ids = make(idSet)
+		r.files[node.FilePath] = ids
+	}
+	ids.add(node.ID)
 }
 
 func (r *InMemoryGraphRepository) GetNode(id string) (*models.CodeNode, bool) {
@@ -47,8 +165,224 @@ func (r *InMemoryGraphRepository) GetAllNodes() []*models.CodeNode {
 	return nodes
 }
 
+func (r *InMemoryGraphRepository) DeleteNode(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteNode(id)
+}
+
+func (r *InMemoryGraphRepository) deleteNode(id string) {
+	node, ok := r.nodes[id]
+	if !ok {
+		return
+	}
+	for edgeID := range r.out[id] {
+		r.deleteEdge(edgeID)
+	}
+	for edgeID := range r.in[id] {
+		r.deleteEdge(edgeID)
+	}
+	delete(r.files[node.FilePath], id)
+	if len(r.files[node.FilePath]) == 0 {
+		delete(r.files, node.FilePath)
+	}
+	delete(r.nodes, id)
+	if r.journal != nil {
+		r.journal.nodes[id] = nil
+	}
+}
+
+func (r *InMemoryGraphRepository) ReplaceFileNodes(filePath string, nodes []*models.CodeNode) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replaceFileNodes(filePath, nodes)
+}
+
+func (r *InMemoryGraphRepository) replaceFileNodes(filePath string, nodes []*models.CodeNode) {
+	keep := make(idSet, len(nodes))
+	for _, node := range nodes {
+		keep.add(node.ID)
+	}
+	for id := range r.files[filePath] {
+		if _, ok := keep[id]; !ok {
+			r.deleteNode(id)
+			continue
+		}
+		for edgeID := range r.out[id] {
+			r.deleteEdge(edgeID)
+		}
+	}
+	for _, node := range nodes {
+		r.saveNode(node)
+	}
+}
+
+func (r *InMemoryGraphRepository) SaveEdge(edge *models.Edge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saveEdge(edge)
+}
+
+func (r *InMemoryGraphRepository) saveEdge(edge *models.Edge) error {
+	for _, id := range []string{edge.Source, edge.Target} {
+		if _, ok := r.nodes[id]; !ok {
+			return fmt.Errorf("%w: %s %s", ErrDanglingEdge, edge.Kind, id)
+		}
+	}
+	r.edges[edge.ID] = edge
+	for _, link := range []struct {
+		index  map[string]idSet
+		nodeID string
+	}{{r.out, edge.Source}, {r.in, edge.Target}} {
+		ids, ok := link.index[link.nodeID]
+		if !ok {
+			ids = make(idSet)
+			link.index[link.nodeID] = ids
+		}
+		ids.add(edge.ID)
+	}
+	if r.journal != nil {
+		r.journal.edges[edge.ID] = edge
+	}
+	return nil
+}
+
+func (r *InMemoryGraphRepository) DeleteEdge(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteEdge(id)
+}
+
+func (r *InMemoryGraphRepository) deleteEdge(id string) {
+	edge, ok := r.edges[id]
+	if !ok {
+		return
+	}
+	delete(r.out[edge.Source], id)
+	if len(r.out[edge.Source]) == 0 {
+		delete(r.out, edge.Source)
+	}
+	delete(r.in[edge.Target], id)
+	if len(r.in[edge.Target]) == 0 {
+		delete(r.in, edge.Target)
+	}
+	delete(r.edges, id)
+	if r.journal != nil {
+		r.journal.edges[id] = nil
+	}
+}
+
+func (r *InMemoryGraphRepository) GetAllEdges(kinds ...models.EdgeKind) []*models.Edge {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	edges := make([]*models.Edge, 0, len(r.edges))
+	for _, edge := range r.edges {
+		if edge.HasKind(kinds...) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func (r *InMemoryGraphRepository) OutEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.out[nodeID], kinds)
+}
+
+func (r *InMemoryGraphRepository) InEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.in[nodeID], kinds)
+}
+
+func (r *InMemoryGraphRepository) collect(ids idSet, kinds []models.EdgeKind) []*models.Edge {
+	var edges []*models.Edge
+	for id := range ids {
+		if edge := r.edges[id]; edge.HasKind(kinds...) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func (r *InMemoryGraphRepository) Files() map[string]*models.FileRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.manifest)
+}
+
+func (r *InMemoryGraphRepository) GetFile(path string) (*models.FileRecord, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.manifest[path]
+	return f, ok
+}
+
+func (r *InMemoryGraphRepository) SaveFiles(files ...*models.FileRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveFiles(files)
+}
+
+func (r *InMemoryGraphRepository) saveFiles(files []*models.FileRecord) {
+	for _, f := range files {
+		r.manifest[f.Path] = f
+		if r.journal != nil {
+			r.journal.files[f.Path] = f
+		}
+	}
+}
+
+func (r *InMemoryGraphRepository) DeleteFiles(paths ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteFiles(paths)
+}
+
+func (r *InMemoryGraphRepository) deleteFiles(paths []string) {
+	for _, path := range paths {
+		delete(r.manifest, path)
+		if r.journal != nil {
+			r.journal.files[path] = nil
+		}
+	}
+}
+
 func (r *InMemoryGraphRepository) Clear() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.nodes = make(map[string]*models.CodeNode)
+	r.reset()
 }
+
+func (r *InMemoryGraphRepository) Batch(fn func(w GraphWriter) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fn(graphWriter{r})
+}
+
+// graphWriter is the GraphWriter of a repository whose lock is held.
+type graphWriter struct {
+	r *InMemoryGraphRepository
+}
+
+func (w graphWriter) ReplaceFileNodes(filePath string, nodes []*models.CodeNode) {
+	w.r.replaceFileNodes(filePath, nodes)
+}
+
+func (w graphWriter) SaveEdge(edge *models.Edge) error { return w.r.saveEdge(edge) }
+
+func (w graphWriter) OutEdges(nodeID string, kinds ...models.EdgeKind) []*models.Edge {
+	return w.r.collect(w.r.out[nodeID], kinds)
+}
+
+func (w graphWriter) DeleteEdge(id string) { w.r.deleteEdge(id) }
+
+func (w graphWriter) GetFile(path string) (*models.FileRecord, bool) {
+	f, ok := w.r.manifest[path]
+	return f, ok
+}
+
+func (w graphWriter) SaveFiles(files ...*models.FileRecord) { w.r.saveFiles(files) }
+
+func (w graphWriter) DeleteFiles(paths ...string) { w.r.deleteFiles(paths) }
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func node(id, file string) *models.CodeNode {
+	return &models.CodeNode{ID: id, Type: models.NodeFunction, Name: id, FilePath: file}
+}
+
+// seed stores nodes a1 and a2 of a.go, b1 of b.go and the edges
+// a1 -CALLS-> b1, b1 -CALLS-> a2 and a1 -HANDLES_ROUTE-> a2.
+func seed(t *testing.T, r GraphRepository) {
+	t.Helper()
+	r.ReplaceFileNodes("a.go", []*models.CodeNode{node("a1", "a.go"), node("a2", "a.go")})
+	r.ReplaceFileNodes("b.go", []*models.CodeNode{node("b1", "b.go")})
+	for _, e := range []*models.Edge{
+		models.NewEdge("a1", "b1", models.EdgeCalls),
+		models.NewEdge("b1", "a2", models.EdgeCalls),
+		models.NewEdge("a1", "a2", models.EdgeHandlesRoute),
+	} {
+		if err := r.SaveEdge(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// describe lists the nodes of r as "id@file" and its edges as
+// "source-KIND->target", sorted.
+func describe(r GraphRepository) (nodes, edges string) {
+	var ns, es []string
+	for _, n := range r.GetAllNodes() {
+		ns = append(ns, n.ID+"@"+n.FilePath)
+	}
+	for _, e := range r.GetAllEdges() {
+		es = append(es, e.Source+"-"+string(e.Kind)+"->"+e.Target)
+	}
+	sort.Strings(ns)
+	sort.Strings(es)
+	return strings.Join(ns, " "), strings.Join(es, " ")
+}
+
+func edgeNames(edges []*models.Edge) string {
+	var names []string
+	for _, e := range edges {
+		names = append(names, e.Source+"-"+string(e.Kind)+"->"+e.Target)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// testGraphRepository checks the consistency rules of GraphRepository on the
+// repositories returned by open, each of them empty.
+func testGraphRepository(t *testing.T, open func(t *testing.T) GraphRepository) {
+	tests := []struct {
+		name      string
+		change    func(t *testing.T, r GraphRepository)
+		wantNodes string
+		wantEdges string
+	}{
+		{
+			name:      "seeded",
+			change:    func(*testing.T, GraphRepository) {},
+			wantNodes: "a1@a.go a2@a.go b1@b.go",
+			wantEdges: "a1-CALLS->b1 a1-HANDLES_ROUTE->a2 b1-CALLS->a2",
+		},
+		{
+			name: "dangling edge",
+			change: func(t *testing.T, r GraphRepository) {
+				if err := r.SaveEdge(models.NewEdge("a1", "missing", models.EdgeCalls)); !errors.Is(err, ErrDanglingEdge) {
+					t.Errorf("SaveEdge to a missing node: error = %v, want ErrDanglingEdge", err)
+				}
+			},
+			wantNodes: "a1@a.go a2@a.go b1@b.go",
+			wantEdges: "a1-CALLS->b1 a1-HANDLES_ROUTE->a2 b1-CALLS->a2",
+		},
+		{
+			name:      "delete node",
+			change:    func(_ *testing.T, r GraphRepository) { r.DeleteNode("a2") },
+			wantNodes: "a1@a.go b1@b.go",
+			wantEdges: "a1-CALLS->b1",
+		},
+		{
+			name: "replace file keeping IDs",
+			change: func(_ *testing.T, r GraphRepository) {
+				r.ReplaceFileNodes("a.go", []*models.CodeNode{node("a1", "a.go"), node("a2", "a.go")})
+			},
+			// Outgoing edges are derived again by linking; incoming ones stay.
+			wantNodes: "a1@a.go a2@a.go b1@b.go",
+			wantEdges: "b1-CALLS->a2",
+		},
+		{
+			name: "replace file dropping a node",
+			change: func(_ *testing.T, r GraphRepository) {
+				r.ReplaceFileNodes("a.go", []*models.CodeNode{node("a1", "a.go"), node("a3", "a.go")})
+			},
+			wantNodes: "a1@a.go a3@a.go b1@b.go",
+			wantEdges: "",
+		},
+		{
+			name:      "remove file",
+			change:    func(_ *testing.T, r GraphRepository) { r.ReplaceFileNodes("b.go", nil) },
+			wantNodes: "a1@a.go a2@a.go",
+			wantEdges: "a1-HANDLES_ROUTE->a2",
+		},
+		{
+			name: "node moved to another file",
+			change: func(_ *testing.T, r GraphRepository) {
+				r.ReplaceFileNodes("c.go", []*models.CodeNode{node("b1", "c.go")})
+				r.ReplaceFileNodes("b.go", nil)
+			},
+			wantNodes: "a1@a.go a2@a.go b1@c.go",
+			wantEdges: "a1-CALLS->b1 a1-HANDLES_ROUTE->a2 b1-CALLS->a2",
+		},
+		{
+			name: "batch",
+			change: func(t *testing.T, r GraphRepository) {
+				err := r.Batch(func(w GraphWriter) error {
+					if got := edgeNames(w.OutEdges("a1", models.EdgeCalls)); got != "a1-CALLS->b1" {
+						t.Errorf("OutEdges(a1, CALLS) in batch = %s", got)
+					}
+					w.DeleteEdge(models.NewEdge("a1", "b1", models.EdgeCalls).ID)
+					w.ReplaceFileNodes("c.go", []*models.CodeNode{node("c1", "c.go")})
+					return w.SaveEdge(models.NewEdge("c1", "a1", models.EdgeCalls))
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantNodes: "a1@a.go a2@a.go b1@b.go c1@c.go",
+			wantEdges: "a1-HANDLES_ROUTE->a2 b1-CALLS->a2 c1-CALLS->a1",
+		},
+		{
+			name:   "clear",
+			change: func(_ *testing.T, r GraphRepository) { r.Clear() },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := open(t)
+			seed(t, r)
+			tt.change(t, r)
+			nodes, edges := describe(r)
+			if nodes != tt.wantNodes {
+				t.Errorf("nodes = %q, want %q", nodes, tt.wantNodes)
+			}
+			if edges != tt.wantEdges {
+				t.Errorf("edges = %q, want %q", edges, tt.wantEdges)
+			}
+		})
+	}
+
+	t.Run("edge queries", func(t *testing.T) {
+		r := open(t)
+		seed(t, r)
+		for _, q := range []struct {
+			name string
+			got  []*models.Edge
+			want string
+		}{
+			{"out", r.OutEdges("a1"), "a1-CALLS->b1 a1-HANDLES_ROUTE->a2"},
+			{"out of kind", r.OutEdges("a1", models.EdgeHandlesRoute), "a1-HANDLES_ROUTE->a2"},
+			{"in", r.InEdges("a2"), "a1-HANDLES_ROUTE->a2 b1-CALLS->a2"},
+			{"in of kinds", r.InEdges("a2", models.EdgeCalls, models.EdgeImplements), "b1-CALLS->a2"},
+			{"all of kind", r.GetAllEdges(models.EdgeCalls), "a1-CALLS->b1 b1-CALLS->a2"},
+			{"none", r.OutEdges("a2"), ""},
+		} {
+			if got := edgeNames(q.got); got != q.want {
+				t.Errorf("%s: edges = %q, want %q", q.name, got, q.want)
+			}
+		}
+	})
+
+	t.Run("manifest", func(t *testing.T) {
+		r := open(t)
+		r.SaveFiles(&models.FileRecord{Path: "a.go", Hash: "1"}, &models.FileRecord{Path: "b.go", Hash: "2"})
+		r.SaveFiles(&models.FileRecord{Path: "a.go", Hash: "3"})
+		r.DeleteFiles("b.go", "missing.go")
+		if f, ok := r.GetFile("a.go"); !ok || f.Hash != "3" {
+			t.Errorf("GetFile(a.go) = %+v, %v; want the second record", f, ok)
+		}
+		if files := r.Files(); len(files) != 1 {
+			t.Errorf("Files() = %v, want a.go only", files)
+		}
+	})
+}
+
+func TestInMemoryGraphRepository(t *testing.T) {
+	testGraphRepository(t, func(*testing.T) GraphRepository { return NewInMemoryGraphRepository() })
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+)
+
+// Open returns the project repository selected by cfg. Persistent repositories
+// also implement io.Closer and must be closed when no longer used.
+func Open(cfg config.StorageConfig) (ProjectRepository, error) {
+	switch cfg.Driver {
+	case config.StorageMemory, "":
+		return NewInMemoryProjectRepository(), nil
+	case config.StorageBolt:
+		repo, err := NewBoltProjectRepository(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.CompactOnStart {
+			if err := repo.Compact(); err != nil {
+				repo.Close()
+				return nil, err
+			}
+		}
+		return repo, nil
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
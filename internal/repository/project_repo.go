@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+var (
+	// ErrProjectExists is returned when creating a project whose ID is taken.
+	ErrProjectExists = errors.New("project already exists")
+	// ErrProjectNotFound is returned when a project ID is not stored.
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+// ProjectRepository stores projects, each owning a separate GraphRepository.
+type ProjectRepository interface {
+	CreateProject(project *models.Project) error
+	GetProject(id string) (*models.Project, bool)
+	// ListProjects returns every project, oldest first.
+	ListProjects() []*models.Project
+	// DeleteProject removes a project together with its graph.
+	DeleteProject(id string) error
+	// Graph returns the graph of a project.
+	Graph(id string) (GraphRepository, bool)
+}
+
+type InMemoryProjectRepository struct {
+	mu       sync.RWMutex
+	projects map[string]*models.Project
+	graphs   map[string]*InMemoryGraphRepository
+}
+
+func NewInMemoryProjectRepository() *InMemoryProjectRepository {
+	return &InMemoryProjectRepository{
+		projects: make(map[string]*models.Project),
+		graphs:   make(map[string]*InMemoryGraphRepository),
+	}
+}
+
+func (r *InMemoryProjectRepository) CreateProject(project *models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[project.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrProjectExists, project.ID)
+	}
+	r.projects[project.ID] = project
+	r.graphs[project.ID] = NewInMemoryGraphRepository()
+	return nil
+}
+
+func (r *InMemoryProjectRepository) GetProject(id string) (*models.Project, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	project, ok := r.projects[id]
+	return project, ok
+}
+
+func (r *InMemoryProjectRepository) ListProjects() []*models.Project {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedProjects(r.projects)
+}
+
+func (r *InMemoryProjectRepository) DeleteProject(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, id)
+	}
+	delete(r.projects, id)
+	delete(r.graphs, id)
+	return nil
+}
+
+func (r *InMemoryProjectRepository) Graph(id string) (GraphRepository, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	graph, ok := r.graphs[id]
+	return graph, ok
+}
+
+// sortedProjects orders projects by creation time, then ID.
+func sortedProjects(projects map[string]*models.Project) []*models.Project {
+	sorted := make([]*models.Project, 0, len(projects))
+	for _, p := range projects {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
@@ -5,15 +5,37 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(scanHandler *handlers.ScanHandler) *gin.Engine {
+func SetupRouter(projectHandler *handlers.ProjectHandler, scanHandler *handlers.ScanHandler, jobHandler *handlers.JobHandler, watchHandler *handlers.WatchHandler, diffHandler *handlers.DiffHandler) *gin.Engine {
 	r := gin.Default()
 
 	v1 := r.Group("/v1")
 	{
-		v1.POST("/scan", scanHandler.ScanFile)
-		v1.POST("/upload", scanHandler.UploadZip)
-		v1.POST("/scan/dir", scanHandler.ScanDirectory)
-		v1.GET("/nodes", scanHandler.GetAllNodes)
+		v1.POST("/projects", projectHandler.CreateProject)
+		v1.GET("/projects", projectHandler.ListProjects)
+		v1.GET("/projects/:project", projectHandler.GetProject)
+		v1.DELETE("/projects/:project", projectHandler.DeleteProject)
+
+		v1.GET("/jobs/:id", jobHandler.GetJob)
+		v1.DELETE("/jobs/:id", jobHandler.CancelJob)
+		v1.GET("/jobs/:id/events", jobHandler.StreamEvents)
+
+		v1.GET("/watch", watchHandler.GetWatch)
+		v1.GET("/watch/events", watchHandler.StreamEvents)
+
+		v1.GET("/diff", diffHandler.GetDiff)
+	}
+
+	// Scans and queries are confined to one project's graph
+	project := v1.Group("/projects/:project")
+	{
+		project.POST("/scan", scanHandler.ScanFile)
+		project.POST("/upload", scanHandler.UploadZip)
+		project.POST("/scan/dir", scanHandler.ScanDirectory)
+		project.POST("/scan/git", scanHandler.ScanGit)
+		project.GET("/nodes", scanHandler.GetAllNodes)
+		project.GET("/nodes/:id/edges", scanHandler.GetNodeEdges)
+		project.GET("/edges", scanHandler.GetAllEdges)
+		project.GET("/routes", scanHandler.FindRoutes)
 	}
 
 	return r
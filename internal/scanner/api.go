@@ -1,8 +1,75 @@
 package scanner
 
-import "github.com/chinmay-sawant/gosourcemapper/internal/models"
+import (
+	"context"
+	"io/fs"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
 
 type Scanner interface {
-	// Scan parses the given file content and returns a list of CodeNodes
-	Scan(filePath string, content []byte) ([]*models.CodeNode, error)
+	// Scan parses the given file content and returns a list of CodeNodes.
+	// root is the directory of the scanned tree holding filePath: node IDs
+	// are derived from the path of the file relative to it (see
+	// models.IDPath), so they do not change with where the tree is checked
+	// out. An empty root derives them from filePath as given.
+	// It stops early with ctx.Err() once ctx is cancelled.
+	Scan(ctx context.Context, root, filePath string, content []byte) ([]*models.CodeNode, error)
+}
+
+// TreeScanner is a Scanner that may need other files of the tree holding the
+// scanned file, such as the build manifest of its package. Scan reads them
+// from disk; ScanTree reads them from the tree, for files that are not on
+// disk as they are scanned, like those of a git commit.
+type TreeScanner interface {
+	Scanner
+	// ScanTree is Scan for the file at filePath in tree, whose Root must be
+	// absolute and is the root of the scan.
+	ScanTree(ctx context.Context, tree Tree, filePath string, content []byte) ([]*models.CodeNode, error)
+}
+
+// Tree is a scanned file tree, handed to linkers once every file has been scanned.
+type Tree struct {
+	// FS holds the scanned files.
+	FS fs.FS
+	// Root is joined with paths in FS to form the FilePath of scanned nodes.
+	Root string
+	// Scope, when set, holds the files (as node FilePaths) to link. Relations
+	// starting elsewhere, and nodes derived elsewhere, are already known and
+	// may be left out. Nil links the whole tree.
+	Scope map[string]bool
+}
+
+// SkipDir reports whether directories called name are left out of scans and
+// of watch mode: hidden ones such as .git, those the go tool ignores (_*,
+// testdata, which covers __pycache__ too) and those holding third-party code
+// (vendor, node_modules). It applies below the root of a tree, never to the
+// root itself.
+func SkipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" || name == "node_modules"
+}
+
+// Linker resolves relations that span files, such as calls between packages,
+// once a whole tree has been scanned. It returns the edges it found along with
+// any nodes it derived; it may also enrich the given nodes in place. Linking
+// stops early with ctx.Err() once ctx is cancelled.
+type Linker interface {
+	Link(ctx context.Context, tree Tree, nodes []*models.CodeNode) (*models.Graph, error)
+}
+
+// ScopedLinker is a Linker able to relink part of a tree, so that a rescan
+// after a few files changed does not redo the work for the whole tree.
+// Linkers that are not scoped are cheap enough to run over every rescan.
+// The nodes a scoped linker derives must lie in files it links, so that those
+// derived before are kept along with the files left out of its scope.
+type ScopedLinker interface {
+	Linker
+	// Scope returns the files whose relations may have changed now that the
+	// given files (node FilePaths) were added, changed or removed. Link with
+	// that scope must find for them what a link of the whole tree would.
+	Scope(ctx context.Context, tree Tree, changed []string) (map[string]bool, error)
+	// EdgeKinds lists the kinds of edges the linker adds, so that the edges
+	// outside its scope can be told apart from those of other linkers.
+	EdgeKinds() []models.EdgeKind
 }
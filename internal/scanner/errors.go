@@ -0,0 +1,16 @@
+package scanner
+
+import "fmt"
+
+// ParseError is returned by a Scanner when a file is not valid source code.
+// Line and Column locate the first error; both are 1-based.
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
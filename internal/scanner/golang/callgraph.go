@@ -0,0 +1,143 @@
+package golang
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// linkCalls adds a CALLS edge from every function to each function or method
+// it calls, and mirrors the targets into CodeNode.Dependencies. A call through
+// an interface method reaches every implementation of that method.
+func (ls *linkState) linkCalls() {
+	ls.eachFile(func(pk *pkg, _ string, f *ast.File) {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			obj, _ := pk.info.Defs[fd.Name].(*types.Func)
+			caller, ok := ls.funcs[obj]
+			if !ok {
+				continue
+			}
+
+			targets := make(map[string]*models.CodeNode)
+			// Calls inside closures are attributed to the enclosing declaration.
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				for _, callee := range ls.callees(pk.info, call) {
+					if target, ok := ls.funcs[callee]; ok {
+						targets[target.ID] = target
+					}
+				}
+				return true
+			})
+
+			caller.Dependencies = caller.Dependencies[:0]
+			for id := range targets {
+				caller.Dependencies = append(caller.Dependencies, id)
+			}
+			sort.Strings(caller.Dependencies)
+			for _, id := range caller.Dependencies {
+				ls.addEdge(caller, targets[id], models.EdgeCalls, nil)
+			}
+		}
+	})
+}
+
+// callees returns the functions a call expression may invoke.
+func (ls *linkState) callees(info *types.Info, call *ast.CallExpr) []*types.Func {
+	fun := ast.Unparen(call.Fun)
+	// Explicit instantiations: f[int](x), T[K, V].m(x)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if fn, ok := info.Uses[f].(*types.Func); ok {
+			return []*types.Func{fn.Origin()}
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[f]; ok {
+			fn, ok := sel.Obj().(*types.Func)
+			if !ok {
+				return nil // a struct field of func type
+			}
+			if types.IsInterface(fn.Type().(*types.Signature).Recv().Type()) {
+				return ls.implementations(fn)
+			}
+			return []*types.Func{fn.Origin()}
+		}
+		// Package-qualified function: pkg.F(x)
+		if fn, ok := info.Uses[f.Sel].(*types.Func); ok {
+			return []*types.Func{fn.Origin()}
+		}
+	}
+	return nil
+}
+
+// implementations returns the concrete methods that may be invoked through the
+// interface method m, searching every named type declared in the program.
+func (ls *linkState) implementations(m *types.Func) []*types.Func {
+	if impls, ok := ls.impls[m]; ok {
+		return impls
+	}
+
+	iface, ok := m.Type().(*types.Signature).Recv().Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	var impls []*types.Func
+	ls.eachNamedType(func(named *types.Named) {
+		if typ := implementor(named, iface); typ != nil {
+			obj, _, _ := types.LookupFieldOrMethod(typ, true, m.Pkg(), m.Name())
+			if fn, ok := obj.(*types.Func); ok {
+				impls = append(impls, fn.Origin())
+			}
+		}
+	})
+	ls.impls[m] = impls
+	return impls
+}
+
+// eachNamedType calls fn for every concrete, non-generic named type declared
+// at package level in the program.
+func (ls *linkState) eachNamedType(fn func(named *types.Named)) {
+	for _, pk := range ls.prog.sortedPackages() {
+		if pk.types == nil {
+			continue
+		}
+		scope := pk.types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || types.IsInterface(named) || named.TypeParams().Len() > 0 {
+				continue
+			}
+			fn(named)
+		}
+	}
+}
+
+// implementor returns named or *named, whichever satisfies iface first, or nil.
+func implementor(named *types.Named, iface *types.Interface) types.Type {
+	for _, typ := range []types.Type{named, types.NewPointer(named)} {
+		if types.Implements(typ, iface) {
+			return typ
+		}
+	}
+	return nil
+}
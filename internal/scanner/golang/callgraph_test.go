@@ -0,0 +1,89 @@
+package golang
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func TestLinkCalls(t *testing.T) {
+	files := map[string]string{
+		"go.mod": goMod,
+		"store/store.go": `package store
+
+type Store interface {
+	Load(id string) string
+}
+
+type Memory struct{}
+
+func (m *Memory) Load(id string) string { return id }
+
+type Disk struct{}
+
+func (d Disk) Load(id string) string { return read(id) }
+
+func read(id string) string { return id }
+`,
+		"main.go": `package main
+
+import "example.com/app/store"
+
+func get(s store.Store) string {
+	return s.Load("x")
+}
+
+func run() {
+	defer func() { get(&store.Memory{}) }()
+	helper()
+}
+
+func helper() {}
+
+func main() {
+	run()
+	run()
+}
+`,
+	}
+	nodes, edges := linkTree(t, files)
+	byID := make(map[string]*models.CodeNode)
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	var got []string
+	for _, e := range edges {
+		if e.Kind == models.EdgeCalls {
+			got = append(got, byID[e.Source].Name+" -> "+byID[e.Target].Name)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"(Disk).Load -> read",
+		"get -> (Disk).Load", // through the interface, to every implementation
+		"get -> (Memory).Load",
+		"main -> run", // once, however many calls
+		"run -> get",  // from within a closure
+		"run -> helper",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, n := range nodes {
+		if n.Name != "run" {
+			continue
+		}
+		var deps []string
+		for _, id := range n.Dependencies {
+			deps = append(deps, byID[id].Name)
+		}
+		sort.Strings(deps)
+		if strings.Join(deps, " ") != "get helper" {
+			t.Errorf("Dependencies of run = %v, want [get helper]", deps)
+		}
+	}
+}
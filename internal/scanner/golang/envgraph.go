@@ -0,0 +1,240 @@
+package golang
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// envRead is a read of an environment variable with a constant name.
+type envRead struct {
+	key  string
+	def  string // default applied when the variable is unset, "" if none
+	call *ast.CallExpr
+}
+
+// linkEnv adds an ENV_VAR node per module for every environment variable read
+// with a constant name, and a READS_ENV edge from each function reading it.
+// Defaults are recognised in the usual forms:
+//
+//	v := os.Getenv("K"); if v == "" { v = "default" }
+//	v, ok := os.LookupEnv("K"); if !ok { v = "default" }
+//	cmp.Or(os.Getenv("K"), "default")
+//	getEnv("K", "default"), where getEnv passes its parameter to os.Getenv
+func (ls *linkState) linkEnv() {
+	helpers := ls.envHelpers()
+	vars := make(map[[2]string]*models.CodeNode) // module, key -> node
+
+	ls.eachFuncDecl(func(pk *pkg, filePath string, f *ast.File, fd *ast.FuncDecl, fn *types.Func) {
+		reads := envReads(pk.info, fd, helpers)
+		reader := ls.funcs[fn]
+		for _, read := range reads {
+			k := [2]string{pk.module, read.key}
+			node, ok := vars[k]
+			if !ok {
+				node = &models.CodeNode{
+					ID:         models.NewNodeID("go", pk.module, models.IDPath(ls.root, filePath), read.key, models.NodeEnvVar),
+					Type:       models.NodeEnvVar,
+					Name:       read.key,
+					Language:   "go",
+					FilePath:   filePath,
+					LineNumber: ls.prog.fset.Position(read.call.Pos()).Line,
+					Metadata:   map[string]interface{}{"module": pk.module},
+				}
+				vars[k] = node
+				ls.graph.Nodes = append(ls.graph.Nodes, node)
+			}
+			if _, ok := node.Metadata["default"]; !ok && read.def != "" {
+				node.Metadata["default"] = read.def
+			}
+			if reader != nil {
+				var attrs map[string]interface{}
+				if read.def != "" {
+					attrs = map[string]interface{}{"default": read.def}
+				}
+				ls.addEdge(reader, node, models.EdgeReadsEnv, attrs)
+			}
+		}
+	})
+}
+
+// envHelpers finds functions that read the variable named by one of their
+// parameters, returning the index of that parameter.
+func (ls *linkState) envHelpers() map[*types.Func]int {
+	helpers := make(map[*types.Func]int)
+	ls.eachFuncDecl(func(pk *pkg, _ string, _ *ast.File, fd *ast.FuncDecl, fn *types.Func) {
+		if fn == nil {
+			return
+		}
+		params := fn.Type().(*types.Signature).Params()
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isGetenv(pk.info, call) || len(call.Args) != 1 {
+				return true
+			}
+			if ident, ok := call.Args[0].(*ast.Ident); ok {
+				for i := 0; i < params.Len(); i++ {
+					if pk.info.Uses[ident] == params.At(i) {
+						helpers[fn] = i
+					}
+				}
+			}
+			return true
+		})
+	})
+	return helpers
+}
+
+// envReads returns the environment variables fd reads, each once, in order.
+func envReads(info *types.Info, fd *ast.FuncDecl, helpers map[*types.Func]int) []*envRead {
+	var reads []*envRead
+	byCall := make(map[*ast.CallExpr]*envRead)
+	add := func(call *ast.CallExpr, key, def string) {
+		read := &envRead{key: key, def: def, call: call}
+		reads = append(reads, read)
+		byCall[call] = read
+	}
+	// Variables assigned from a read, so that a later fallback can be attributed.
+	assigned := make(map[types.Object]*envRead)
+
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.CallExpr:
+			if isGetenv(info, t) && len(t.Args) == 1 {
+				if key, ok := constString(info, t.Args[0]); ok {
+					add(t, key, "")
+				}
+				return true
+			}
+			if fn := calledFunc(info, t); fn != nil {
+				if i, ok := helpers[fn]; ok && i < len(t.Args) {
+					if key, ok := constString(info, t.Args[i]); ok {
+						def := ""
+						for j, arg := range t.Args {
+							if v, ok := constString(info, arg); ok && j != i {
+								def = v
+								break
+							}
+						}
+						add(t, key, def)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.AssignStmt:
+			if len(t.Rhs) != 1 {
+				return true
+			}
+			call, ok := ast.Unparen(t.Rhs[0]).(*ast.CallExpr)
+			if !ok || byCall[call] == nil {
+				return true
+			}
+			for _, lhs := range t.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					if obj := objectOf(info, ident); obj != nil {
+						assigned[obj] = byCall[call]
+					}
+				}
+			}
+		case *ast.IfStmt:
+			// if v == "" { v = "default" }
+			read := mentionedRead(info, t.Cond, assigned)
+			if read == nil || read.def != "" {
+				return true
+			}
+			for _, stmt := range t.Body.List {
+				assign, ok := stmt.(*ast.AssignStmt)
+				if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+					continue
+				}
+				ident, ok := assign.Lhs[0].(*ast.Ident)
+				if !ok || assigned[objectOf(info, ident)] != read {
+					continue
+				}
+				if v, ok := constString(info, assign.Rhs[0]); ok {
+					read.def = v
+				}
+			}
+		case *ast.CallExpr:
+			// cmp.Or(os.Getenv("K"), "default")
+			fn := calledFunc(info, t)
+			if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "cmp" || fn.Name() != "Or" {
+				return true
+			}
+			for i, arg := range t.Args {
+				call, ok := ast.Unparen(arg).(*ast.CallExpr)
+				if !ok || byCall[call] == nil {
+					continue
+				}
+				for _, rest := range t.Args[i+1:] {
+					if v, ok := constString(info, rest); ok {
+						byCall[call].def = v
+						break
+					}
+				}
+			}
+		}
+		return true
+	})
+	return reads
+}
+
+// mentionedRead returns the read assigned to a variable used in expr.
+func mentionedRead(info *types.Info, expr ast.Expr, assigned map[types.Object]*envRead) *envRead {
+	var found *envRead
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && found == nil {
+			found = assigned[info.Uses[ident]]
+		}
+		return found == nil
+	})
+	return found
+}
+
+func objectOf(info *types.Info, ident *ast.Ident) types.Object {
+	if obj := info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return info.Uses[ident]
+}
+
+// isGetenv reports whether call is os.Getenv or os.LookupEnv.
+func isGetenv(info *types.Info, call *ast.CallExpr) bool {
+	fn := calledFunc(info, call)
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == "os" && (fn.Name() == "Getenv" || fn.Name() == "LookupEnv")
+}
+
+// calledFunc returns the statically called function of call, or nil.
+func calledFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[fun].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[fun]; ok {
+			fn, _ := sel.Obj().(*types.Func)
+			return fn
+		}
+		fn, _ := info.Uses[fun.Sel].(*types.Func)
+		return fn
+	case *ast.IndexExpr:
+		// Explicit instantiation: cmp.Or[string](...)
+		return calledFunc(info, &ast.CallExpr{Fun: fun.X})
+	}
+	return nil
+}
+
+// constString returns the value of a constant string expression.
+func constString(info *types.Info, expr ast.Expr) (string, bool) {
+	if tv, ok := info.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return constant.StringVal(tv.Value), true
+	}
+	return "", false
+}
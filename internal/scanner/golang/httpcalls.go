@@ -0,0 +1,289 @@
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// requestShape tells where an outbound request API takes its method and URL.
+type requestShape struct {
+	method    string // fixed HTTP method, "" when passed as an argument
+	methodArg int    // index of the method argument, -1 when fixed
+	urlArg    int    // index of the URL argument, -1 when the request was built elsewhere
+}
+
+// Package-level functions of net/http that send or build a request.
+var httpFuncs = map[string]requestShape{
+	"Get":                   {method: "GET", methodArg: -1, urlArg: 0},
+	"Head":                  {method: "HEAD", methodArg: -1, urlArg: 0},
+	"Post":                  {method: "POST", methodArg: -1, urlArg: 0},
+	"PostForm":              {method: "POST", methodArg: -1, urlArg: 0},
+	"NewRequest":            {methodArg: 0, urlArg: 1},
+	"NewRequestWithContext": {methodArg: 1, urlArg: 2},
+}
+
+// Methods of *http.Client that send a request.
+var clientMethods = map[string]requestShape{
+	"Get":      {method: "GET", methodArg: -1, urlArg: 0},
+	"Head":     {method: "HEAD", methodArg: -1, urlArg: 0},
+	"Post":     {method: "POST", methodArg: -1, urlArg: 0},
+	"PostForm": {method: "POST", methodArg: -1, urlArg: 0},
+	"Do":       {methodArg: -1, urlArg: -1},
+}
+
+// requestSite is a call that sends or builds an outbound HTTP request.
+type requestSite struct {
+	call  *ast.CallExpr
+	name  string // e.g. "http.NewRequestWithContext" or "(*http.Client).Do"
+	shape requestShape
+}
+
+func (r requestSite) methodExpr() ast.Expr { return argAt(r.call, r.shape.methodArg) }
+func (r requestSite) urlExpr() ast.Expr    { return argAt(r.call, r.shape.urlArg) }
+
+func argAt(call *ast.CallExpr, i int) ast.Expr {
+	if i < 0 || i >= len(call.Args) {
+		return nil
+	}
+	return call.Args[i]
+}
+
+func httpFuncSite(call *ast.CallExpr, name string) (requestSite, bool) {
+	shape, ok := httpFuncs[name]
+	return requestSite{call: call, name: "http." + name, shape: shape}, ok
+}
+
+func clientMethodSite(call *ast.CallExpr, name string) (requestSite, bool) {
+	shape, ok := clientMethods[name]
+	if !ok || (name == "Do" && builtByNewRequest(argAt(call, 0))) {
+		// client.Do(req) with req := http.NewRequest(...) is the same request
+		// as the NewRequest call, which already carries its method and URL.
+		return requestSite{}, false
+	}
+	return requestSite{call: call, name: "(*http.Client)." + name, shape: shape}, true
+}
+
+// builtByNewRequest reports whether expr is a variable assigned from a
+// NewRequest or NewRequestWithContext call.
+func builtByNewRequest(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return false
+	}
+	assign, ok := ident.Obj.Decl.(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	for _, rhs := range assign.Rhs {
+		if call, ok := rhs.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && strings.HasPrefix(sel.Sel.Name, "NewRequest") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// httpImportNames returns the names under which file imports net/http.
+func httpImportNames(file *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, imp := range file.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p != "net/http" {
+			continue
+		}
+		switch {
+		case imp.Name == nil:
+			names["http"] = true
+		case imp.Name.Name != "_" && imp.Name.Name != ".":
+			names[imp.Name.Name] = true
+		}
+	}
+	return names
+}
+
+// syntacticRequest recognises request sites without type information:
+// http.Get(url), http.NewRequestWithContext(ctx, method, url, body),
+// http.DefaultClient.Do(req) and c.Do(req) where c is declared as *http.Client,
+// under whatever name the file imports net/http.
+func syntacticRequest(httpNames map[string]bool, call *ast.CallExpr) (requestSite, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return requestSite{}, false
+	}
+	isHTTP := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Obj == nil && httpNames[ident.Name]
+	}
+	switch x := sel.X.(type) {
+	case *ast.Ident:
+		if isHTTP(x) {
+			return httpFuncSite(call, sel.Sel.Name)
+		}
+		if typ, ok := declaredType(x).(*ast.StarExpr); ok {
+			if t, ok := typ.X.(*ast.SelectorExpr); ok && isHTTP(t.X) && t.Sel.Name == "Client" {
+				return clientMethodSite(call, sel.Sel.Name)
+			}
+		}
+	case *ast.SelectorExpr:
+		if isHTTP(x.X) && x.Sel.Name == "DefaultClient" {
+			return clientMethodSite(call, sel.Sel.Name)
+		}
+	}
+	return requestSite{}, false
+}
+
+// strPart is a piece of a string expression: literal text, a parameter of the
+// enclosing function, or an expression whose value is unknown.
+type strPart struct {
+	text  string // literal text, or the source of the expression
+	lit   bool
+	param int // index of the enclosing function's parameter, -1 otherwise
+}
+
+// strTemplate is a string expression folded as far as constants allow, e.g.
+// c.baseURL + "/v1/orders?user_id=" + userID.
+type strTemplate []strPart
+
+// String renders unknown parts in braces: "{c.baseURL}/v1/orders?user_id={userID}".
+func (t strTemplate) String() string {
+	var b strings.Builder
+	for _, p := range t {
+		if p.lit {
+			b.WriteString(p.text)
+		} else {
+			b.WriteString("{" + p.text + "}")
+		}
+	}
+	return b.String()
+}
+
+// forwardsPath reports whether the template ends with a parameter that is
+// part of the path, as in baseURL + path: the function's callers then choose
+// the endpoint. A parameter only filling in a query value does not count.
+func (t strTemplate) forwardsPath() bool {
+	if len(t) == 0 || t[len(t)-1].param < 0 {
+		return false
+	}
+	for _, p := range t {
+		if p.lit && strings.Contains(p.text, "?") {
+			return false
+		}
+	}
+	return true
+}
+
+// substitute replaces parameter parts with the templates of the call arguments.
+func (t strTemplate) substitute(args []strTemplate) strTemplate {
+	var out strTemplate
+	for _, p := range t {
+		if p.param >= 0 && p.param < len(args) {
+			out = append(out, args[p.param]...)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// folder folds string expressions. eval returns the value of constant
+// expressions; param returns the parameter index an identifier refers to, or -1.
+type folder struct {
+	eval  func(ast.Expr) (string, bool)
+	param func(*ast.Ident) int
+}
+
+func (f folder) fold(expr ast.Expr) strTemplate {
+	if expr == nil {
+		return nil
+	}
+	if v, ok := f.eval(expr); ok {
+		return strTemplate{{text: v, lit: true, param: -1}}
+	}
+	switch t := expr.(type) {
+	case *ast.ParenExpr:
+		return f.fold(t.X)
+	case *ast.BinaryExpr:
+		if t.Op == token.ADD {
+			return append(f.fold(t.X), f.fold(t.Y)...)
+		}
+	case *ast.Ident:
+		if i := f.param(t); i >= 0 {
+			return strTemplate{{text: t.Name, param: i}}
+		}
+	case *ast.CallExpr:
+		if tmpl, ok := f.sprintf(t); ok {
+			return tmpl
+		}
+	}
+	return strTemplate{{text: exprString(expr), param: -1}}
+}
+
+// sprintf folds fmt.Sprintf calls with a constant format, substituting each verb.
+func (f folder) sprintf(call *ast.CallExpr) (strTemplate, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Sprintf" || len(call.Args) == 0 {
+		return nil, false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "fmt" {
+		return nil, false
+	}
+	format, ok := f.eval(call.Args[0])
+	if !ok {
+		return nil, false
+	}
+	var out strTemplate
+	args := call.Args[1:]
+	lit := func(s string) {
+		if s != "" {
+			out = append(out, strPart{text: s, lit: true, param: -1})
+		}
+	}
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 || i == len(format)-1 {
+			lit(format)
+			return out, true
+		}
+		lit(format[:i])
+		format = format[i+1:]
+		if format[0] == '%' {
+			lit("%")
+			format = format[1:]
+			continue
+		}
+		// Skip flags, width and precision up to the verb.
+		j := strings.IndexFunc(format, func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') })
+		if j < 0 || len(args) == 0 {
+			return nil, false
+		}
+		out = append(out, f.fold(args[0])...)
+		args, format = args[1:], format[j+1:]
+	}
+}
+
+// httpMethod normalises a folded method: constants are upper-cased, anything
+// else is rendered as a template.
+func httpMethod(t strTemplate) string {
+	if len(t) == 1 && t[0].lit {
+		return strings.ToUpper(t[0].text)
+	}
+	return t.String()
+}
+
+// syntacticFolder folds with the constants of a single file, including the
+// http.MethodX constants under the file's import names.
+func syntacticFolder(httpNames map[string]bool) folder {
+	return folder{
+		eval: func(expr ast.Expr) (string, bool) {
+			if sel, ok := expr.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && httpNames[x.Name] && strings.HasPrefix(sel.Sel.Name, "Method") {
+					return strings.ToUpper(strings.TrimPrefix(sel.Sel.Name, "Method")), true
+				}
+			}
+			return stringValue(expr)
+		},
+		param: func(*ast.Ident) int { return -1 },
+	}
+}
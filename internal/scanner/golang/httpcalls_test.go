@@ -0,0 +1,137 @@
+package golang
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func TestLinkHTTPCalls(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want lists the HTTP_CALL nodes as "caller>name METHOD url".
+		want []string
+	}{
+		{
+			name: "package functions",
+			files: map[string]string{
+				"main.go": `package main
+
+import (
+	"fmt"
+	stdhttp "net/http"
+	"strings"
+)
+
+const usersURL = "http://users"
+
+func health() {
+	stdhttp.Get(usersURL + "/health")
+}
+
+func order(id int) {
+	stdhttp.Post(fmt.Sprintf("http://orders/v1/orders/%d", id), "application/json", strings.NewReader("{}"))
+}
+`,
+			},
+			want: []string{
+				"health>http.Get GET http://users/health",
+				"order>http.Post POST http://orders/v1/orders/{id}",
+			},
+		},
+		{
+			name: "request sent by a client",
+			files: map[string]string{
+				"main.go": `package main
+
+import (
+	"context"
+	"net/http"
+)
+
+var client = &http.Client{}
+
+func remove(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, "http://orders/v1/orders/"+id, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(req)
+	return err
+}
+
+func fetch(url string) {
+	http.DefaultClient.Get(url + "?verbose=1")
+}
+`,
+			},
+			want: []string{
+				"fetch>(*http.Client).Get GET {url}?verbose=1",
+				"remove>http.NewRequestWithContext DELETE http://orders/v1/orders/{id}",
+			},
+		},
+		{
+			name: "wrapper in another package",
+			files: map[string]string{
+				"client/client.go": `package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type Client struct {
+	baseURL string
+	hc      *http.Client
+}
+
+func (c *Client) Get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.hc.Do(req)
+}
+`,
+				"main.go": `package main
+
+import (
+	"context"
+
+	"example.com/app/client"
+)
+
+func fetchUser(c *client.Client, id string) {
+	c.Get(context.Background(), "/v1/users/"+id)
+}
+`,
+			},
+			want: []string{
+				"(Client).Get>http.NewRequestWithContext GET {c.baseURL}{path}",
+				"fetchUser>(*client.Client).Get GET {c.baseURL}/v1/users/{id}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"go.mod": goMod}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			nodes, _ := linkTree(t, files)
+			var got []string
+			for _, n := range nodes {
+				if n.Type == models.NodeHTTPCall {
+					got = append(got, n.Metadata["caller"].(string)+">"+n.Name+" "+n.Metadata["method"].(string)+" "+n.Metadata["url"].(string))
+				}
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("HTTP calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package golang
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// maxWrapperDepth bounds how many layers of wrapper functions are followed.
+const maxWrapperDepth = 4
+
+// wrapper is a declared function that sends a request to a path given by its
+// caller, such as a client's Get(ctx, path, result).
+type wrapper struct {
+	node   *models.CodeNode
+	method strTemplate
+	url    strTemplate
+}
+
+// linkHTTPCalls finds outbound request sites with type information. It
+// refines the method and URL of the HTTP_CALL nodes the scanner found, adds the
+// sites only types reveal (aliased imports, calls on *http.Client fields), and
+// follows wrapper functions so that each call of a wrapper becomes an
+// HTTP_CALL node whose URL combines the wrapper's template with the arguments.
+func (ls *linkState) linkHTTPCalls(nodes []*models.CodeNode, idx declIndex) {
+	hc := &httpCalls{ls: ls, idx: idx, scanned: make(map[string][]*models.CodeNode), ids: make(map[string]*models.IDAllocator)}
+	for _, n := range nodes {
+		if n.Language == "go" && n.Type == models.NodeHTTPCall {
+			hc.scanned[n.FilePath] = append(hc.scanned[n.FilePath], n)
+		}
+	}
+
+	pending := make(map[*types.Func]*wrapper)
+	ls.eachFuncDecl(func(pk *pkg, filePath string, f *ast.File, fd *ast.FuncDecl, fn *types.Func) {
+		fold := ls.folder(pk.info, fn)
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			site, ok := typedRequest(pk.info, call)
+			if !ok {
+				return true
+			}
+			method := strTemplate{{text: site.shape.method, lit: true, param: -1}}
+			if site.shape.method == "" {
+				method = fold.fold(site.methodExpr())
+			}
+			url := fold.fold(site.urlExpr())
+			hc.node(filePath, f, fd, call, site.name, method, url, nil)
+			if _, ok := pending[fn]; !ok && url.forwardsPath() {
+				if node, ok := ls.funcs[fn]; ok {
+					pending[fn] = &wrapper{node: node, method: method, url: url}
+				}
+			}
+			return true
+		})
+	})
+
+	// Each round visits the call sites of the wrappers found in the previous
+	// one; a caller that forwards its own parameters is a wrapper in turn.
+	for depth := 0; len(pending) > 0 && depth < maxWrapperDepth; depth++ {
+		next := make(map[*types.Func]*wrapper)
+		ls.eachFuncDecl(func(pk *pkg, filePath string, f *ast.File, fd *ast.FuncDecl, fn *types.Func) {
+			fold := ls.folder(pk.info, fn)
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				for _, callee := range ls.callees(pk.info, call) {
+					w, ok := pending[callee]
+					if !ok || callee == fn {
+						continue
+					}
+					args := make([]strTemplate, len(call.Args))
+					for i, arg := range call.Args {
+						args[i] = fold.fold(arg)
+					}
+					method, url := w.method.substitute(args), w.url.substitute(args)
+					meta := map[string]interface{}{"wrapper": w.node.Name}
+					if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && pk.info.Selections[sel] != nil {
+						meta["receiver"] = exprString(sel.X)
+					}
+					hc.node(filePath, f, fd, call, wrapperCallName(callee), method, url, meta)
+					if _, ok := next[fn]; !ok && url.forwardsPath() {
+						if node, ok := ls.funcs[fn]; ok {
+							next[fn] = &wrapper{node: node, method: method, url: url}
+						}
+					}
+				}
+				return true
+			})
+		})
+		pending = next
+	}
+}
+
+// httpCalls creates and refines HTTP_CALL nodes while linking.
+type httpCalls struct {
+	ls      *linkState
+	idx     declIndex
+	scanned map[string][]*models.CodeNode // HTTP_CALL nodes from the scanner, by file
+	ids     map[string]*models.IDAllocator
+}
+
+// node refines the scanned HTTP_CALL node of a call site, or adds one.
+func (hc *httpCalls) node(filePath string, f *ast.File, fd *ast.FuncDecl, call *ast.CallExpr, name string, method, url strTemplate, meta map[string]interface{}) {
+	line := hc.ls.prog.fset.Position(call.Pos()).Line
+	key := declKey{filePath, line, name}
+	if node, ok := hc.idx[key]; ok {
+		if node.Metadata == nil {
+			node.Metadata = make(map[string]interface{})
+		}
+		if len(method) > 0 {
+			node.Metadata["method"] = httpMethod(method)
+		}
+		if len(url) > 0 {
+			node.Metadata["url"] = url.String()
+		}
+		return
+	}
+
+	caller := funcName(fd)
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta["caller"] = caller
+	if len(method) > 0 {
+		meta["method"] = httpMethod(method)
+	}
+	if len(url) > 0 {
+		meta["url"] = url.String()
+	}
+	node := &models.CodeNode{
+		ID:         hc.allocator(filePath, f).ID(caller+">"+name, models.NodeHTTPCall),
+		Type:       models.NodeHTTPCall,
+		Name:       name,
+		Language:   "go",
+		FilePath:   filePath,
+		LineNumber: line,
+		Metadata:   meta,
+	}
+	hc.idx[key] = node
+	hc.ls.graph.Nodes = append(hc.ls.graph.Nodes, node)
+}
+
+// allocator returns the ID allocator of a file, advanced past the IDs the
+// scanner already handed out so that added nodes never collide with them.
+func (hc *httpCalls) allocator(filePath string, f *ast.File) *models.IDAllocator {
+	if ids, ok := hc.ids[filePath]; ok {
+		return ids
+	}
+	ids := models.NewIDAllocator("go", f.Name.Name, models.IDPath(hc.ls.root, filePath))
+	for _, n := range hc.scanned[filePath] {
+		caller, _ := n.Metadata["caller"].(string)
+		ids.ID(caller+">"+n.Name, models.NodeHTTPCall)
+	}
+	hc.ids[filePath] = ids
+	return ids
+}
+
+// typedRequest recognises calls of the net/http request API by their callee,
+// whatever name net/http is imported under.
+func typedRequest(info *types.Info, call *ast.CallExpr) (requestSite, bool) {
+	var obj types.Object
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		obj = info.Uses[fun]
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[fun]; ok {
+			obj = sel.Obj()
+		} else {
+			obj = info.Uses[fun.Sel]
+		}
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "net/http" {
+		return requestSite{}, false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return httpFuncSite(call, fn.Name())
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok && named.Obj().Name() == "Client" {
+		return clientMethodSite(call, fn.Name())
+	}
+	return requestSite{}, false
+}
+
+// folder folds string expressions inside fn with the constants known to the
+// type checker, marking the parameters of fn.
+func (ls *linkState) folder(info *types.Info, fn *types.Func) folder {
+	params := make(map[types.Object]int)
+	if fn != nil {
+		sig := fn.Type().(*types.Signature)
+		for i := 0; i < sig.Params().Len(); i++ {
+			params[sig.Params().At(i)] = i
+		}
+	}
+	return folder{
+		eval: func(expr ast.Expr) (string, bool) { return constString(info, expr) },
+		param: func(ident *ast.Ident) int {
+			if i, ok := params[info.Uses[ident]]; ok {
+				return i
+			}
+			return -1
+		},
+	}
+}
+
+// wrapperCallName names a call of a wrapper after the function, qualified by
+// its package: "(*client.HTTPClient).Get" or "client.Fetch".
+func wrapperCallName(fn *types.Func) string {
+	qualifier := func(p *types.Package) string { return p.Name() }
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		return fn.Pkg().Name() + "." + fn.Name()
+	}
+	return "(" + types.TypeString(sig.Recv().Type(), qualifier) + ")." + fn.Name()
+}
+
+// eachFuncDecl calls fn for every function declaration with a body.
+func (ls *linkState) eachFuncDecl(fn func(pk *pkg, filePath string, f *ast.File, fd *ast.FuncDecl, obj *types.Func)) {
+	ls.eachFile(func(pk *pkg, filePath string, f *ast.File) {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			obj, _ := pk.info.Defs[fd.Name].(*types.Func)
+			fn(pk, filePath, f, fd, obj)
+		}
+	})
+}
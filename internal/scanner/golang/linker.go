@@ -0,0 +1,148 @@
+package golang
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sync"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// GoLinker resolves relations between Go nodes with go/types: the call graph
+// (CALLS), the interfaces each struct satisfies (IMPLEMENTS), type
+// embedding (EMBEDS) and the functions serving HTTP routes (HANDLES_ROUTE).
+// It also corrects the framework and path of ROUTE nodes, completes outbound
+// HTTP_CALL nodes, following wrapper functions, and records the environment
+// variables read by the code (ENV_VAR, READS_ENV).
+// Whole packages are loaded from the scanned tree and type-checked; see
+// loadProgram. With tree.Scope set, only the packages holding files in scope
+// and their imports are type-checked.
+type GoLinker struct {
+	once sync.Once
+	std  *stdImporter
+}
+
+func NewGoLinker() *GoLinker {
+	return &GoLinker{}
+}
+
+func (l *GoLinker) Link(ctx context.Context, tree scanner.Tree, nodes []*models.CodeNode) (*models.Graph, error) {
+	if !hasGoNodes(nodes) {
+		return nil, nil
+	}
+	l.once.Do(func() { l.std = newStdImporter() })
+
+	prog, err := loadProgram(ctx, tree.FS, tree.Root, l.std, tree.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("load go packages: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ls := &linkState{
+		root:  tree.Root,
+		prog:  prog,
+		funcs: make(map[*types.Func]*models.CodeNode),
+		types: make(map[*types.TypeName]*models.CodeNode),
+		impls: make(map[*types.Func][]*types.Func),
+		graph: &models.Graph{},
+	}
+	idx := newDeclIndex(nodes)
+	ls.indexDecls(idx)
+	ls.linkCalls()
+	ls.linkTypes()
+	ls.linkRoutes(nodes, idx)
+	ls.linkHTTPCalls(nodes, idx)
+	ls.linkEnv()
+	return ls.graph, nil
+}
+
+func hasGoNodes(nodes []*models.CodeNode) bool {
+	for _, n := range nodes {
+		if n.Language == "go" {
+			return true
+		}
+	}
+	return false
+}
+
+// declKey locates the node of a declaration: scanners and the loader agree on
+// file path, line and name, which together identify a declaration.
+type declKey struct {
+	file string
+	line int
+	name string
+}
+
+type declIndex map[declKey]*models.CodeNode
+
+func newDeclIndex(nodes []*models.CodeNode) declIndex {
+	idx := make(declIndex)
+	for _, n := range nodes {
+		if n.Language == "go" {
+			idx[declKey{n.FilePath, n.LineNumber, n.Name}] = n
+		}
+	}
+	return idx
+}
+
+// linkState holds one loaded program and the mapping from its objects to scanned nodes.
+type linkState struct {
+	root  string // of the tree, which node IDs are relative to
+	prog  *program
+	funcs map[*types.Func]*models.CodeNode
+	types map[*types.TypeName]*models.CodeNode
+	impls map[*types.Func][]*types.Func // interface method -> implementations
+	graph *models.Graph
+}
+
+// eachFile calls fn for every type-checked file of the program, in import
+// path order.
+func (ls *linkState) eachFile(fn func(pk *pkg, filePath string, f *ast.File)) {
+	for _, pk := range ls.prog.sortedPackages() {
+		if pk.info == nil {
+			continue
+		}
+		for i, f := range pk.files {
+			fn(pk, pk.filePaths[i], f)
+		}
+	}
+}
+
+func (ls *linkState) indexDecls(idx declIndex) {
+	ls.eachFile(func(pk *pkg, filePath string, f *ast.File) {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch d := n.(type) {
+			case *ast.FuncDecl:
+				obj, ok := pk.info.Defs[d.Name].(*types.Func)
+				if !ok {
+					return true
+				}
+				line := ls.prog.fset.Position(d.Pos()).Line
+				if node, ok := idx[declKey{filePath, line, funcName(d)}]; ok {
+					ls.funcs[obj] = node
+				}
+			case *ast.TypeSpec:
+				obj, ok := pk.info.Defs[d.Name].(*types.TypeName)
+				if !ok {
+					return true
+				}
+				line := ls.prog.fset.Position(d.Pos()).Line
+				if node, ok := idx[declKey{filePath, line, d.Name.Name}]; ok {
+					ls.types[obj] = node
+				}
+			}
+			return true
+		})
+	})
+}
+
+func (ls *linkState) addEdge(source, target *models.CodeNode, kind models.EdgeKind, attrs map[string]interface{}) {
+	edge := models.NewEdge(source.ID, target.ID, kind)
+	edge.Attributes = attrs
+	ls.graph.Edges = append(ls.graph.Edges, edge)
+}
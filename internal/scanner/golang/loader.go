@@ -0,0 +1,367 @@
+package golang
+
+import (
+	"context"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+	"golang.org/x/mod/modfile"
+)
+
+// program is the set of type-checked packages found in one scanned tree.
+type program struct {
+	fset     *token.FileSet
+	packages map[string]*pkg // by import path
+	std      *stdImporter
+	checking map[string]bool           // guards against import cycles
+	external map[string]*types.Package // imports from outside the tree
+}
+
+// pkg is a single type-checked Go package.
+type pkg struct {
+	path      string
+	module    string // path of the enclosing module, "" outside any module
+	dir       string // directory within the scanned FS
+	files     []*ast.File
+	filePaths []string // node FilePath of each entry in files
+	types     *types.Package
+	info      *types.Info
+}
+
+// stdImporter imports standard library packages. Imports are costly, so one
+// instance is shared by every load and guarded by a mutex.
+type stdImporter struct {
+	mu   sync.Mutex
+	fset *token.FileSet
+	imp  types.ImporterFrom
+	src  types.ImporterFrom
+}
+
+func newStdImporter() *stdImporter {
+	fset := token.NewFileSet()
+	return &stdImporter{
+		fset: fset,
+		imp:  importer.Default().(types.ImporterFrom),
+		src:  importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+	}
+}
+
+func (s *stdImporter) Import(path string) (*types.Package, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.imp.ImportFrom(path, "", 0)
+	if err != nil {
+		// No export data available (e.g. no build cache); type-check from source.
+		p, err = s.src.ImportFrom(path, "", 0)
+	}
+	return p, err
+}
+
+// loadProgram parses every Go package under root in fsys and type-checks them.
+// Packages of the same module import each other; the standard library is
+// imported normally and any other dependency is replaced by an empty package,
+// so the result is usable without downloading modules. When scope is set,
+// only the packages holding one of its files are checked, along with the
+// packages they import.
+func loadProgram(ctx context.Context, fsys fs.FS, root string, std *stdImporter, scope map[string]bool) (*program, error) {
+	prog := &program{
+		fset:     token.NewFileSet(),
+		packages: make(map[string]*pkg),
+		std:      std,
+		checking: make(map[string]bool),
+		external: make(map[string]*types.Package),
+	}
+
+	modules := make(map[string]string) // module dir -> module path
+	dirs := make(map[string][]string)  // package dir -> .go files
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && scanner.SkipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.Name() == "go.mod":
+			data, err := fs.ReadFile(fsys, p)
+			if err == nil {
+				if mod := modfile.ModulePath(data); mod != "" {
+					modules[path.Dir(p)] = mod
+				}
+			}
+		case strings.HasSuffix(p, ".go") && !strings.HasSuffix(p, "_test.go"):
+			dirs[path.Dir(p)] = append(dirs[path.Dir(p)], p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for dir, files := range dirs {
+		sort.Strings(files)
+		pk := &pkg{path: importPath(modules, dir), module: moduleOf(modules, dir), dir: dir}
+		pkgName := ""
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			content, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			f, err := parser.ParseFile(prog.fset, filepath.Join(root, filepath.FromSlash(file)), content, parser.ParseComments)
+			if err != nil || !buildable(file, f) {
+				continue
+			}
+			// A directory holds a single package; stray files (e.g. "package main"
+			// generators next to a library) are left out.
+			if pkgName == "" {
+				pkgName = f.Name.Name
+			} else if f.Name.Name != pkgName {
+				continue
+			}
+			pk.files = append(pk.files, f)
+			pk.filePaths = append(pk.filePaths, filepath.Join(root, filepath.FromSlash(file)))
+		}
+		if len(pk.files) > 0 {
+			prog.packages[pk.path] = pk
+		}
+	}
+
+	paths := make([]string, 0, len(prog.packages))
+	for p := range prog.packages {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if pk := prog.packages[p]; scope == nil || pk.holdsAny(scope) {
+			prog.check(pk)
+		}
+	}
+	return prog, nil
+}
+
+// holdsAny reports whether one of the package's files is in files.
+func (pk *pkg) holdsAny(files map[string]bool) bool {
+	for _, f := range pk.filePaths {
+		if files[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// check type-checks pk once its imports have been checked. Type errors are
+// ignored: partially typed packages still resolve most calls.
+func (prog *program) check(pk *pkg) *types.Package {
+	if pk.types != nil || prog.checking[pk.path] {
+		return pk.types
+	}
+	prog.checking[pk.path] = true
+	defer delete(prog.checking, pk.path)
+
+	pk.info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	conf := types.Config{
+		Importer:    importerFunc(prog.importPackage),
+		Error:       func(error) {},
+		FakeImportC: true,
+	}
+	pk.types, _ = conf.Check(pk.path, prog.fset, pk.files, pk.info)
+	return pk.types
+}
+
+func (prog *program) importPackage(importPath string) (*types.Package, error) {
+	if pk, ok := prog.packages[importPath]; ok {
+		if t := prog.check(pk); t != nil {
+			return t, nil
+		}
+	}
+	if t, ok := prog.external[importPath]; ok {
+		return t, nil
+	}
+	var t *types.Package
+	if isStdlib(importPath) {
+		t, _ = prog.std.Import(importPath)
+	}
+	if t == nil {
+		// Unknown dependency: an empty, complete package lets type checking go on.
+		t = types.NewPackage(importPath, guessPackageName(importPath))
+		t.MarkComplete()
+	}
+	prog.external[importPath] = t
+	return t, nil
+}
+
+// sortedPackages returns the packages in import path order.
+func (prog *program) sortedPackages() []*pkg {
+	pkgs := make([]*pkg, 0, len(prog.packages))
+	for _, pk := range prog.packages {
+		pkgs = append(pkgs, pk)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].path < pkgs[j].path })
+	return pkgs
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// importPath derives the import path of dir from the closest enclosing go.mod.
+// Directories outside any module are identified by their path.
+func importPath(modules map[string]string, dir string) string {
+	for d := dir; ; d = path.Dir(d) {
+		if mod, ok := modules[d]; ok {
+			switch {
+			case d == dir:
+				return mod
+			case d == ".":
+				return path.Join(mod, dir)
+			}
+			return path.Join(mod, strings.TrimPrefix(dir, d+"/"))
+		}
+		if d == "." {
+			return dir
+		}
+	}
+}
+
+// moduleOf returns the path of the module enclosing dir, or "".
+func moduleOf(modules map[string]string, dir string) string {
+	for d := dir; ; d = path.Dir(d) {
+		if mod, ok := modules[d]; ok {
+			return mod
+		}
+		if d == "." {
+			return ""
+		}
+	}
+}
+
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// guessPackageName returns the conventional package name for an import path,
+// ignoring major version suffixes such as "/v2".
+func guessPackageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// buildable reports whether file would be compiled for the host platform.
+func buildable(name string, f *ast.File) bool {
+	ctx := build.Default
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				continue
+			}
+			if !expr.Eval(func(tag string) bool { return matchTag(ctx, tag) }) {
+				return false
+			}
+		}
+	}
+
+	// Honour _GOOS / _GOARCH file name suffixes.
+	base := strings.TrimSuffix(path.Base(name), ".go")
+	parts := strings.Split(base, "_")
+	if n := len(parts); n >= 2 {
+		last := parts[n-1]
+		if knownArch[last] {
+			if last != ctx.GOARCH {
+				return false
+			}
+			if n >= 3 && knownOS[parts[n-2]] && parts[n-2] != ctx.GOOS {
+				return false
+			}
+		} else if knownOS[last] && last != ctx.GOOS && !(last == "unix" && unixOS[ctx.GOOS]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchTag(ctx build.Context, tag string) bool {
+	switch {
+	case tag == ctx.GOOS || tag == ctx.GOARCH || tag == ctx.Compiler:
+		return true
+	case tag == "unix":
+		return unixOS[ctx.GOOS]
+	case tag == "cgo":
+		return ctx.CgoEnabled
+	}
+	for _, rt := range ctx.ReleaseTags {
+		if tag == rt {
+			return true
+		}
+	}
+	for _, t := range ctx.BuildTags {
+		if tag == t {
+			return true
+		}
+	}
+	return false
+}
+
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+}
+
+var unixOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "linux": true, "netbsd": true, "openbsd": true, "solaris": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true, "mips": true,
+	"mipsle": true, "mips64": true, "mips64le": true, "ppc64": true, "ppc64le": true,
+	"riscv64": true, "s390x": true, "wasm": true,
+}
@@ -0,0 +1,93 @@
+package golang
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// moduleResolver finds the import path of a file's package from the nearest
+// go.mod above it. Lookups are cached per directory.
+type moduleResolver struct {
+	// read returns the go.mod of an absolute directory.
+	read func(dir string) ([]byte, error)
+
+	mu   sync.Mutex
+	dirs map[string]module // directory -> enclosing module
+}
+
+// module is a go.mod found; the zero value means "no module".
+type module struct {
+	dir  string
+	path string
+}
+
+// newModuleResolver returns a resolver reading go.mod files on disk.
+func newModuleResolver() *moduleResolver {
+	return &moduleResolver{
+		read: func(dir string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir, "go.mod"))
+		},
+		dirs: make(map[string]module),
+	}
+}
+
+// treeModuleResolver returns a resolver reading go.mod files from tree, whose
+// Root must be absolute; modules enclosing the tree are not found.
+func treeModuleResolver(tree scanner.Tree) *moduleResolver {
+	return &moduleResolver{
+		read: func(dir string) ([]byte, error) {
+			rel, err := filepath.Rel(tree.Root, dir)
+			if err != nil || !filepath.IsLocal(rel) {
+				return nil, fs.ErrNotExist
+			}
+			return fs.ReadFile(tree.FS, path.Join(filepath.ToSlash(rel), "go.mod"))
+		},
+		dirs: make(map[string]module),
+	}
+}
+
+// importPath returns the import path of the package containing filePath, or
+// pkgName when the file is not inside a module.
+func (r *moduleResolver) importPath(filePath, pkgName string) string {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return pkgName
+	}
+	mod := r.lookup(dir)
+	if mod.path == "" {
+		return pkgName
+	}
+	rel, err := filepath.Rel(mod.dir, dir)
+	if err != nil {
+		return pkgName
+	}
+	return path.Join(mod.path, filepath.ToSlash(rel))
+}
+
+func (r *moduleResolver) lookup(dir string) module {
+	r.mu.Lock()
+	if mod, ok := r.dirs[dir]; ok {
+		r.mu.Unlock()
+		return mod
+	}
+	r.mu.Unlock()
+
+	var mod module
+	if data, err := r.read(dir); err == nil {
+		mod = module{dir: dir, path: modfile.ModulePath(data)}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = r.lookup(parent)
+	}
+
+	r.mu.Lock()
+	r.dirs[dir] = mod
+	r.mu.Unlock()
+	return mod
+}
@@ -0,0 +1,438 @@
+package golang
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// linkRoutes finds the route registrations of the program with type
+// information and adds a HANDLES_ROUTE edge from the function serving each
+// route to its ROUTE node. The framework of a registration is that of the
+// router it is made on, and its path carries the prefixes of the groups the
+// router was created from, also when the group is passed to another function.
+// The ROUTE nodes of the scanner are corrected accordingly; a registration
+// made under several prefixes gets a node per path. Handlers passed as method
+// values on an interface reach every implementation; inline function literals
+// have no declared function to link, so their routes get no edge.
+func (ls *linkState) linkRoutes(nodes []*models.CodeNode, idx declIndex) {
+	scanned := make(map[string]*models.CodeNode)
+	for _, n := range nodes {
+		if n.Language == "go" && n.Type == models.NodeRoute {
+			scanned[n.ID] = n
+		}
+	}
+	rt := newRouteTypes(ls)
+	ls.eachFile(func(pk *pkg, filePath string, f *ast.File) {
+		// Replaying the allocation of the scanner finds its node for each
+		// registration, whatever name an earlier link gave the node; nodes
+		// added here then get IDs past those of the scanner.
+		ids := models.NewIDAllocator("go", f.Name.Name, models.IDPath(ls.root, filePath))
+		byCall := make(map[*ast.CallExpr]*models.CodeNode)
+		for _, site := range findRoutes(f) {
+			if node, ok := scanned[ids.ID(site.name(), models.NodeRoute)]; ok {
+				setRoute(node, site)
+				byCall[site.call] = node
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, site := range rt.routes(pk.info, f) {
+			node, ok := byCall[site.call]
+			if ok {
+				delete(byCall, site.call) // further paths of the call get nodes of their own
+				setRoute(node, site)
+			} else {
+				key := declKey{filePath, ls.prog.fset.Position(site.call.Pos()).Line, site.name()}
+				if node, ok = idx[key]; !ok {
+					node = newRouteNode(ls.prog.fset, f, site, filePath, ids)
+					idx[key] = node
+					ls.graph.Nodes = append(ls.graph.Nodes, node)
+				}
+			}
+			if seen[node.ID] {
+				continue
+			}
+			seen[node.ID] = true
+			for _, handler := range ls.handlers(pk.info, site.handler) {
+				ls.addEdge(handler, node, models.EdgeHandlesRoute, map[string]interface{}{
+					"framework": site.framework,
+				})
+			}
+		}
+	})
+}
+
+// setRoute records the method, path and framework of site in its node.
+func setRoute(node *models.CodeNode, site routeSite) {
+	if node.Metadata == nil {
+		node.Metadata = make(map[string]interface{})
+	}
+	node.Name = site.name()
+	node.Metadata["method"] = site.method
+	node.Metadata["path"] = site.path
+	node.Metadata["framework"] = site.framework
+}
+
+// handlers returns the function nodes a route's handler expression refers to.
+func (ls *linkState) handlers(info *types.Info, handler ast.Expr) []*models.CodeNode {
+	var fns []*types.Func
+	switch h := ast.Unparen(handler).(type) {
+	case nil:
+		return nil
+	case *ast.Ident:
+		if fn, ok := info.Uses[h].(*types.Func); ok {
+			fns = []*types.Func{fn.Origin()}
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[h]; ok {
+			if fn, ok := sel.Obj().(*types.Func); ok {
+				if types.IsInterface(fn.Type().(*types.Signature).Recv().Type()) {
+					fns = ls.implementations(fn)
+				} else {
+					fns = []*types.Func{fn.Origin()}
+				}
+			}
+		} else if fn, ok := info.Uses[h.Sel].(*types.Func); ok {
+			fns = []*types.Func{fn.Origin()}
+		}
+	case *ast.CallExpr:
+		// Adapters such as gin.WrapF(fn) or http.HandlerFunc(fn): the handler
+		// is whichever declared function is passed in.
+		var nodes []*models.CodeNode
+		for _, arg := range h.Args {
+			nodes = append(nodes, ls.handlers(info, arg)...)
+		}
+		return nodes
+	}
+	return ls.nodesOf(fns)
+}
+
+func (ls *linkState) nodesOf(fns []*types.Func) []*models.CodeNode {
+	var nodes []*models.CodeNode
+	seen := make(map[string]bool)
+	for _, fn := range fns {
+		if node, ok := ls.funcs[fn]; ok && !seen[node.ID] {
+			seen[node.ID] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// routeTypes tells, with type information, the framework a router belongs to
+// and the path prefixes it carries. Routers of third-party frameworks have no
+// type, as their packages are not loaded, so both are traced back through the
+// declarations and assignments of the variables, fields and parameters
+// holding them, and through the arguments of every call of a function taking
+// one.
+type routeTypes struct {
+	ls         *linkState
+	declared   map[types.Object]binding   // type of a variable; result type of a function
+	bindings   map[types.Object][]binding // values given to a variable
+	frameworks map[types.Object]string
+	prefixes   map[types.Object][]string
+}
+
+// binding is an expression of the program given to a variable, or the type
+// expression it is declared with.
+type binding struct {
+	info *types.Info
+	expr ast.Expr
+	// suffix is appended to the prefixes of expr: the path of chi's
+	// r.Route("/x", func(r chi.Router) {...}) for the parameter of the literal.
+	suffix string
+}
+
+func newRouteTypes(ls *linkState) *routeTypes {
+	rt := &routeTypes{
+		ls:         ls,
+		declared:   make(map[types.Object]binding),
+		bindings:   make(map[types.Object][]binding),
+		frameworks: make(map[types.Object]string),
+		prefixes:   make(map[types.Object][]string),
+	}
+	ls.eachFile(func(pk *pkg, _ string, f *ast.File) {
+		info := pk.info
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch t := n.(type) {
+			case *ast.FuncDecl:
+				if fn, ok := info.Defs[t.Name].(*types.Func); ok && t.Type.Results != nil && len(t.Type.Results.List) > 0 {
+					rt.declared[fn] = binding{info: info, expr: t.Type.Results.List[0].Type}
+				}
+			case *ast.Field:
+				for _, name := range t.Names {
+					if obj := info.Defs[name]; obj != nil {
+						rt.declared[obj] = binding{info: info, expr: t.Type}
+					}
+				}
+			case *ast.ValueSpec:
+				for i, name := range t.Names {
+					obj := info.Defs[name]
+					if obj == nil {
+						continue
+					}
+					if t.Type != nil {
+						rt.declared[obj] = binding{info: info, expr: t.Type}
+					}
+					if len(t.Names) == len(t.Values) {
+						rt.bind(obj, binding{info: info, expr: t.Values[i]})
+					}
+				}
+			case *ast.AssignStmt:
+				if len(t.Lhs) == len(t.Rhs) {
+					for i, lhs := range t.Lhs {
+						rt.bind(assigned(info, lhs), binding{info: info, expr: t.Rhs[i]})
+					}
+				}
+			case *ast.KeyValueExpr:
+				// &Server{router: gin.New()}
+				if key, ok := t.Key.(*ast.Ident); ok {
+					if field, ok := info.Uses[key].(*types.Var); ok && field.IsField() {
+						rt.bind(field, binding{info: info, expr: t.Value})
+					}
+				}
+			case *ast.CallExpr:
+				rt.bindCall(info, t)
+			}
+			return true
+		})
+	})
+	return rt
+}
+
+func (rt *routeTypes) bind(obj types.Object, b binding) {
+	if obj != nil {
+		rt.bindings[obj] = append(rt.bindings[obj], b)
+	}
+}
+
+// bindCall binds the parameters of the functions of the program call may
+// invoke to its arguments, and the router parameter of a function literal
+// passed to chi's Route or Group to the router they are called on.
+func (rt *routeTypes) bindCall(info *types.Info, call *ast.CallExpr) {
+	if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && len(call.Args) > 0 {
+		if lit, ok := call.Args[len(call.Args)-1].(*ast.FuncLit); ok && len(lit.Type.Params.List) > 0 && len(lit.Type.Params.List[0].Names) > 0 {
+			b := binding{info: info, expr: sel.X}
+			switch {
+			case sel.Sel.Name == "Route" && len(call.Args) == 2:
+				b.suffix, ok = constString(info, call.Args[0])
+			case sel.Sel.Name == "Group" && len(call.Args) == 1:
+			default:
+				ok = false
+			}
+			if ok {
+				rt.bind(info.Defs[lit.Type.Params.List[0].Names[0]], b)
+			}
+		}
+	}
+	for _, fn := range rt.ls.callees(info, call) {
+		if fn.Pkg() == nil || rt.ls.prog.packages[fn.Pkg().Path()] == nil {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		for i, arg := range call.Args {
+			if i >= sig.Params().Len() || (sig.Variadic() && i == sig.Params().Len()-1) {
+				break
+			}
+			rt.bind(sig.Params().At(i), binding{info: info, expr: arg})
+		}
+	}
+}
+
+// assigned returns the variable or field an assignment stores to.
+func assigned(info *types.Info, lhs ast.Expr) types.Object {
+	switch t := ast.Unparen(lhs).(type) {
+	case *ast.Ident:
+		return info.ObjectOf(t)
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[t]; ok && sel.Kind() == types.FieldVal {
+			return sel.Obj()
+		}
+	}
+	return nil
+}
+
+// routes returns the route registrations of f, in source order, with a site
+// per path when the router carries several prefixes.
+func (rt *routeTypes) routes(info *types.Info, f *ast.File) []routeSite {
+	var sites []routeSite
+	eval := func(expr ast.Expr) (string, bool) { return constString(info, expr) }
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok || !registersRoutes(sel.Sel.Name) {
+			return true
+		}
+		fw := rt.framework(info, sel.X)
+		if fw == "" {
+			return true
+		}
+		site, ok := registration(fw, call, eval)
+		if !ok {
+			return true
+		}
+		for _, prefix := range rt.prefixesOf(info, sel.X) {
+			s := site
+			s.path = joinPath(prefix, site.path)
+			sites = append(sites, s)
+		}
+		return true
+	})
+	return sites
+}
+
+// framework returns the framework of the router expr denotes, or "".
+func (rt *routeTypes) framework(info *types.Info, expr ast.Expr) string {
+	expr = ast.Unparen(expr)
+	if fw := typeFramework(info.TypeOf(expr)); fw != "" {
+		return fw // *http.ServeMux
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if pkgName, ok := info.Uses[t].(*types.PkgName); ok {
+			return frameworkOfPath(pkgName.Imported().Path()) // http.HandleFunc
+		}
+		return rt.objectFramework(info.ObjectOf(t))
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			if pkgName, ok := info.Uses[x].(*types.PkgName); ok {
+				return frameworkOfPath(pkgName.Imported().Path()) // gin.Default, *chi.Mux
+			}
+		}
+		if sel, ok := info.Selections[t]; ok && sel.Kind() == types.FieldVal {
+			return rt.objectFramework(sel.Obj())
+		}
+		return rt.framework(info, t.X) // r.Group
+	case *ast.CallExpr:
+		for _, fn := range rt.ls.callees(info, t) {
+			if b, ok := rt.declared[fn]; ok {
+				if fw := rt.framework(b.info, b.expr); fw != "" {
+					return fw
+				}
+			}
+		}
+		return rt.framework(info, t.Fun)
+	case *ast.StarExpr:
+		return rt.framework(info, t.X)
+	case *ast.UnaryExpr:
+		return rt.framework(info, t.X)
+	case *ast.CompositeLit:
+		return rt.framework(info, t.Type)
+	case *ast.IndexExpr:
+		return rt.framework(info, t.X)
+	}
+	return ""
+}
+
+// objectFramework returns the framework of the router held by a variable,
+// after its declared type or else the values it is given.
+func (rt *routeTypes) objectFramework(obj types.Object) string {
+	if obj == nil {
+		return ""
+	}
+	if fw, ok := rt.frameworks[obj]; ok {
+		return fw
+	}
+	rt.frameworks[obj] = "" // guards against cycles
+	fw := ""
+	if b, ok := rt.declared[obj]; ok {
+		fw = rt.framework(b.info, b.expr)
+	}
+	for _, b := range rt.bindings[obj] {
+		if fw != "" {
+			break
+		}
+		fw = rt.framework(b.info, b.expr)
+	}
+	rt.frameworks[obj] = fw
+	return fw
+}
+
+// typeFramework returns the framework declaring typ, or a pointer to it.
+func typeFramework(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return ""
+	}
+	return frameworkOfPath(named.Obj().Pkg().Path())
+}
+
+// prefixesOf returns the path prefixes of the router expr denotes, sorted;
+// "" stands for no prefix.
+func (rt *routeTypes) prefixesOf(info *types.Info, expr ast.Expr) []string {
+	switch t := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return rt.objectPrefixes(info.ObjectOf(t))
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[t]; ok && sel.Kind() == types.FieldVal {
+			return rt.objectPrefixes(sel.Obj())
+		}
+	case *ast.CallExpr:
+		sel, ok := ast.Unparen(t.Fun).(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		switch sel.Sel.Name {
+		case "Group", "Route":
+			// r.Group("/v1") in Gin and Echo, r.Route("/v1", fn) in chi
+			if len(t.Args) > 0 {
+				if p, ok := constString(info, t.Args[0]); ok {
+					return joinPrefixes(rt.prefixesOf(info, sel.X), p)
+				}
+			}
+			return rt.prefixesOf(info, sel.X)
+		case "With":
+			// chi middleware chains keep the prefix of the router
+			return rt.prefixesOf(info, sel.X)
+		}
+	}
+	return []string{""}
+}
+
+// objectPrefixes returns the prefixes of every router a variable is given.
+func (rt *routeTypes) objectPrefixes(obj types.Object) []string {
+	if obj == nil {
+		return []string{""}
+	}
+	if prefixes, ok := rt.prefixes[obj]; ok {
+		return prefixes
+	}
+	rt.prefixes[obj] = []string{""} // guards against cycles
+	set := make(map[string]bool)
+	for _, b := range rt.bindings[obj] {
+		prefixes := rt.prefixesOf(b.info, b.expr)
+		if b.suffix != "" {
+			prefixes = joinPrefixes(prefixes, b.suffix)
+		}
+		for _, p := range prefixes {
+			set[p] = true
+		}
+	}
+	prefixes := []string{""}
+	if len(set) > 0 {
+		prefixes = make([]string, 0, len(set))
+		for p := range set {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+	}
+	rt.prefixes[obj] = prefixes
+	return prefixes
+}
+
+func joinPrefixes(prefixes []string, p string) []string {
+	joined := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		joined[i] = joinPath(prefix, p)
+	}
+	return joined
+}
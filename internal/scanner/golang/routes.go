@@ -0,0 +1,420 @@
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// Frameworks recognised by their import path. Route registration calls are
+// interpreted according to the framework of the router: GoScanner guesses it
+// from the imports of the file, GoLinker finds it with type information.
+const (
+	frameworkGin     = "gin"
+	frameworkEcho    = "echo"
+	frameworkChi     = "chi"
+	frameworkNetHTTP = "net/http"
+)
+
+var frameworkImports = []struct {
+	prefix    string
+	framework string
+}{
+	{"github.com/gin-gonic/gin", frameworkGin},
+	{"github.com/labstack/echo", frameworkEcho},
+	{"github.com/go-chi/chi", frameworkChi},
+	{"net/http", frameworkNetHTTP},
+}
+
+// Gin and Echo name their registration methods after the HTTP method.
+var upperMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true,
+	"HEAD": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// chi uses Go-style method names.
+var chiMethods = map[string]string{
+	"Get": "GET", "Post": "POST", "Put": "PUT", "Delete": "DELETE", "Patch": "PATCH",
+	"Head": "HEAD", "Options": "OPTIONS", "Connect": "CONNECT", "Trace": "TRACE",
+}
+
+// methodAny marks routes that accept every HTTP method.
+const methodAny = "ANY"
+
+// routeSite is an HTTP route registration found in a file.
+type routeSite struct {
+	call      *ast.CallExpr
+	method    string
+	path      string   // full path, including group prefixes
+	handler   ast.Expr // nil when the handler could not be identified
+	framework string
+}
+
+func (r routeSite) name() string {
+	return r.method + " " + r.path
+}
+
+// routeFinder walks a file in source order, tracking the path prefix bound to
+// router groups so that registrations on a group get their full path.
+type routeFinder struct {
+	frameworks map[string]bool
+	prefixes   map[interface{}]string // *ast.Object or expression text -> prefix
+	sites      []routeSite
+}
+
+// findRoutes returns the route registrations of file, in source order.
+func findRoutes(file *ast.File) []routeSite {
+	rf := &routeFinder{
+		frameworks: make(map[string]bool),
+		prefixes:   make(map[interface{}]string),
+	}
+	for _, imp := range file.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		if fw := frameworkOfPath(p); fw != "" {
+			rf.frameworks[fw] = true
+		}
+	}
+	if len(rf.frameworks) == 0 {
+		return nil
+	}
+	ast.Inspect(file, rf.visit)
+	return rf.sites
+}
+
+func (rf *routeFinder) visit(n ast.Node) bool {
+	switch t := n.(type) {
+	case *ast.AssignStmt:
+		// v1 := r.Group("/v1")
+		if len(t.Lhs) == len(t.Rhs) {
+			for i, rhs := range t.Rhs {
+				if prefix, ok := rf.groupPrefix(rhs); ok {
+					rf.prefixes[key(t.Lhs[i])] = prefix
+				}
+			}
+		}
+	case *ast.ValueSpec:
+		// var v1 = r.Group("/v1")
+		if len(t.Names) == len(t.Values) {
+			for i, value := range t.Values {
+				if prefix, ok := rf.groupPrefix(value); ok {
+					rf.prefixes[key(t.Names[i])] = prefix
+				}
+			}
+		}
+	case *ast.CallExpr:
+		rf.bindSubrouter(t)
+		if sel, ok := t.Fun.(*ast.SelectorExpr); ok {
+			if site, ok := registration(rf.framework(sel, len(t.Args)), t, stringValue); ok {
+				site.path = joinPath(rf.prefixOf(sel.X), site.path)
+				rf.sites = append(rf.sites, site)
+			}
+		}
+	}
+	return true
+}
+
+// bindSubrouter gives the router parameter of chi's r.Route("/x", func(r chi.Router) {...})
+// and r.Group(func(r chi.Router) {...}) the prefix of the enclosing router.
+func (rf *routeFinder) bindSubrouter(call *ast.CallExpr) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !rf.frameworks[frameworkChi] || len(call.Args) == 0 {
+		return
+	}
+	prefix := rf.prefixOf(sel.X)
+	switch sel.Sel.Name {
+	case "Route":
+		if len(call.Args) != 2 {
+			return
+		}
+		p, ok := stringValue(call.Args[0])
+		if !ok {
+			return
+		}
+		prefix = joinPath(prefix, p)
+	case "Group":
+	default:
+		return
+	}
+	lit, ok := call.Args[len(call.Args)-1].(*ast.FuncLit)
+	if !ok || lit.Type.Params == nil || len(lit.Type.Params.List) == 0 || len(lit.Type.Params.List[0].Names) == 0 {
+		return
+	}
+	rf.prefixes[key(lit.Type.Params.List[0].Names[0])] = prefix
+}
+
+// groupPrefix reports the prefix of expr when it creates a router group.
+func (rf *routeFinder) groupPrefix(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	switch sel.Sel.Name {
+	case "Group", "Route":
+		if len(call.Args) > 0 {
+			if p, ok := stringValue(call.Args[0]); ok {
+				return joinPath(rf.prefixOf(sel.X), p), true
+			}
+		}
+	case "With":
+		// chi middleware chains keep the prefix of the router
+		return rf.prefixOf(sel.X), true
+	}
+	return "", false
+}
+
+// prefixOf returns the path prefix bound to a router expression.
+func (rf *routeFinder) prefixOf(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return rf.prefixes[key(t)]
+	case *ast.CallExpr:
+		if prefix, ok := rf.groupPrefix(t); ok {
+			return prefix
+		}
+	case *ast.ParenExpr:
+		return rf.prefixOf(t.X)
+	}
+	return ""
+}
+
+// framework guesses the framework of a route registration from the imports
+// of the file and the shape of the call; GoLinker decides it from the type of
+// the router instead.
+func (rf *routeFinder) framework(sel *ast.SelectorExpr, nargs int) string {
+	name := sel.Sel.Name
+	switch {
+	case upperMethods[name] || name == "Any":
+		if rf.frameworks[frameworkEcho] && !rf.frameworks[frameworkGin] {
+			return frameworkEcho
+		}
+		return frameworkGin
+	case chiMethods[name] != "" && rf.frameworks[frameworkChi]:
+		return frameworkChi
+	case name == "Handle" && nargs == 3:
+		return frameworkGin
+	case (name == "Method" || name == "MethodFunc") && nargs == 3:
+		return frameworkChi
+	case (name == "Handle" || name == "HandleFunc") && nargs == 2:
+		if pkg, ok := sel.X.(*ast.Ident); rf.frameworks[frameworkChi] && !(ok && pkg.Name == "http") {
+			return frameworkChi
+		}
+		return frameworkNetHTTP
+	}
+	return ""
+}
+
+// registersRoutes reports whether name is the name of a method or function
+// registering routes in one of the frameworks.
+func registersRoutes(name string) bool {
+	switch name {
+	case "Any", "Handle", "HandleFunc", "Method", "MethodFunc":
+		return true
+	}
+	return upperMethods[name] || chiMethods[name] != ""
+}
+
+// registration interprets call as the registration of a handler on a router
+// of framework fw, evaluating strings with eval. The path of the site is the
+// one written in the call, without the prefix of the router.
+func registration(fw string, call *ast.CallExpr, eval func(ast.Expr) (string, bool)) (routeSite, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || len(call.Args) < 2 {
+		return routeSite{}, false
+	}
+	name := sel.Sel.Name
+	args := call.Args
+	site := routeSite{call: call, framework: fw}
+
+	var pathArg ast.Expr
+	switch {
+	case (fw == frameworkGin || fw == frameworkEcho) && (upperMethods[name] || name == "Any"):
+		// r.GET(path, middleware..., handler) in Gin; e.GET(path, handler, middleware...) in Echo
+		site.method, pathArg, site.handler = name, args[0], args[len(args)-1]
+		if name == "Any" {
+			site.method = methodAny
+		}
+		if fw == frameworkEcho {
+			site.handler = args[1]
+		}
+	case fw == frameworkChi && chiMethods[name] != "":
+		site.method, pathArg, site.handler = chiMethods[name], args[0], args[1]
+	case (fw == frameworkGin && name == "Handle" || fw == frameworkChi && (name == "Method" || name == "MethodFunc")) && len(args) == 3:
+		// r.Handle("GET", path, handler) in Gin; r.Method("GET", path, handler) in chi
+		method, ok := eval(args[0])
+		if !ok {
+			return routeSite{}, false
+		}
+		site.method, pathArg, site.handler = strings.ToUpper(method), args[1], args[2]
+	case (fw == frameworkNetHTTP || fw == frameworkChi) && (name == "Handle" || name == "HandleFunc") && len(args) == 2:
+		// mux.HandleFunc("GET /orders/{id}", handler) in net/http; r.HandleFunc(path, handler) in chi
+		pattern, ok := eval(args[0])
+		if !ok {
+			return routeSite{}, false
+		}
+		site.method, site.handler = methodAny, args[1]
+		if method, p, found := strings.Cut(pattern, " "); found && upperMethods[method] {
+			site.method, pattern = method, strings.TrimSpace(p)
+		}
+		site.path = pattern
+		return site, strings.HasPrefix(pattern, "/")
+	default:
+		return routeSite{}, false
+	}
+
+	p, ok := eval(pathArg)
+	if !ok || (p != "" && !strings.HasPrefix(p, "/")) {
+		return routeSite{}, false
+	}
+	site.path = p
+	return site, true
+}
+
+// frameworkOfPath returns the framework an import path belongs to, or "".
+func frameworkOfPath(importPath string) string {
+	for _, fi := range frameworkImports {
+		if importPath == fi.prefix || strings.HasPrefix(importPath, fi.prefix+"/") {
+			return fi.framework
+		}
+	}
+	return ""
+}
+
+// key identifies a router variable: by its declaration when the parser
+// resolved one, by its source text otherwise (e.g. s.router).
+func key(expr ast.Expr) interface{} {
+	if ident, ok := expr.(*ast.Ident); ok && ident.Obj != nil {
+		return ident.Obj
+	}
+	return exprString(expr)
+}
+
+// stringValue evaluates string literals, constants declared in the same file
+// and concatenations of them.
+func stringValue(expr ast.Expr) (string, bool) {
+	switch t := expr.(type) {
+	case *ast.BasicLit:
+		if t.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(t.Value)
+		return s, err == nil
+	case *ast.ParenExpr:
+		return stringValue(t.X)
+	case *ast.BinaryExpr:
+		if t.Op != token.ADD {
+			return "", false
+		}
+		x, ok := stringValue(t.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := stringValue(t.Y)
+		return x + y, ok
+	case *ast.Ident:
+		if t.Obj == nil || t.Obj.Kind != ast.Con {
+			return "", false
+		}
+		spec, ok := t.Obj.Decl.(*ast.ValueSpec)
+		if !ok {
+			return "", false
+		}
+		for i, name := range spec.Names {
+			if name.Name == t.Name && i < len(spec.Values) {
+				return stringValue(spec.Values[i])
+			}
+		}
+	}
+	return "", false
+}
+
+// joinPath appends p to prefix with exactly one slash between them.
+func joinPath(prefix, p string) string {
+	switch {
+	case p == "":
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	case prefix == "":
+		return p
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// handlerSymbol names the function a handler expression refers to without
+// type information: "(OrderHandler).GetByID" for h.GetByID where h is declared
+// as *handlers.OrderHandler, or the plain function name.
+func handlerSymbol(file *ast.File, expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		x, ok := t.X.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		if isImportName(file, x.Name) {
+			return t.Sel.Name
+		}
+		if typ := declaredType(x); typ != nil {
+			if name := typeName(typ); name != "" {
+				return "(" + name + ")." + t.Sel.Name
+			}
+		}
+	}
+	return ""
+}
+
+// declaredType returns the type expression an identifier was declared with,
+// for parameters and typed var declarations.
+func declaredType(ident *ast.Ident) ast.Expr {
+	if ident.Obj == nil {
+		return nil
+	}
+	switch d := ident.Obj.Decl.(type) {
+	case *ast.Field:
+		return d.Type
+	case *ast.ValueSpec:
+		return d.Type
+	}
+	return nil
+}
+
+// typeName returns the base name of a named type expression: "OrderHandler"
+// for *handlers.OrderHandler.
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return typeName(t.X)
+	case *ast.IndexListExpr:
+		return typeName(t.X)
+	}
+	return ""
+}
+
+func isImportName(file *ast.File, name string) bool {
+	for _, imp := range file.Imports {
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return true
+			}
+			continue
+		}
+		p, _ := strconv.Unquote(imp.Path.Value)
+		if guessPackageName(p) == name {
+			return true
+		}
+	}
+	return false
+}
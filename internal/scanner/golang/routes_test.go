@@ -0,0 +1,360 @@
+package golang
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// linkTree writes files, keyed by slash-separated path, to a new directory,
+// scans every Go file and links the tree. It returns the nodes, those derived
+// by the linker included, and the edges.
+func linkTree(t *testing.T, files map[string]string) ([]*models.CodeNode, []*models.Edge) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	tree := scanner.Tree{FS: os.DirFS(root), Root: root}
+	s := NewGoScanner()
+	var nodes []*models.CodeNode
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		scanned, err := s.ScanTree(ctx, tree, filepath.Join(root, filepath.FromSlash(name)), []byte(content))
+		if err != nil {
+			t.Fatalf("scan %s: %v", name, err)
+		}
+		nodes = append(nodes, scanned...)
+	}
+	graph, err := NewGoLinker().Link(ctx, tree, nodes)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	return append(nodes, graph.Nodes...), graph.Edges
+}
+
+const goMod = "module example.com/app\n\ngo 1.23\n"
+
+func TestLinkRoutes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want maps each route to its framework and the handlers linked to
+		// it, in "framework: handler, ..." form.
+		want map[string]string
+	}{
+		{
+			name: "gin groups passed to another function",
+			files: map[string]string{
+				"main.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+type Handler struct{}
+
+func (h *Handler) Create(c *gin.Context) {}
+func (h *Handler) Get(c *gin.Context)    {}
+
+func main() {
+	h := &Handler{}
+	r := gin.Default()
+	api := r.Group("/api/v1")
+	registerUsers(api.Group("/users"), h)
+	r.Run()
+}
+`,
+				"users.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+func registerUsers(g *gin.RouterGroup, h *Handler) {
+	g.POST("", h.Create)
+	g.GET("/:id", h.Get)
+}
+`,
+			},
+			want: map[string]string{
+				"POST /api/v1/users":    "gin: (Handler).Create",
+				"GET /api/v1/users/:id": "gin: (Handler).Get",
+			},
+		},
+		{
+			name: "group mounted under two prefixes",
+			files: map[string]string{
+				"main.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+func ping(c *gin.Context) {}
+
+func mount(g *gin.RouterGroup) {
+	g.GET("/ping", ping)
+}
+
+func main() {
+	r := gin.New()
+	mount(r.Group("/v1"))
+	mount(r.Group("/v2"))
+}
+`,
+			},
+			want: map[string]string{
+				"GET /v1/ping": "gin: ping",
+				"GET /v2/ping": "gin: ping",
+			},
+		},
+		{
+			name: "ServeMux in a file importing chi",
+			files: map[string]string{
+				"main.go": `package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func health(w http.ResponseWriter, r *http.Request)     {}
+func listUsers(w http.ResponseWriter, r *http.Request)  {}
+func createUser(w http.ResponseWriter, r *http.Request) {}
+
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", health)
+
+	r := chi.NewRouter()
+	r.Get("/users", listUsers)
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/users", createUser)
+	})
+	mux.Handle("/", r)
+}
+`,
+			},
+			want: map[string]string{
+				"ANY /health":       "net/http: health",
+				"GET /users":        "chi: listUsers",
+				"POST /admin/users": "chi: createUser",
+				"ANY /":             "net/http: ",
+			},
+		},
+		{
+			name: "inline handler",
+			files: map[string]string{
+				"main.go": `package main
+
+import "net/http"
+
+func setup() {
+	http.HandleFunc("GET /inline", func(w http.ResponseWriter, r *http.Request) {})
+}
+`,
+			},
+			want: map[string]string{
+				"GET /inline": "net/http: ",
+			},
+		},
+		{
+			name: "echo router held in a field",
+			files: map[string]string{
+				"server.go": `package main
+
+import "github.com/labstack/echo/v4"
+
+type Server struct {
+	router *echo.Echo
+}
+
+func NewServer() *Server {
+	return &Server{router: echo.New()}
+}
+
+func (s *Server) status(c echo.Context) error { return nil }
+func (s *Server) list(c echo.Context) error   { return nil }
+
+func (s *Server) routes() {
+	s.router.GET("/status", s.status)
+	api := s.router.Group("/api")
+	api.GET("/items", s.list, nil)
+}
+`,
+			},
+			want: map[string]string{
+				"GET /status":    "echo: (Server).status",
+				"GET /api/items": "echo: (Server).list",
+			},
+		},
+		{
+			name: "router returned by a constructor",
+			files: map[string]string{
+				"main.go": `package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const base = "/orders"
+
+func list(w http.ResponseWriter, r *http.Request) {}
+
+func newRouter() *chi.Mux {
+	return chi.NewRouter()
+}
+
+func main() {
+	r := newRouter()
+	r.Method(http.MethodGet, base, http.HandlerFunc(list))
+}
+`,
+			},
+			want: map[string]string{
+				"GET /orders": "chi: list",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"go.mod": goMod}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			nodes, edges := linkTree(t, files)
+
+			byID := make(map[string]*models.CodeNode)
+			for _, n := range nodes {
+				byID[n.ID] = n
+			}
+			handlers := make(map[string][]string) // route ID -> handler names
+			for _, e := range edges {
+				if e.Kind == models.EdgeHandlesRoute {
+					handlers[e.Target] = append(handlers[e.Target], byID[e.Source].Name)
+				}
+			}
+			got := make(map[string]string)
+			for _, n := range nodes {
+				if n.Type != models.NodeRoute {
+					continue
+				}
+				if _, dup := got[n.Name]; dup {
+					t.Errorf("route %s found twice", n.Name)
+				}
+				sort.Strings(handlers[n.ID])
+				got[n.Name] = n.Metadata["framework"].(string) + ": " + strings.Join(handlers[n.ID], ", ")
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("route %s = %q, want %q", name, got[name], want)
+				}
+			}
+			for name := range got {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("unexpected route %s = %q", name, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestFindRoutes(t *testing.T) {
+	// Without types, the framework is guessed from the imports and prefixes
+	// only follow the groups of the file.
+	tests := []struct {
+		name string
+		src  string
+		want []string // "framework METHOD path"
+	}{
+		{
+			name: "gin",
+			src: `package main
+
+import "github.com/gin-gonic/gin"
+
+func main() {
+	r := gin.Default()
+	v1 := r.Group("/v1")
+	v1.GET("/users/:id", getUser)
+	r.Handle("delete", "/users/:id", deleteUser)
+	r.Any("/proxy", proxy)
+}
+`,
+			want: []string{"gin GET /v1/users/:id", "gin DELETE /users/:id", "gin ANY /proxy"},
+		},
+		{
+			name: "chi",
+			src: `package main
+
+import "github.com/go-chi/chi/v5"
+
+const users = "/users"
+
+func main() {
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.With(auth).Get(users+"/{id}", getUser)
+	})
+	r.HandleFunc("/raw", raw)
+}
+`,
+			want: []string{"chi GET /api/users/{id}", "chi ANY /raw"},
+		},
+		{
+			name: "net/http",
+			src: `package main
+
+import "net/http"
+
+func main() {
+	http.HandleFunc("POST /orders", create)
+	mux := http.NewServeMux()
+	mux.Handle("/static/", files)
+	mux.HandleFunc("relative", nope)
+}
+`,
+			want: []string{"net/http POST /orders", "net/http ANY /static/"},
+		},
+		{
+			name: "no framework",
+			src: `package main
+
+func main() {
+	c.GET("/users", list)
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := NewGoScanner().Scan(context.Background(), "", "main.go", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range nodes {
+				if n.Type == models.NodeRoute {
+					got = append(got, n.Metadata["framework"].(string)+" "+n.Name)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("routes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,83 +1,121 @@
 package golang
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	goscanner "go/scanner"
 	"go/token"
 
 	"github.com/chinmay-sawant/gosourcemapper/internal/models"
-	"github.com/google/uuid"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
 )
 
-type GoScanner struct{}
+type GoScanner struct {
+	modules *moduleResolver
+}
 
 func NewGoScanner() *GoScanner {
-	return &GoScanner{}
+	return &GoScanner{modules: newModuleResolver()}
+}
+
+func (s *GoScanner) Scan(ctx context.Context, root, filePath string, content []byte) ([]*models.CodeNode, error) {
+	return s.scan(ctx, s.modules, root, filePath, content)
+}
+
+// ScanTree finds the module of the file in tree rather than on disk.
+func (s *GoScanner) ScanTree(ctx context.Context, tree scanner.Tree, filePath string, content []byte) ([]*models.CodeNode, error) {
+	return s.scan(ctx, treeModuleResolver(tree), tree.Root, filePath, content)
 }
 
-func (s *GoScanner) Scan(filePath string, content []byte) ([]*models.CodeNode, error) {
+func (s *GoScanner) scan(ctx context.Context, modules *moduleResolver, root, filePath string, content []byte) ([]*models.CodeNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	fset := token.NewFileSet()
 	node, err := parser.ParseFile(fset, filePath, content, parser.ParseComments)
 	if err != nil {
-		return nil, err
+		return nil, parseError(filePath, err)
 	}
 
 	var nodes []*models.CodeNode
+	ids := models.NewIDAllocator("go", node.Name.Name, models.IDPath(root, filePath))
+	pkgPath := modules.importPath(filePath, node.Name.Name)
+	httpNames := httpImportNames(node)
+
+	// enclosing is the function declaration currently being walked, used to
+	// give call sites a stable identity within the file.
+	var enclosing *ast.FuncDecl
 
 	ast.Inspect(node, func(n ast.Node) bool {
 		switch t := n.(type) {
 		case *ast.FuncDecl:
-			nodes = append(nodes, s.parseFunction(fset, node, t, filePath))
+			enclosing = t
+			nodes = append(nodes, s.parseFunction(fset, node, t, filePath, pkgPath, ids))
 		case *ast.TypeSpec:
-			if _, ok := t.Type.(*ast.InterfaceType); ok {
-				nodes = append(nodes, s.parseInterface(fset, node, t, filePath))
+			switch t.Type.(type) {
+			case *ast.InterfaceType:
+				nodes = append(nodes, s.parseType(fset, node, t, filePath, models.NodeInterface, ids))
+			case *ast.StructType:
+				nodes = append(nodes, s.parseType(fset, node, t, filePath, models.NodeStruct, ids))
 			}
 		case *ast.CallExpr:
-			// Detect HTTP Clients (Basic Heuristic)
-			if httpNode := s.parseHTTPCall(fset, t, filePath); httpNode != nil {
-				nodes = append(nodes, httpNode)
+			caller := ""
+			if enclosing != nil && t.Pos() >= enclosing.Pos() && t.End() <= enclosing.End() {
+				caller = funcName(enclosing)
+			}
+			// Detect outbound HTTP requests; GoLinker adds those only types reveal
+			if site, ok := syntacticRequest(httpNames, t); ok {
+				nodes = append(nodes, newHTTPCallNode(fset, site, syntacticFolder(httpNames), filePath, caller, ids))
 			}
 		}
 		return true
 	})
 
+	nodes = append(nodes, s.parseRoutes(fset, node, filePath, ids)...)
 	return nodes, nil
 }
 
-func (s *GoScanner) parseFunction(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl, filePath string) *models.CodeNode {
-	comments := s.extractComments(fset, file, fn.Pos())
+// funcName returns the qualified name of a function declaration, using
+// "(Type).Method" for methods.
+func funcName(fn *ast.FuncDecl) string {
 	name := fn.Name.Name
 	if fn.Recv != nil {
 		// Method
 		for _, field := range fn.Recv.List {
-			typeExpr := field.Type
-			if star, ok := typeExpr.(*ast.StarExpr); ok {
-				if ident, ok := star.X.(*ast.Ident); ok {
-					name = fmt.Sprintf("(%s).%s", ident.Name, name)
-				}
-			} else if ident, ok := typeExpr.(*ast.Ident); ok {
-				name = fmt.Sprintf("(%s).%s", ident.Name, name)
+			if recv, _ := receiverType(field.Type); recv != "" {
+				name = fmt.Sprintf("(%s).%s", recv, name)
 			}
 		}
 	}
+	return name
+}
+
+func (s *GoScanner) parseFunction(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl, filePath, pkgPath string, ids *models.IDAllocator) *models.CodeNode {
+	comments := s.extractComments(fset, file, fn.Pos())
+	name := funcName(fn)
+	meta := funcMetadata(fn)
+	meta["package"] = pkgPath
 
 	return &models.CodeNode{
-		ID:         uuid.New().String(),
+		ID:         ids.ID(name, models.NodeFunction),
 		Type:       models.NodeFunction,
 		Name:       name,
 		Language:   "go",
 		FilePath:   filePath,
 		LineNumber: fset.Position(fn.Pos()).Line,
+		Signature:  signature(fn),
 		Comments:   comments,
+		Metadata:   meta,
 	}
 }
 
-func (s *GoScanner) parseInterface(fset *token.FileSet, file *ast.File, typeSpec *ast.TypeSpec, filePath string) *models.CodeNode {
+func (s *GoScanner) parseType(fset *token.FileSet, file *ast.File, typeSpec *ast.TypeSpec, filePath string, kind models.NodeType, ids *models.IDAllocator) *models.CodeNode {
 	comments := s.extractComments(fset, file, typeSpec.Pos())
 	return &models.CodeNode{
-		ID:         uuid.New().String(),
-		Type:       models.NodeInterface,
+		ID:         ids.ID(typeSpec.Name.Name, kind),
+		Type:       kind,
 		Name:       typeSpec.Name.Name,
 		Language:   "go",
 		FilePath:   filePath,
@@ -86,23 +124,67 @@ func (s *GoScanner) parseInterface(fset *token.FileSet, file *ast.File, typeSpec
 	}
 }
 
-func (s *GoScanner) parseHTTPCall(fset *token.FileSet, call *ast.CallExpr, filePath string) *models.CodeNode {
-	// Heuristic: Check for http.Get, http.Post, http.NewRequest
-	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
-		if ident, ok := sel.X.(*ast.Ident); ok {
-			if ident.Name == "http" && (sel.Sel.Name == "Get" || sel.Sel.Name == "Post" || sel.Sel.Name == "NewRequest") {
-				return &models.CodeNode{
-					ID:         uuid.New().String(),
-					Type:       models.NodeHTTPCall,
-					Name:       fmt.Sprintf("http.%s", sel.Sel.Name),
-					Language:   "go",
-					FilePath:   filePath,
-					LineNumber: fset.Position(call.Pos()).Line,
-				}
-			}
+// newHTTPCallNode describes an outbound request site, recording its method,
+// URL template and enclosing function.
+func newHTTPCallNode(fset *token.FileSet, site requestSite, f folder, filePath, caller string, ids *models.IDAllocator) *models.CodeNode {
+	meta := map[string]interface{}{"caller": caller}
+	if site.shape.method != "" {
+		meta["method"] = site.shape.method
+	} else if expr := site.methodExpr(); expr != nil {
+		meta["method"] = httpMethod(f.fold(expr))
+	}
+	if expr := site.urlExpr(); expr != nil {
+		meta["url"] = f.fold(expr).String()
+	}
+	return &models.CodeNode{
+		// Call sites are identified by the function they appear in
+		ID:         ids.ID(caller+">"+site.name, models.NodeHTTPCall),
+		Type:       models.NodeHTTPCall,
+		Name:       site.name,
+		Language:   "go",
+		FilePath:   filePath,
+		LineNumber: fset.Position(site.call.Pos()).Line,
+		Metadata:   meta,
+	}
+}
+
+// parseRoutes returns a ROUTE node for every handler registration in file.
+// The handler is recorded as written; GoLinker resolves it to a function node
+// and corrects the framework and path with type information.
+func (s *GoScanner) parseRoutes(fset *token.FileSet, file *ast.File, filePath string, ids *models.IDAllocator) []*models.CodeNode {
+	var nodes []*models.CodeNode
+	for _, site := range findRoutes(file) {
+		nodes = append(nodes, newRouteNode(fset, file, site, filePath, ids))
+	}
+	return nodes
+}
+
+// newRouteNode describes a route registration, recording its method, path,
+// framework and handler expression.
+func newRouteNode(fset *token.FileSet, file *ast.File, site routeSite, filePath string, ids *models.IDAllocator) *models.CodeNode {
+	name := site.name()
+	meta := map[string]interface{}{
+		"method":    site.method,
+		"path":      site.path,
+		"framework": site.framework,
+	}
+	if lit, ok := site.handler.(*ast.FuncLit); ok {
+		meta["handler"] = exprString(lit.Type) // inline handler
+	} else if site.handler != nil {
+		meta["handler"] = exprString(site.handler)
+		if symbol := handlerSymbol(file, site.handler); symbol != "" {
+			meta["handler_symbol"] = symbol
 		}
 	}
-	return nil
+	return &models.CodeNode{
+		ID:         ids.ID(name, models.NodeRoute),
+		Type:       models.NodeRoute,
+		Name:       name,
+		Language:   "go",
+		FilePath:   filePath,
+		LineNumber: fset.Position(site.call.Pos()).Line,
+		Metadata:   meta,
+	}
 }
 
 // extractComments recursively finds comments appearing immediately before the position.
@@ -155,3 +237,18 @@ func (s *GoScanner) extractComments(fset *token.FileSet, file *ast.File, pos tok
 
 	return comments
 }
+
+// parseError turns the syntax errors reported by go/parser into a
+// scanner.ParseError located at the first of them.
+func parseError(filePath string, err error) error {
+	list, ok := err.(goscanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return err
+	}
+	first := list[0]
+	msg := first.Msg
+	if len(list) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(list)-1)
+	}
+	return &scanner.ParseError{File: filePath, Line: first.Pos.Line, Column: first.Pos.Column, Msg: msg}
+}
@@ -0,0 +1,102 @@
+package golang
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// Scope returns the Go files of the modules holding changed Go files and of
+// the modules of the tree that require them, directly or not. Relinking whole
+// modules finds what a full link would, except calls through an interface to
+// implementations in modules that do not depend on it; those are found again
+// by the next full scan. Changes to other files leave Go relations alone.
+func (l *GoLinker) Scope(ctx context.Context, tree scanner.Tree, changed []string) (map[string]bool, error) {
+	modules := make(map[string]string)    // module dir -> module path
+	requires := make(map[string][]string) // module path -> required module paths
+	var files []string
+	err := fs.WalkDir(tree.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && scanner.SkipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case d.Name() == "go.mod":
+			data, err := fs.ReadFile(tree.FS, p)
+			if err != nil {
+				return nil
+			}
+			f, err := modfile.ParseLax(p, data, nil)
+			if err != nil || f.Module == nil {
+				return nil
+			}
+			modules[path.Dir(p)] = f.Module.Mod.Path
+			for _, r := range f.Require {
+				requires[f.Module.Mod.Path] = append(requires[f.Module.Mod.Path], r.Mod.Path)
+			}
+		case strings.HasSuffix(p, ".go"):
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Files outside any module count as one more module, with path "".
+	affected := make(map[string]bool)
+	for _, file := range changed {
+		rel, err := filepath.Rel(tree.Root, file)
+		if err != nil || !strings.HasSuffix(rel, ".go") || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		affected[moduleOf(modules, path.Dir(filepath.ToSlash(rel)))] = true
+	}
+	for grown := len(affected) > 0; grown; {
+		grown = false
+		for mod, reqs := range requires {
+			if affected[mod] {
+				continue
+			}
+			for _, req := range reqs {
+				if affected[req] {
+					affected[mod], grown = true, true
+					break
+				}
+			}
+		}
+	}
+
+	scope := make(map[string]bool)
+	for _, file := range files {
+		if affected[moduleOf(modules, path.Dir(file))] {
+			scope[filepath.Join(tree.Root, filepath.FromSlash(file))] = true
+		}
+	}
+	return scope, nil
+}
+
+func (l *GoLinker) EdgeKinds() []models.EdgeKind {
+	return []models.EdgeKind{
+		models.EdgeCalls,
+		models.EdgeImplements,
+		models.EdgeEmbeds,
+		models.EdgeHandlesRoute,
+		models.EdgeReadsEnv,
+	}
+}
@@ -0,0 +1,100 @@
+package golang
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// receiverType returns the base type name of a method receiver and whether it
+// is a pointer, looking through type parameters: (s *Set[T]) gives "Set", true.
+func receiverType(expr ast.Expr) (name string, pointer bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr, pointer = star.X, true
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, pointer
+	}
+	return "", pointer
+}
+
+// signature prints the header of a function declaration without its body,
+// e.g. "func (h *Handler) Get[T any](ctx context.Context, id T) (*Item, error)".
+func signature(fn *ast.FuncDecl) string {
+	header := &ast.FuncDecl{Recv: fn.Recv, Name: fn.Name, Type: fn.Type}
+	var buf bytes.Buffer
+	// A fresh FileSet drops the original positions, so the header is printed
+	// on a single line however the source was wrapped.
+	if err := printer.Fprint(&buf, token.NewFileSet(), header); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// funcMetadata describes a function declaration for API consumers: its
+// receiver, parameters, results and visibility.
+func funcMetadata(fn *ast.FuncDecl) map[string]interface{} {
+	meta := map[string]interface{}{
+		"exported": fn.Name.IsExported(),
+		"params":   fields(fn.Type.Params),
+		"results":  fields(fn.Type.Results),
+		"variadic": isVariadic(fn.Type),
+	}
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		recv := fn.Recv.List[0]
+		name, pointer := receiverType(recv.Type)
+		meta["receiver"] = name
+		meta["pointer_receiver"] = pointer
+		if len(recv.Names) > 0 {
+			meta["receiver_name"] = recv.Names[0].Name
+		}
+	}
+	if fn.Type.TypeParams != nil {
+		meta["type_params"] = fields(fn.Type.TypeParams)
+	}
+	return meta
+}
+
+// fields flattens a field list into one Param per name; unnamed fields get an empty name.
+func fields(list *ast.FieldList) []models.Param {
+	params := []models.Param{}
+	if list == nil {
+		return params
+	}
+	for _, field := range list.List {
+		typ := exprString(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, models.Param{Type: typ})
+			continue
+		}
+		for _, name := range field.Names {
+			params = append(params, models.Param{Name: name.Name, Type: typ})
+		}
+	}
+	return params
+}
+
+func isVariadic(ft *ast.FuncType) bool {
+	if ft.Params == nil || len(ft.Params.List) == 0 {
+		return false
+	}
+	_, ok := ft.Params.List[len(ft.Params.List)-1].Type.(*ast.Ellipsis)
+	return ok
+}
+
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), expr); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,142 @@
+package golang
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func TestSignature(t *testing.T) {
+	src := `package p
+
+import "context"
+
+func Map[K comparable, V any, S ~[]V](m map[K]V, f func(K, V) bool) S { return nil }
+
+func Printf(format string, args ...any) (n int, err error) { return }
+
+func Handle(context.Context, *Request, int) error { return nil }
+
+func ignore(_ context.Context, _, n int) {}
+
+func (s *Set[T]) Add(
+	items ...T,
+) {
+}
+
+func (s Set[T]) Len() int { return 0 }
+
+func (Pair[K, V]) Swap() (V, K) { var v V; var k K; return v, k }
+
+func (*Client) close() {}
+`
+	tests := []struct {
+		name      string
+		signature string
+		// params, results and type params in "name type, ..." form
+		params, results, typeParams string
+		meta                        map[string]interface{}
+	}{
+		{
+			name:       "Map",
+			signature:  "func Map[K comparable, V any, S ~[]V](m map[K]V, f func(K, V) bool) S",
+			params:     "m map[K]V, f func(K, V) bool",
+			results:    "S",
+			typeParams: "K comparable, V any, S ~[]V",
+			meta:       map[string]interface{}{"exported": true, "variadic": false, "receiver": nil},
+		},
+		{
+			name:      "Printf",
+			signature: "func Printf(format string, args ...any) (n int, err error)",
+			params:    "format string, args ...any",
+			results:   "n int, err error",
+			meta:      map[string]interface{}{"variadic": true, "type_params": nil},
+		},
+		{
+			name:      "Handle",
+			signature: "func Handle(context.Context, *Request, int) error",
+			params:    "context.Context, *Request, int",
+			results:   "error",
+			meta:      map[string]interface{}{"variadic": false},
+		},
+		{
+			name:      "ignore",
+			signature: "func ignore(_ context.Context, _, n int)",
+			params:    "_ context.Context, _ int, n int",
+			meta:      map[string]interface{}{"exported": false},
+		},
+		{
+			name:      "(Set).Add",
+			signature: "func (s *Set[T]) Add(items ...T)",
+			params:    "items ...T",
+			meta:      map[string]interface{}{"receiver": "Set", "pointer_receiver": true, "receiver_name": "s", "variadic": true, "type_params": nil},
+		},
+		{
+			name:      "(Set).Len",
+			signature: "func (s Set[T]) Len() int",
+			results:   "int",
+			meta:      map[string]interface{}{"receiver": "Set", "pointer_receiver": false, "receiver_name": "s"},
+		},
+		{
+			name:      "(Pair).Swap",
+			signature: "func (Pair[K, V]) Swap() (V, K)",
+			results:   "V, K",
+			meta:      map[string]interface{}{"receiver": "Pair", "pointer_receiver": false, "receiver_name": nil},
+		},
+		{
+			name:      "(Client).close",
+			signature: "func (*Client) close()",
+			meta:      map[string]interface{}{"receiver": "Client", "pointer_receiver": true, "exported": false},
+		},
+	}
+
+	nodes, err := NewGoScanner().Scan(context.Background(), "", "p.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*models.CodeNode)
+	for _, n := range nodes {
+		if n.Type == models.NodeFunction {
+			byName[n.Name] = n
+		}
+	}
+	params := func(v interface{}) string {
+		ps, _ := v.([]models.Param)
+		var out []string
+		for _, p := range ps {
+			out = append(out, strings.TrimSpace(p.Name+" "+p.Type))
+		}
+		return strings.Join(out, ", ")
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("no function %s", tt.name)
+			}
+			if n.Signature != tt.signature {
+				t.Errorf("signature = %q, want %q", n.Signature, tt.signature)
+			}
+			for _, f := range []struct{ key, want string }{
+				{"params", tt.params},
+				{"results", tt.results},
+				{"type_params", tt.typeParams},
+			} {
+				if got := params(n.Metadata[f.key]); got != f.want {
+					t.Errorf("%s = %q, want %q", f.key, got, f.want)
+				}
+			}
+			for key, want := range tt.meta {
+				got, ok := n.Metadata[key]
+				switch {
+				case want == nil && ok:
+					t.Errorf("%s = %v, want none", key, got)
+				case want != nil && got != want:
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package golang
+
+import (
+	"go/types"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// linkTypes adds IMPLEMENTS edges from structs to the interfaces of the tree
+// they satisfy, and EMBEDS edges for embedded struct fields and interfaces.
+func (ls *linkState) linkTypes() {
+	var ifaces []*types.TypeName
+	for _, pk := range ls.prog.sortedPackages() {
+		if pk.types == nil {
+			continue
+		}
+		scope := pk.types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || ls.types[tn] == nil {
+				continue
+			}
+			// The empty interface is satisfied by everything and says nothing.
+			if iface, ok := tn.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+				ifaces = append(ifaces, tn)
+			}
+		}
+	}
+
+	ls.eachNamedType(func(named *types.Named) {
+		node, ok := ls.types[named.Obj()]
+		if !ok {
+			return
+		}
+		for _, tn := range ifaces {
+			iface := tn.Type().Underlying().(*types.Interface)
+			if typ := implementor(named, iface); typ != nil {
+				_, pointer := typ.(*types.Pointer)
+				ls.addEdge(node, ls.types[tn], models.EdgeImplements, map[string]interface{}{"pointer_receiver": pointer})
+			}
+		}
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				if field := st.Field(i); field.Embedded() {
+					ls.addEmbed(node, field.Type())
+				}
+			}
+		}
+	})
+
+	for _, tn := range ifaces {
+		iface := tn.Type().Underlying().(*types.Interface)
+		for i := 0; i < iface.NumEmbeddeds(); i++ {
+			ls.addEmbed(ls.types[tn], iface.EmbeddedType(i))
+		}
+	}
+}
+
+func (ls *linkState) addEmbed(node *models.CodeNode, typ types.Type) {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return
+	}
+	if target, ok := ls.types[named.Origin().Obj()]; ok {
+		ls.addEdge(node, target, models.EdgeEmbeds, nil)
+	}
+}
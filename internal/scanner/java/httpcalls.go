@@ -0,0 +1,689 @@
+package java
+
+import (
+	"path"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// Methods of RestTemplate that send a request, with their HTTP method;
+// exchange and execute take it as their second argument.
+var restTemplateMethods = map[string]string{
+	"getForObject": "GET", "getForEntity": "GET", "headForHeaders": "HEAD",
+	"postForObject": "POST", "postForEntity": "POST", "postForLocation": "POST",
+	"put": "PUT", "patchForObject": "PATCH", "delete": "DELETE",
+	"optionsForAllow": "OPTIONS", "exchange": "", "execute": "",
+}
+
+// Methods starting a request in the fluent WebClient and RestClient APIs.
+var fluentVerbs = map[string]string{
+	"get": "GET", "post": "POST", "put": "PUT", "patch": "PATCH",
+	"delete": "DELETE", "head": "HEAD", "options": "OPTIONS",
+}
+
+// Methods of HttpRequest.Builder setting the HTTP method, besides method.
+var builderVerbs = map[string]string{
+	"GET": "GET", "POST": "POST", "PUT": "PUT", "DELETE": "DELETE", "HEAD": "HEAD",
+}
+
+// fluentClients are the clients whose requests are built by a chain of calls.
+var fluentClients = map[string]bool{"WebClient": true, "RestClient": true}
+
+// Annotations marking the methods of a test class.
+var testAnnotations = map[string]bool{
+	"Test": true, "ParameterizedTest": true, "RepeatedTest": true, "TestFactory": true,
+}
+
+// callSite is an outbound HTTP request sent or declared by the code.
+type callSite struct {
+	name     string // e.g. RestTemplate.getForObject or WebClient.get
+	line     int
+	method   string
+	url      string
+	caller   string // qualified name of the enclosing method, or type
+	scope    string // caller, with the parameter types of a method
+	receiver string // the client the request is sent with, if named
+}
+
+// chainCall is one call of a chain such as webClient.get().uri("/x").
+type chainCall struct {
+	name string
+	args [][]token
+}
+
+// callFinder reads the outbound requests of a file. Client variables are
+// recognised by their declared type, whatever their scope: a file rarely
+// gives one name to variables of different clients.
+type callFinder struct {
+	f     *file
+	rf    *routeFinder
+	types map[string]string  // variable name -> simple name of its client type
+	bases map[string][]token // fluent client variable -> base URL expression
+	// fields are the fields of the file by name, whose initializers and
+	// @Value annotations give URLs.
+	fields map[string]*fieldDecl
+	tests  []scopeRange // test classes, whose requests are left out
+	sites  []callSite
+}
+
+// httpCalls adds an HTTP_CALL node for every outbound request of the file:
+// RestTemplate calls, WebClient and RestClient chains, HttpRequest builders
+// and the methods of @FeignClient interfaces. Each records the HTTP method
+// and URL template, as far as constants tell them, and its enclosing method.
+// Test sources are left out, and so are the requests of test classes.
+func (f *file) httpCalls() {
+	if isTestFile(f.path) {
+		return
+	}
+	cf := &callFinder{
+		f:      f,
+		rf:     newRouteFinder(f.unit),
+		types:  make(map[string]string),
+		bases:  make(map[string][]token),
+		fields: make(map[string]*fieldDecl),
+	}
+	var walk func(decls []*typeDecl)
+	walk = func(decls []*typeDecl) {
+		for _, decl := range decls {
+			for _, field := range decl.fields {
+				cf.fields[field.name] = field
+			}
+			for _, m := range decl.methods {
+				if hasAnyAnnotation(m.mods, testAnnotations) {
+					cf.tests = append(cf.tests, scopeRange{start: decl.start, end: decl.end})
+					break
+				}
+			}
+			cf.feignClient(decl)
+			walk(decl.types)
+		}
+	}
+	walk(f.unit.types)
+	cf.declarations()
+	for i := range f.toks {
+		cf.request(i)
+	}
+
+	for _, site := range cf.sites {
+		meta := map[string]interface{}{"caller": site.caller}
+		if site.method != "" {
+			meta["method"] = site.method
+		}
+		if site.url != "" {
+			meta["url"] = site.url
+		}
+		if site.receiver != "" {
+			meta["receiver"] = site.receiver
+		}
+		f.nodes = append(f.nodes, &models.CodeNode{
+			// Call sites are identified by the method they appear in
+			ID:         f.ids.ID(site.scope+">"+site.name, models.NodeHTTPCall),
+			Type:       models.NodeHTTPCall,
+			Name:       site.name,
+			Language:   "java",
+			FilePath:   f.path,
+			LineNumber: site.line,
+			Metadata:   meta,
+		})
+	}
+}
+
+// isTestFile reports whether path is a test source, by the Maven and Gradle
+// layout or the naming conventions of JUnit.
+func isTestFile(p string) bool {
+	p = strings.ReplaceAll(p, "\\", "/")
+	if strings.Contains(p, "/src/test/") || strings.Contains(p, "/src/it/") {
+		return true
+	}
+	name := strings.TrimSuffix(path.Base(p), ".java")
+	return strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests") || strings.HasSuffix(name, "IT")
+}
+
+func hasAnyAnnotation(mods modifiers, names map[string]bool) bool {
+	for _, a := range mods.annotations {
+		if names[a.name[strings.LastIndexByte(a.name, '.')+1:]] {
+			return true
+		}
+	}
+	return false
+}
+
+// declarations finds the variables of the file holding clients, and the
+// base URL of the fluent clients built with one.
+func (cf *callFinder) declarations() {
+	toks := cf.f.toks
+	for i := 0; i+2 < len(toks); i++ {
+		t := toks[i]
+		if t.kind != tokIdent || toks[i+1].kind != tokIdent {
+			continue
+		}
+		if (t.is("RestTemplate") || fluentClients[t.text]) && isDeclarator(toks[i+2]) {
+			cf.types[toks[i+1].text] = t.text
+		}
+	}
+	for i := 1; i+1 < len(toks); i++ {
+		// name = value, in a declaration or not
+		name := toks[i-1]
+		if name.kind != tokIdent || !toks[i].is("=") || toks[i+1].is("=") {
+			continue
+		}
+		value := initializer(toks, i+1)
+		if len(value) >= 2 && value[1].is(".") && fluentClients[value[0].text] {
+			cf.types[name.text] = value[0].text
+		}
+		if len(value) >= 2 && value[0].is("new") && value[1].is("RestTemplate") {
+			cf.types[name.text] = "RestTemplate"
+		}
+		if !fluentClients[cf.types[name.text]] {
+			continue
+		}
+		for j := 0; j+2 < len(value); j++ {
+			if (value[j].is("baseUrl") || value[j].is("create")) && value[j+1].is("(") {
+				if args, _, ok := callArgs(value, j+1); ok && len(args) > 0 {
+					cf.bases[name.text] = args[0]
+				}
+			}
+		}
+	}
+}
+
+// isDeclarator reports whether t may follow the name of a declared variable.
+func isDeclarator(t token) bool {
+	return t.is("=") || t.is(";") || t.is(",") || t.is(")")
+}
+
+// initializer returns the expression starting at token i, up to the comma
+// or semicolon ending it.
+func initializer(toks []token, i int) []token {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch t := toks[j]; {
+		case t.kind == tokEOF:
+			return toks[i:j]
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			if depth == 0 {
+				return toks[i:j]
+			}
+			depth--
+		case depth == 0 && (t.is(";") || t.is(",")):
+			return toks[i:j]
+		}
+	}
+	return toks[i:]
+}
+
+// callArgs splits the arguments of the call whose "(" is toks[open],
+// returning the index after the closing parenthesis.
+func callArgs(toks []token, open int) ([][]token, int, bool) {
+	var args [][]token
+	depth, start := 0, open+1
+	for j := open; j < len(toks); j++ {
+		switch t := toks[j]; {
+		case t.kind == tokEOF:
+			return nil, j, false
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			depth--
+			if depth == 0 {
+				if j > start {
+					args = append(args, toks[start:j])
+				}
+				return args, j + 1, true
+			}
+		case t.is(",") && depth == 1:
+			args = append(args, toks[start:j])
+			start = j + 1
+		}
+	}
+	return nil, len(toks), false
+}
+
+// chain reads the calls chained from toks[i], a ".".
+func chain(toks []token, i int) []chainCall {
+	var calls []chainCall
+	for i+2 < len(toks) && toks[i].is(".") && toks[i+1].kind == tokIdent && toks[i+2].is("(") {
+		args, next, ok := callArgs(toks, i+2)
+		if !ok {
+			break
+		}
+		calls = append(calls, chainCall{name: toks[i+1].text, args: args})
+		i = next
+	}
+	return calls
+}
+
+// request records the request sent by the expression starting at token i,
+// if any.
+func (cf *callFinder) request(i int) {
+	toks := cf.f.toks
+	t := toks[i]
+	if t.kind != tokIdent || i+2 >= len(toks) || !toks[i+1].is(".") {
+		return
+	}
+	if i > 0 && toks[i-1].is(".") && !(i > 1 && toks[i-2].is("this")) {
+		// A member of something else, unless a field of this object
+		return
+	}
+	scope, ok := cf.f.scopeAt(i)
+	if !ok || cf.inTest(i) {
+		return
+	}
+	site := callSite{line: t.line, caller: scope.name, scope: scope.name}
+	if scope.method != nil {
+		site.scope = cf.f.qualified(scope.method.identity())
+	}
+	calls := chain(toks, i+1)
+	if len(calls) == 0 {
+		return
+	}
+	switch typ := cf.types[t.text]; {
+	case typ == "RestTemplate":
+		if !cf.restTemplate(&site, calls[0], scope, i) {
+			return
+		}
+		site.receiver = receiverText(toks, i)
+	case fluentClients[typ]:
+		if !cf.fluent(&site, typ, calls, cf.bases[t.text], receiverText(toks, i), scope, i) {
+			return
+		}
+		site.receiver = receiverText(toks, i)
+	case fluentClients[t.text] && (calls[0].name == "create" || calls[0].name == "builder"):
+		if !cf.fluent(&site, t.text, calls, nil, "", scope, i) {
+			return
+		}
+	case t.is("HttpRequest") && calls[0].name == "newBuilder":
+		cf.httpRequest(&site, calls, scope, i)
+	default:
+		return
+	}
+	cf.sites = append(cf.sites, site)
+}
+
+// receiverText renders the variable at toks[i], with its this qualifier.
+func receiverText(toks []token, i int) string {
+	if i > 1 && toks[i-1].is(".") && toks[i-2].is("this") {
+		return "this." + toks[i].text
+	}
+	return toks[i].text
+}
+
+func (cf *callFinder) inTest(i int) bool {
+	for _, s := range cf.tests {
+		if i >= s.start && i < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+// restTemplate describes a call of a RestTemplate method:
+// getForObject(url, ...), or exchange(url, method, ...).
+func (cf *callFinder) restTemplate(site *callSite, c chainCall, scope scopeRange, at int) bool {
+	method, ok := restTemplateMethods[c.name]
+	if !ok {
+		return false
+	}
+	site.name = "RestTemplate." + c.name
+	site.method = method
+	switch {
+	case method != "" && len(c.args) > 0:
+		site.url = cf.fold(c.args[0], scope, at, 0)
+	case len(c.args) >= 3:
+		site.method = cf.httpMethod(c.args[1], scope, at)
+		site.url = cf.fold(c.args[0], scope, at, 0)
+	}
+	// exchange(requestEntity, type) carries its method and URL in the entity
+	return true
+}
+
+// fluent describes a request chain of a WebClient or RestClient, such as
+// webClient.get().uri("/orders/{id}", id) or
+// WebClient.create(base).post().uri(...). base is the base URL the client
+// was built with, if known; receiver names it otherwise.
+func (cf *callFinder) fluent(site *callSite, client string, calls []chainCall, base []token, receiver string, scope scopeRange, at int) bool {
+	var uri string
+	found := false
+	for _, c := range calls {
+		switch {
+		case c.name == "create" || c.name == "baseUrl":
+			if len(c.args) > 0 {
+				base = c.args[0]
+			}
+		case fluentVerbs[c.name] != "" && len(c.args) == 0:
+			site.name, site.method, found = client+"."+c.name, fluentVerbs[c.name], true
+		case c.name == "method" && len(c.args) == 1:
+			site.name, site.method, found = client+".method", cf.httpMethod(c.args[0], scope, at), true
+		case c.name == "uri" && found && len(c.args) > 0:
+			uri = cf.uri(c.args[0], scope, at)
+		}
+	}
+	if !found {
+		return false
+	}
+	switch {
+	case uri == "" || strings.Contains(uri, "://"):
+		site.url = uri
+	case base != nil:
+		site.url = strings.TrimSuffix(cf.fold(base, scope, at, 0), "/") + uri
+	case receiver != "" && strings.HasPrefix(uri, "/"):
+		// Built elsewhere, with a base URL named after the client
+		site.url = "{" + receiver + "}" + uri
+	default:
+		site.url = uri
+	}
+	return true
+}
+
+// uri folds the argument of a uri call: a URL, or a function of a
+// UriBuilder, of which the path is kept.
+func (cf *callFinder) uri(arg []token, scope scopeRange, at int) string {
+	lambda := false
+	for i, t := range arg {
+		if t.is("-") && i+1 < len(arg) && arg[i+1].is(">") {
+			lambda = true
+		}
+	}
+	if !lambda {
+		return cf.fold(arg, scope, at, 0)
+	}
+	for i := 0; i+2 < len(arg); i++ {
+		if arg[i].is(".") && arg[i+1].is("path") && arg[i+2].is("(") {
+			if args, _, ok := callArgs(arg, i+2); ok && len(args) > 0 {
+				return cf.fold(args[0], scope, at, 0)
+			}
+		}
+	}
+	return ""
+}
+
+// httpRequest describes a request of java.net.http:
+// HttpRequest.newBuilder(URI.create(url)).POST(body).build().
+func (cf *callFinder) httpRequest(site *callSite, calls []chainCall, scope scopeRange, at int) {
+	site.name, site.method = "HttpRequest.newBuilder", "GET"
+	for _, c := range calls {
+		switch {
+		case (c.name == "newBuilder" || c.name == "uri") && len(c.args) == 1:
+			site.url = cf.fold(c.args[0], scope, at, 0)
+		case builderVerbs[c.name] != "":
+			site.method = builderVerbs[c.name]
+		case c.name == "method" && len(c.args) > 0:
+			site.method = cf.httpMethod(c.args[0], scope, at)
+		}
+	}
+}
+
+// httpMethod folds a method argument: HttpMethod.GET, "GET" or a template.
+func (cf *callFinder) httpMethod(arg []token, scope scopeRange, at int) string {
+	if n := len(arg); n > 0 && arg[n-1].kind == tokIdent && jaxrsMethods[arg[n-1].text] {
+		return arg[n-1].text
+	}
+	m := cf.fold(arg, scope, at, 0)
+	if !strings.Contains(m, "{") {
+		return strings.ToUpper(m)
+	}
+	return m
+}
+
+// fold renders a string expression as a template, like the Go scanner does:
+// literal text as is, the rest in braces, as in "{baseUrl}/orders/{id}".
+// Constants, fields initialized with a URL or injected with @Value, and the
+// local variables assigned before token at in the enclosing method are
+// replaced by their values.
+func (cf *callFinder) fold(expr []token, scope scopeRange, at, depth int) string {
+	var b strings.Builder
+	for _, operand := range splitOperands(expr) {
+		b.WriteString(cf.foldOperand(operand, scope, at, depth))
+	}
+	return b.String()
+}
+
+// splitOperands splits a concatenation into its operands.
+func splitOperands(expr []token) [][]token {
+	var operands [][]token
+	depth, start := 0, 0
+	for i, t := range expr {
+		switch {
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			depth--
+		case t.is("+") && depth == 0 && i > start:
+			operands = append(operands, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(operands, expr[start:])
+}
+
+func (cf *callFinder) foldOperand(expr []token, scope scopeRange, at, depth int) string {
+	unknown := "{" + joinTokens(expr) + "}"
+	n := len(expr)
+	if n == 0 || depth > 8 {
+		return unknown
+	}
+	switch {
+	case n == 1 && expr[0].kind == tokString:
+		s, ok := cf.rf.stringValue(expr, 0)
+		if !ok {
+			return unknown
+		}
+		return s
+	case n == 1 && expr[0].kind == tokNumber:
+		return expr[0].text
+	case expr[0].is("(") && expr[n-1].is(")"):
+		return cf.fold(expr[1:n-1], scope, at, depth+1)
+	case n > 3 && isCall(expr, "URI", "create"):
+		// URI.create(url), the same for the request
+		if args, next, ok := callArgs(expr, 3); ok && next == n && len(args) == 1 {
+			return cf.fold(args[0], scope, at, depth+1)
+		}
+	case n > 2 && expr[0].is("new") && expr[1].is("URI") && expr[2].is("("):
+		if args, next, ok := callArgs(expr, 2); ok && next == n && len(args) == 1 {
+			return cf.fold(args[0], scope, at, depth+1)
+		}
+	case n > 3 && isCall(expr, "String", "format"):
+		if args, next, ok := callArgs(expr, 3); ok && next == n && len(args) > 0 {
+			return cf.format(args, scope, at, depth)
+		}
+	}
+
+	// A variable, possibly qualified: Constants.BASE or this.baseUrl
+	name := expr[n-1]
+	for i, t := range expr {
+		if i%2 == 0 && t.kind != tokIdent || i%2 == 1 && !t.is(".") {
+			return unknown
+		}
+	}
+	if s, ok := cf.rf.stringValue(expr, 0); ok {
+		return s
+	}
+	if n == 1 && scope.method != nil {
+		if value := localValue(cf.f.toks, scope.method, name.text, at); value != nil {
+			return cf.fold(value, scope, at, depth+1)
+		}
+	}
+	if field, ok := cf.fields[name.text]; ok && (n == 1 || expr[0].is("this")) {
+		if a := annotationNamed(field.mods, "Value"); a != nil && len(a.args) == 1 {
+			if s, ok := cf.rf.stringValue(a.args[0].value, 0); ok {
+				return placeholders(s)
+			}
+		}
+		if field.init != nil {
+			return cf.fold(field.init, scope, at, depth+1)
+		}
+	}
+	return unknown
+}
+
+// isCall reports whether expr starts with the call x.name(.
+func isCall(expr []token, x, name string) bool {
+	return expr[0].is(x) && expr[1].is(".") && expr[2].is(name) && expr[3].is("(")
+}
+
+// format folds String.format(format, args...), substituting each argument.
+func (cf *callFinder) format(args [][]token, scope scopeRange, at, depth int) string {
+	format, ok := cf.rf.stringValue(args[0], 0)
+	if !ok {
+		return "{" + joinTokens(args[0]) + "}"
+	}
+	var b strings.Builder
+	args = args[1:]
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 || i == len(format)-1 {
+			b.WriteString(format)
+			return b.String()
+		}
+		b.WriteString(format[:i])
+		format = format[i+1:]
+		if format[0] == '%' {
+			b.WriteByte('%')
+			format = format[1:]
+			continue
+		}
+		// Skip flags, width and precision up to the conversion.
+		j := strings.IndexFunc(format, func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') })
+		if j < 0 {
+			b.WriteString("%" + format)
+			return b.String()
+		}
+		if len(args) == 0 {
+			b.WriteString("{}")
+		} else {
+			b.WriteString(cf.foldOperand(args[0], scope, at, depth+1))
+			args = args[1:]
+		}
+		format = format[j+1:]
+	}
+}
+
+// localValue returns the expression last assigned to the variable name in
+// the body of m before token at, or nil.
+func localValue(toks []token, m *methodDecl, name string, at int) []token {
+	for i := at - 1; i > m.start; i-- {
+		if toks[i].is(name) && toks[i].kind == tokIdent && toks[i+1].is("=") && !toks[i+2].is("=") && !toks[i-1].is(".") {
+			return initializer(toks, i+2)
+		}
+	}
+	return nil
+}
+
+// placeholders replaces the property placeholders of a Spring value,
+// ${orders.url:http://localhost:8081}, by their default, or by the name of
+// the property in braces when they have none.
+func placeholders(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		key, def, found := strings.Cut(s[i+2:i+end], ":")
+		if found {
+			b.WriteString(def)
+		} else {
+			b.WriteString("{" + key + "}")
+		}
+		s = s[i+end+1:]
+	}
+}
+
+// feignClient records a request for every mapped method of decl, if it is a
+// @FeignClient interface. The base URL is its url, or else the name of the
+// service, which Feign resolves through discovery.
+func (cf *callFinder) feignClient(decl *typeDecl) {
+	a := annotationNamed(decl.mods, "FeignClient")
+	if a == nil {
+		return
+	}
+	attr := func(names ...string) string {
+		for _, name := range names {
+			for _, arg := range a.args {
+				if arg.name == name {
+					if s, ok := cf.rf.stringValue(arg.value, 0); ok {
+						return placeholders(s)
+					}
+					return "{" + joinTokens(arg.value) + "}"
+				}
+			}
+		}
+		return ""
+	}
+	base := attr("url")
+	if base == "" {
+		if name := attr("name", "value", "serviceId"); name != "" {
+			base = "http://" + name
+		}
+	}
+	base = strings.TrimSuffix(base, "/")
+	prefix := attr("path")
+	if m := annotationNamed(decl.mods, "RequestMapping"); m != nil {
+		if paths, ok := cf.rf.paths(m, "value", "path"); ok && len(paths) > 0 {
+			prefix = joinPath(prefix, paths[0])
+		}
+	}
+
+	for _, m := range decl.methods {
+		for _, ma := range m.mods.annotations {
+			method, p, ok := cf.feignMapping(ma)
+			if !ok {
+				continue
+			}
+			cf.sites = append(cf.sites, callSite{
+				name:     "FeignClient",
+				line:     ma.line,
+				method:   method,
+				url:      base + joinPath(prefix, p),
+				caller:   cf.f.qualified(m.scope()),
+				scope:    cf.f.qualified(m.identity()),
+				receiver: decl.name,
+			})
+			break
+		}
+	}
+}
+
+// feignMapping reads the method and path of a Feign client method from its
+// Spring mapping annotation, or from the request line of Feign's own
+// @RequestLine("GET /repos/{owner}").
+func (cf *callFinder) feignMapping(a *annotation) (method, p string, ok bool) {
+	simple := a.name[strings.LastIndexByte(a.name, '.')+1:]
+	if simple == "RequestLine" && len(a.args) == 1 {
+		line, ok := cf.rf.stringValue(a.args[0].value, 0)
+		if !ok {
+			return "", "", false
+		}
+		method, p, _ = strings.Cut(strings.TrimSpace(line), " ")
+		p, _, _ = strings.Cut(strings.TrimSpace(p), " ") // HTTP version
+		return strings.ToUpper(method), p, true
+	}
+	verb, mapped := springMappings[simple]
+	if !mapped {
+		return "", "", false
+	}
+	paths, ok := cf.rf.paths(a, "value", "path")
+	if !ok {
+		return "", "", false
+	}
+	if len(paths) > 0 {
+		p = paths[0]
+	}
+	if verb == methodAny {
+		verb = ""
+		if methods := requestMethods(a); len(methods) > 0 {
+			verb = methods[0]
+		}
+	}
+	return verb, p, true
+}
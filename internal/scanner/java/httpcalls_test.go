@@ -0,0 +1,191 @@
+package java
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func TestHTTPCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		path string // Client.java if empty
+		src  string
+		want []string // "caller: name method url [receiver]", in source order
+	}{
+		{
+			name: "RestTemplate",
+			src: `package p;
+
+import org.springframework.web.client.RestTemplate;
+import org.springframework.http.HttpMethod;
+
+class Client {
+    private static final String USERS = "http://users:8080/users";
+    private final RestTemplate rest = new RestTemplate();
+    @Value("${orders.url:http://orders}")
+    private String ordersUrl;
+
+    User get(long id) {
+        return rest.getForObject(USERS + "/" + id, User.class);
+    }
+
+    void sync() {
+        String url = ordersUrl + "/sync";
+        this.rest.exchange(url, HttpMethod.POST, null, Void.class);
+        rest.delete(String.format("%s/%d", USERS, 7));
+    }
+
+    void unknown(RestTemplate other) {
+        other.put(base() + "/x", null);
+        rest.setErrorHandler(null);
+    }
+}
+`,
+			want: []string{
+				"p.Client.get: RestTemplate.getForObject GET http://users:8080/users/{id} [rest]",
+				"p.Client.sync: RestTemplate.exchange POST http://orders/sync [this.rest]",
+				"p.Client.sync: RestTemplate.delete DELETE http://users:8080/users/7 [rest]",
+				"p.Client.unknown: RestTemplate.put PUT {base()}/x [other]",
+			},
+		},
+		{
+			name: "WebClient and RestClient",
+			src: `package p;
+
+import org.springframework.web.reactive.function.client.WebClient;
+import org.springframework.web.client.RestClient;
+
+class Client {
+    private final WebClient orders = WebClient.builder().baseUrl("http://orders").build();
+    private RestClient inventory;
+
+    Mono<Order> order(String id) {
+        return orders.get().uri("/orders/{id}", id).retrieve().bodyToMono(Order.class);
+    }
+
+    void reserve() {
+        inventory.post().uri(b -> b.path("/reservations").build()).retrieve();
+        WebClient.create("http://audit/").method(HttpMethod.PUT).uri("/events").retrieve();
+        orders.retrieve();
+    }
+}
+`,
+			want: []string{
+				"p.Client.order: WebClient.get GET http://orders/orders/{id} [orders]",
+				"p.Client.reserve: RestClient.post POST {inventory}/reservations [inventory]",
+				"p.Client.reserve: WebClient.method PUT http://audit/events",
+			},
+		},
+		{
+			name: "java.net.http",
+			src: `import java.net.URI;
+import java.net.http.HttpRequest;
+
+class Client {
+    static final String BASE = "https://api.example.com";
+
+    void send() {
+        HttpRequest get = HttpRequest.newBuilder(URI.create(BASE + "/items")).build();
+        HttpRequest post = HttpRequest.newBuilder()
+                .uri(new URI(BASE + "/items"))
+                .POST(HttpRequest.BodyPublishers.noBody())
+                .build();
+        HttpRequest patch = HttpRequest.newBuilder().uri(URI.create(BASE)).method("patch", null).build();
+    }
+}
+`,
+			want: []string{
+				"Client.send: HttpRequest.newBuilder GET https://api.example.com/items",
+				"Client.send: HttpRequest.newBuilder POST https://api.example.com/items",
+				"Client.send: HttpRequest.newBuilder PATCH https://api.example.com",
+			},
+		},
+		{
+			name: "Feign clients",
+			src: `package p;
+
+import org.springframework.cloud.openfeign.FeignClient;
+import org.springframework.web.bind.annotation.*;
+import feign.RequestLine;
+
+@FeignClient(name = "billing", path = "/api")
+interface BillingClient {
+    @GetMapping("/invoices/{id}")
+    Invoice invoice(@PathVariable String id);
+
+    @RequestMapping(value = "/invoices", method = RequestMethod.POST)
+    Invoice create(Invoice i);
+
+    default void helper() {}
+}
+
+@FeignClient(value = "github", url = "${github.url}")
+interface GitHub {
+    @RequestLine("GET /repos/{owner}/{repo} HTTP/1.1")
+    Repo repo(String owner, String repo);
+}
+`,
+			want: []string{
+				"p.BillingClient.invoice: FeignClient GET http://billing/api/invoices/{id} [BillingClient]",
+				"p.BillingClient.create: FeignClient POST http://billing/api/invoices [BillingClient]",
+				"p.GitHub.repo: FeignClient GET {github.url}/repos/{owner}/{repo} [GitHub]",
+			},
+		},
+		{
+			name: "test class",
+			src: `import org.junit.jupiter.api.Test;
+
+class Checks {
+    RestTemplate rest;
+
+    @Test
+    void reachable() {
+        rest.getForObject("http://localhost/health", String.class);
+    }
+}
+`,
+		},
+		{
+			name: "test source",
+			path: "/repo/src/test/java/p/Client.java",
+			src: `class Client {
+    RestTemplate rest;
+
+    void call() {
+        rest.getForObject("http://localhost/health", String.class);
+    }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "Client.java"
+			}
+			var got []string
+			for _, n := range scan(t, path, tt.src) {
+				if n.Type != models.NodeHTTPCall {
+					continue
+				}
+				call := fmt.Sprintf("%s: %s %s %s", n.Metadata["caller"], n.Name, str(n.Metadata["method"]), str(n.Metadata["url"]))
+				if r, ok := n.Metadata["receiver"]; ok {
+					call += fmt.Sprintf(" [%s]", r)
+				}
+				got = append(got, call)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
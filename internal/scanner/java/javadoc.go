@@ -0,0 +1,150 @@
+package java
+
+import (
+	"regexp"
+	"strings"
+)
+
+// javadoc is the documentation comment of a declaration.
+type javadoc struct {
+	summary    string            // first sentence of the description
+	params     map[string]string // @param descriptions, type parameters as <T>
+	returns    string
+	throws     map[string]string // @throws and @exception descriptions by type
+	deprecated bool
+	// deprecation is the text of the @deprecated tag, which tells what to use
+	// instead.
+	deprecation string
+}
+
+var (
+	reInlineTag = regexp.MustCompile(`\{@(\w+)\s*([^{}]*)\}`)
+	reHTMLTag   = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	// A sentence ends at a period followed by white space, as javadoc has it.
+	reSentenceEnd = regexp.MustCompile(`\.(\s|$)`)
+)
+
+// isJavadoc reports whether c is a documentation comment, /** ... */.
+func isJavadoc(c comment) bool {
+	return strings.HasPrefix(c.text, "/**") && c.text != "/**/"
+}
+
+// commentLines returns the lines of c without comment markers, nor the
+// leading asterisks of block comments.
+func commentLines(c comment) []string {
+	if strings.HasPrefix(c.text, "//") {
+		return []string{strings.TrimSpace(strings.TrimPrefix(c.text, "//"))}
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(c.text, "/*"), "*/")
+	text = strings.TrimPrefix(text, "*")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+		}
+		lines[i] = line
+	}
+	// Drop the blank lines left by the markers
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// parseJavadoc reads the description and block tags of a documentation
+// comment. Inline tags are replaced by their text, {@code x} by x, and HTML
+// markup is dropped.
+func parseJavadoc(c comment) *javadoc {
+	doc := &javadoc{params: make(map[string]string), throws: make(map[string]string)}
+	var description []string
+	var tag *strings.Builder
+	var tags []string
+	for _, line := range commentLines(c) {
+		switch {
+		case strings.HasPrefix(line, "@"):
+			tags = append(tags, "")
+			tag = &strings.Builder{}
+			tag.WriteString(line)
+		case tag != nil:
+			tag.WriteString("\n" + line)
+		default:
+			description = append(description, line)
+		}
+		if tag != nil {
+			tags[len(tags)-1] = tag.String()
+		}
+	}
+	doc.summary = summary(strings.Join(description, "\n"))
+
+	for _, t := range tags {
+		name, text, _ := strings.Cut(t, " ")
+		if i := strings.IndexAny(name, "\n\t"); i >= 0 {
+			name, text = name[:i], name[i+1:]+" "+text
+		}
+		switch name {
+		case "@param":
+			// Type parameters are named <T>, which is not markup
+			param, desc := tagArgument(text)
+			doc.params[param] = plainText(desc)
+		case "@return":
+			doc.returns = plainText(text)
+		case "@throws", "@exception":
+			typ, desc := tagArgument(text)
+			doc.throws[typ] = plainText(desc)
+		case "@deprecated":
+			doc.deprecated, doc.deprecation = true, plainText(text)
+		}
+	}
+	return doc
+}
+
+// tagArgument splits the text of a block tag into its first word, the name
+// of a parameter or exception, and the description following it.
+func tagArgument(text string) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return fields[0], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+}
+
+// summary returns the first sentence of a description, which ends at the
+// first period followed by white space or at the first paragraph break.
+func summary(description string) string {
+	if i := strings.Index(description, "\n\n"); i >= 0 {
+		description = description[:i]
+	}
+	if i := strings.Index(strings.ToLower(description), "<p>"); i >= 0 {
+		description = description[:i]
+	}
+	text := plainText(description)
+	if loc := reSentenceEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]+1]
+	}
+	return text
+}
+
+// plainText renders javadoc text on a single line without markup.
+func plainText(s string) string {
+	s = reInlineTag.ReplaceAllStringFunc(s, func(tag string) string {
+		m := reInlineTag.FindStringSubmatch(tag)
+		switch name, arg := m[1], strings.TrimSpace(m[2]); name {
+		case "link", "linkplain":
+			// {@link Type#member label} shows the label, or else the reference
+			if _, label, ok := strings.Cut(arg, " "); ok {
+				return strings.TrimSpace(label)
+			}
+			return strings.TrimPrefix(arg, "#")
+		case "inheritDoc":
+			return ""
+		default:
+			return arg
+		}
+	})
+	s = reHTMLTag.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
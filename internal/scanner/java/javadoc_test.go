@@ -0,0 +1,113 @@
+package java
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJavadocMetadata(t *testing.T) {
+	src := `package p;
+
+/**
+ * Stores {@link Order orders} in the <b>primary</b> database. Writes are
+ * transactional.
+ *
+ * @param <K> the key type
+ */
+class Store<K> {
+    /**
+     * Finds an order by id.
+     *
+     * @param id  the {@code id} of the order,
+     *            never null
+     * @return the order, or null
+     * @throws java.io.IOException if the database is unreachable
+     * @deprecated use {@link #lookup(K)} instead
+     */
+    @Override
+    Order find(K id) throws java.io.IOException { return null; }
+
+    @Deprecated(since = "2.0", forRemoval = true)
+    // Kept for the old clients.
+    // Remove with them.
+    void legacy() {}
+
+    /* Not javadoc. */
+    void plain() {}
+
+    /** Summary without period */
+
+    void detached() {}
+}
+`
+	tests := []struct {
+		name     string
+		comments []string
+		want     map[string]interface{} // metadata entries; nil values must be absent
+	}{
+		{
+			name:     "p.Store",
+			comments: []string{"Stores {@link Order orders} in the <b>primary</b> database. Writes are\ntransactional.\n\n@param <K> the key type\n"},
+			want: map[string]interface{}{
+				"summary":    "Stores orders in the primary database.",
+				"param_docs": map[string]string{"<K>": "the key type"},
+				"deprecated": false,
+			},
+		},
+		{
+			name: "p.Store.find",
+			want: map[string]interface{}{
+				"summary":     "Finds an order by id.",
+				"param_docs":  map[string]string{"id": "the id of the order, never null"},
+				"return_doc":  "the order, or null",
+				"throws_docs": map[string]string{"java.io.IOException": "if the database is unreachable"},
+				"deprecated":  true,
+				"deprecation": "use lookup(K) instead",
+			},
+		},
+		{
+			name:     "p.Store.legacy",
+			comments: []string{"Kept for the old clients.\nRemove with them.\n"},
+			want: map[string]interface{}{
+				"summary":          nil,
+				"deprecated":       true,
+				"deprecated_since": "2.0",
+				"for_removal":      true,
+			},
+		},
+		{
+			name:     "p.Store.plain",
+			comments: []string{"Not javadoc.\n"},
+			want:     map[string]interface{}{"summary": nil, "deprecated": false},
+		},
+		{
+			name: "p.Store.detached",
+			want: map[string]interface{}{"summary": nil, "deprecated": false},
+		},
+	}
+	byName := make(map[string]map[string]interface{})
+	comments := make(map[string][]string)
+	for _, n := range scan(t, "Store.java", src) {
+		byName[n.Name], comments[n.Name] = n.Metadata, n.Comments
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("no node %s", tt.name)
+			}
+			if tt.comments != nil && !reflect.DeepEqual(comments[tt.name], tt.comments) {
+				t.Errorf("comments = %q, want %q", comments[tt.name], tt.comments)
+			}
+			for key, want := range tt.want {
+				got, ok := meta[key]
+				switch {
+				case want == nil && ok:
+					t.Errorf("%s = %v, want none", key, got)
+				case want != nil && !reflect.DeepEqual(got, want):
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+		})
+	}
+}
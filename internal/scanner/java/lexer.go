@@ -0,0 +1,268 @@
+package java
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF   tokenKind = iota
+	tokIdent           // identifiers and keywords
+	tokNumber
+	tokString // string literals and text blocks, quotes included
+	tokChar
+	tokOp // operators and separators, one character each except "..." and "::"
+)
+
+// token is a lexeme of a Java source file. Comments are not tokens: those
+// between a token and the one before it are attached to it.
+type token struct {
+	kind     tokenKind
+	text     string
+	line     int
+	col      int
+	comments []comment
+}
+
+// comment is a comment as written, markers included.
+type comment struct {
+	text    string
+	line    int
+	endLine int
+}
+
+func (t token) is(text string) bool {
+	return t.kind != tokString && t.kind != tokChar && t.text == text
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lexer splits Java source into tokens. Operators are returned one
+// character at a time, so that ">>" closing two type argument lists needs no
+// special case; declarations are all the parser reads.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+// lex returns the tokens of src, ending with a tokEOF token that carries the
+// trailing comments.
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, col: 1}
+	var tokens []token
+	var comments []comment
+	for {
+		c, err := l.skipSpace()
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c...)
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		t.comments, comments = comments, nil
+		tokens = append(tokens, t)
+		if t.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// syntaxError is a malformed token or declaration.
+type syntaxError struct {
+	line, col int
+	msg       string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.line, e.col, e.msg)
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...interface{}) error {
+	return &syntaxError{line: line, col: col, msg: fmt.Sprintf(format, args...)}
+}
+
+// advance moves past n bytes, none of which is a newline.
+func (l *lexer) advance(n int) {
+	l.pos += n
+	l.col += n
+}
+
+// newline moves past the newline at pos.
+func (l *lexer) newline() {
+	l.pos++
+	l.line++
+	l.col = 1
+}
+
+// skipSpace moves past white space and comments, returning the comments.
+func (l *lexer) skipSpace() ([]comment, error) {
+	var comments []comment
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.newline()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			l.advance(1)
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end < 0 {
+				end = len(l.src) - l.pos
+			}
+			comments = append(comments, comment{text: l.src[l.pos : l.pos+end], line: l.line, endLine: l.line})
+			l.advance(end)
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			line, col := l.line, l.col
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return nil, l.errorf(line, col, "comment not terminated")
+			}
+			text := l.src[l.pos : l.pos+2+end+2]
+			for _, r := range text {
+				if r == '\n' {
+					l.line++
+					l.col = 1
+				} else {
+					l.col++
+				}
+			}
+			l.pos += len(text)
+			comments = append(comments, comment{text: text, line: line, endLine: l.line})
+		default:
+			return comments, nil
+		}
+	}
+	return comments, nil
+}
+
+func (l *lexer) next() (token, error) {
+	t := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		t.kind = tokEOF
+		return t, nil
+	}
+	start := l.pos
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	switch {
+	case r == '_' || r == '$' || unicode.IsLetter(r):
+		for l.pos < len(l.src) {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.advance(size)
+		}
+		t.kind = tokIdent
+	case r >= '0' && r <= '9' || r == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		l.number()
+		t.kind = tokNumber
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		if err := l.textBlock(); err != nil {
+			return t, err
+		}
+		t.kind = tokString
+	case r == '"' || r == '\'':
+		if err := l.quoted(byte(r)); err != nil {
+			return t, err
+		}
+		t.kind = tokString
+		if r == '\'' {
+			t.kind = tokChar
+		}
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		t.kind = tokOp
+	case strings.HasPrefix(l.src[l.pos:], "::"):
+		l.advance(2)
+		t.kind = tokOp
+	default:
+		l.advance(size)
+		t.kind = tokOp
+	}
+	t.text = l.src[start:l.pos]
+	return t, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// number moves past a numeric literal, loosely: digits, letters, '_' and
+// '.', with a sign after an exponent marker.
+func (l *lexer) number() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case isDigit(c) || c == '.' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			l.advance(1)
+			if (c == 'e' || c == 'E' || c == 'p' || c == 'P') && l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+				l.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// quoted moves past a string or character literal delimited by quote.
+func (l *lexer) quoted(quote byte) error {
+	line, col := l.line, l.col
+	l.advance(1)
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.advance(1)
+			if l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+				l.advance(size)
+			}
+		case '\n':
+			return l.errorf(line, col, "literal not terminated")
+		case quote:
+			l.advance(1)
+			return nil
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.advance(size)
+		}
+	}
+	return l.errorf(line, col, "literal not terminated")
+}
+
+// textBlock moves past a """ text block.
+func (l *lexer) textBlock() error {
+	line, col := l.line, l.col
+	l.advance(3)
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\\':
+			l.advance(1)
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.newline()
+			} else if l.pos < len(l.src) {
+				l.advance(1)
+			}
+		case l.src[l.pos] == '\n':
+			l.newline()
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.advance(3)
+			return nil
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.advance(size)
+		}
+	}
+	return l.errorf(line, col, "text block not terminated")
+}
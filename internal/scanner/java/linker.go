@@ -0,0 +1,84 @@
+package java
+
+import (
+	"context"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// JavaLinker links the ROUTE nodes of Java controllers to the methods
+// serving them (HANDLES_ROUTE). A route and its handler are declared in the
+// same file, so a rescan only relinks the Java files that changed.
+type JavaLinker struct{}
+
+func NewJavaLinker() *JavaLinker {
+	return &JavaLinker{}
+}
+
+func (l *JavaLinker) Link(ctx context.Context, tree scanner.Tree, nodes []*models.CodeNode) (*models.Graph, error) {
+	type funcKey struct{ file, name string }
+	funcs := make(map[funcKey][]*models.CodeNode)
+	var routes []*models.CodeNode
+	for _, n := range nodes {
+		if n.Language != "java" || tree.Scope != nil && !tree.Scope[n.FilePath] {
+			continue
+		}
+		switch n.Type {
+		case models.NodeFunction:
+			key := funcKey{n.FilePath, n.Name}
+			funcs[key] = append(funcs[key], n)
+		case models.NodeRoute:
+			routes = append(routes, n)
+		}
+	}
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	graph := &models.Graph{}
+	for _, route := range routes {
+		name, _ := route.Metadata["handler"].(string)
+		// Overloads share a name: the handler is the one whose declaration,
+		// annotations included, holds the mapping annotation.
+		for _, fn := range funcs[funcKey{route.FilePath, name}] {
+			if fn.LineNumber <= route.LineNumber && route.LineNumber <= intValue(fn.Metadata["end_line"]) {
+				edge := models.NewEdge(fn.ID, route.ID, models.EdgeHandlesRoute)
+				edge.Attributes = map[string]interface{}{"framework": route.Metadata["framework"]}
+				graph.Edges = append(graph.Edges, edge)
+				break
+			}
+		}
+	}
+	return graph, nil
+}
+
+// intValue reads a number from metadata, where stored nodes hold float64.
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// Scope returns the changed Java files.
+func (l *JavaLinker) Scope(ctx context.Context, tree scanner.Tree, changed []string) (map[string]bool, error) {
+	scope := make(map[string]bool)
+	for _, file := range changed {
+		if strings.HasSuffix(file, ".java") {
+			scope[file] = true
+		}
+	}
+	return scope, nil
+}
+
+func (l *JavaLinker) EdgeKinds() []models.EdgeKind {
+	return []models.EdgeKind{models.EdgeHandlesRoute}
+}
@@ -0,0 +1,1064 @@
+package java
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// compilationUnit is the declarations of a Java source file.
+type compilationUnit struct {
+	pkg     string
+	imports []string // as written, e.g. java.util.List, java.util.* or static a.B.c
+	types   []*typeDecl
+}
+
+// annotation is an annotation applied to a declaration.
+type annotation struct {
+	name string // as written, e.g. GetMapping or org.x.GetMapping
+	args []annotationArg
+	text string // source form, e.g. @GetMapping("/x")
+	line int
+}
+
+// annotationArg is an element of an annotation; the element of a single
+// value annotation is named value.
+type annotationArg struct {
+	name  string
+	value []token
+}
+
+// modifiers are the keywords and annotations starting a declaration.
+type modifiers struct {
+	keywords    []string
+	annotations []*annotation
+	first       token // first token of the declaration, modifiers included
+}
+
+func (m modifiers) has(keyword string) bool {
+	for _, k := range m.keywords {
+		if k == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// typeDecl is a class, interface, enum, record or annotation type, possibly
+// local to a method or anonymous.
+type typeDecl struct {
+	kind string // class, interface, enum, record or annotation
+	// name is the simple name; anonymous classes are numbered $1, $2...
+	// within their scope, and enum constant bodies take the constant's name.
+	name string
+	// scope is name qualified by the enclosing types and methods, without the
+	// package, e.g. Outer.Inner or Outer.run.Local.
+	scope      string
+	anonymous  bool
+	mods       modifiers
+	typeParams []models.Param
+	extends    []string
+	implements []string
+	components []models.Param // of a record
+	line       int
+	endLine    int
+	start, end int // token range, from the first modifier to the closing brace
+
+	fields  []*fieldDecl
+	methods []*methodDecl
+	types   []*typeDecl // member, local and anonymous classes declared inside
+}
+
+// fieldDecl is a field; each variable of a declaration has its own.
+type fieldDecl struct {
+	name string
+	typ  string
+	mods modifiers
+	line int
+	init []token // initializer, if any
+}
+
+// methodDecl is a method or constructor.
+type methodDecl struct {
+	name        string
+	owner       *typeDecl
+	mods        modifiers
+	typeParams  []models.Param
+	result      string // empty for constructors
+	params      []models.Param
+	variadic    bool
+	throws      []string
+	constructor bool
+	line        int
+	endLine     int
+	start, end  int     // token range, from the first modifier to the end of the body
+	body        []token // inside the braces; nil for abstract methods
+}
+
+// scope is the name of m qualified by its enclosing types.
+func (m *methodDecl) scope() string {
+	return m.owner.scope + "." + m.name
+}
+
+// identity is the scope of m followed by the types of its parameters, as in
+// "Outer.Inner.run(int,List<String>)", which tells overloads apart.
+func (m *methodDecl) identity() string {
+	types := make([]string, len(m.params))
+	for i, p := range m.params {
+		types[i] = strings.Join(strings.Fields(p.Type), "")
+	}
+	return m.scope() + "(" + strings.Join(types, ",") + ")"
+}
+
+// signature renders the declaration of m without annotations or body.
+func (m *methodDecl) signature() string {
+	var b strings.Builder
+	for _, k := range m.mods.keywords {
+		b.WriteString(k + " ")
+	}
+	if len(m.typeParams) > 0 {
+		b.WriteString(typeParamString(m.typeParams) + " ")
+	}
+	if !m.constructor {
+		b.WriteString(m.result + " ")
+	}
+	b.WriteString(m.name + "(")
+	for i, p := range m.params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		typ := p.Type
+		if m.variadic && i == len(m.params)-1 {
+			typ = strings.TrimSuffix(typ, "[]") + "..."
+		}
+		b.WriteString(typ + " " + p.Name)
+	}
+	b.WriteString(")")
+	if len(m.throws) > 0 {
+		b.WriteString(" throws " + strings.Join(m.throws, ", "))
+	}
+	return b.String()
+}
+
+func typeParamString(params []models.Param) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.Name
+		if p.Type != "" {
+			parts[i] += " extends " + p.Type
+		}
+	}
+	return "<" + strings.Join(parts, ", ") + ">"
+}
+
+// modifierKeywords are the keywords that may start a declaration.
+var modifierKeywords = map[string]bool{
+	"public": true, "protected": true, "private": true, "static": true,
+	"abstract": true, "final": true, "native": true, "synchronized": true,
+	"transient": true, "volatile": true, "strictfp": true, "default": true,
+	"sealed": true,
+}
+
+var primitiveTypes = map[string]bool{
+	"boolean": true, "byte": true, "char": true, "short": true, "int": true,
+	"long": true, "float": true, "double": true, "void": true,
+}
+
+// parser reads the declarations of a compilation unit. Method bodies and
+// initializers are skipped over, but for the classes declared in them.
+type parser struct {
+	toks []token
+	pos  int
+	// anonymous counts the anonymous classes of each scope.
+	anonymous map[string]int
+}
+
+// parse reads the declarations of src.
+func parse(src string) (*compilationUnit, []token, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser{toks: toks, anonymous: make(map[string]int)}
+	unit, err := p.compilationUnit()
+	return unit, toks, err
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+// peekAt returns the token n tokens ahead.
+func (p *parser) peekAt(n int) token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// got moves past the next token if it is text.
+func (p *parser) got(text string) bool {
+	if p.peek().is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.peek()
+	if !t.is(text) {
+		return t, p.errorf(t, "expected %q, found %s", text, t)
+	}
+	return p.next(), nil
+}
+
+func (p *parser) ident() (token, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return t, p.errorf(t, "expected identifier, found %s", t)
+	}
+	return p.next(), nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &syntaxError{line: t.line, col: t.col, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) compilationUnit() (*compilationUnit, error) {
+	unit := &compilationUnit{}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			return unit, nil
+		case t.is(";"):
+			p.next()
+		case t.is("package") || t.is("@") && !p.peekAt(1).is("interface") && p.packageAhead():
+			// Annotations of a package are only found in package-info.java
+			if _, err := p.modifiers(); err != nil {
+				return nil, err
+			}
+			p.next()
+			name, err := p.qualifiedName()
+			if err != nil {
+				return nil, err
+			}
+			unit.pkg = name
+			if _, err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case t.is("import"):
+			p.next()
+			static := p.got("static")
+			name, err := p.qualifiedName()
+			if err != nil {
+				return nil, err
+			}
+			if p.got(".") {
+				if _, err := p.expect("*"); err != nil {
+					return nil, err
+				}
+				name += ".*"
+			}
+			if static {
+				name = "static " + name
+			}
+			unit.imports = append(unit.imports, name)
+			if _, err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case (t.is("module") || t.is("open")) && p.moduleAhead():
+			// A module declaration, in module-info.java, declares no types
+			p.pos = len(p.toks) - 1
+		default:
+			mods, err := p.modifiers()
+			if err != nil {
+				return nil, err
+			}
+			decl, err := p.typeDecl(mods, "")
+			if err != nil {
+				return nil, err
+			}
+			unit.types = append(unit.types, decl)
+		}
+	}
+}
+
+// packageAhead reports whether the annotations at pos precede a package
+// declaration.
+func (p *parser) packageAhead() bool {
+	i := p.skipAnnotations(p.pos)
+	return p.toks[i].is("package")
+}
+
+func (p *parser) moduleAhead() bool {
+	i := p.pos
+	if p.toks[i].is("open") {
+		i++
+	}
+	return p.toks[i].is("module") && i+1 < len(p.toks) && p.toks[i+1].kind == tokIdent
+}
+
+// skipAnnotations returns the index of the first token from i on that is
+// not part of an annotation or a modifier keyword.
+func (p *parser) skipAnnotations(i int) int {
+	for i < len(p.toks)-1 {
+		t := p.toks[i]
+		switch {
+		case modifierKeywords[t.text] && t.kind == tokIdent:
+			i++
+		case t.is("non") && p.toks[i+1].is("-"):
+			i += 3
+		case t.is("@") && !p.toks[i+1].is("interface"):
+			i++
+			for i < len(p.toks)-1 && (p.toks[i].kind == tokIdent || p.toks[i].is(".")) {
+				i++
+			}
+			if p.toks[i].is("(") {
+				depth := 0
+				for ; i < len(p.toks)-1; i++ {
+					if p.toks[i].is("(") {
+						depth++
+					} else if p.toks[i].is(")") {
+						depth--
+						if depth == 0 {
+							i++
+							break
+						}
+					}
+				}
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func (p *parser) qualifiedName() (string, error) {
+	t, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	name := t.text
+	for p.peek().is(".") && p.peekAt(1).kind == tokIdent {
+		p.next()
+		name += "." + p.next().text
+	}
+	return name, nil
+}
+
+func (p *parser) modifiers() (modifiers, error) {
+	mods := modifiers{first: p.peek()}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokIdent && modifierKeywords[t.text]:
+			mods.keywords = append(mods.keywords, p.next().text)
+		case t.is("non") && p.peekAt(1).is("-") && p.peekAt(2).is("sealed"):
+			p.pos += 3
+			mods.keywords = append(mods.keywords, "non-sealed")
+		case t.is("@") && !p.peekAt(1).is("interface"):
+			a, err := p.annotation()
+			if err != nil {
+				return mods, err
+			}
+			mods.annotations = append(mods.annotations, a)
+		default:
+			return mods, nil
+		}
+	}
+}
+
+func (p *parser) annotation() (*annotation, error) {
+	start := p.pos
+	at := p.next()
+	name, err := p.qualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	a := &annotation{name: name, line: at.line}
+	if p.got("(") {
+		for !p.peek().is(")") {
+			arg := annotationArg{name: "value"}
+			if p.peek().kind == tokIdent && p.peekAt(1).is("=") {
+				arg.name = p.next().text
+				p.next()
+			}
+			from := p.pos
+			if err := p.skipUntil(",", ")"); err != nil {
+				return nil, err
+			}
+			arg.value = p.toks[from:p.pos]
+			a.args = append(a.args, arg)
+			if !p.got(",") {
+				break
+			}
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	a.text = joinTokens(p.toks[start:p.pos])
+	return a, nil
+}
+
+// skipUntil moves to the next of stops outside brackets, without looking
+// into what it skips.
+func (p *parser) skipUntil(stops ...string) error {
+	depth := 0
+	for {
+		t := p.peek()
+		if depth == 0 {
+			for _, s := range stops {
+				if t.is(s) {
+					return nil
+				}
+			}
+		}
+		switch {
+		case t.kind == tokEOF:
+			return p.errorf(t, "unexpected end of file")
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			if depth == 0 {
+				return p.errorf(t, "unexpected %s", t)
+			}
+			depth--
+		}
+		p.next()
+	}
+}
+
+// typeDecl reads a type declaration whose modifiers were read, nested in
+// outer, the scope of the enclosing declaration, if any.
+func (p *parser) typeDecl(mods modifiers, outer string) (*typeDecl, error) {
+	start := p.pos - countTokens(p, mods)
+	t := p.next()
+	decl := &typeDecl{mods: mods, line: mods.first.line, start: start}
+	switch {
+	case t.is("class"), t.is("interface"), t.is("enum"), t.is("record"):
+		decl.kind = t.text
+	case t.is("@") && p.peek().is("interface"):
+		p.next()
+		decl.kind = "annotation"
+	default:
+		return nil, p.errorf(t, "expected type declaration, found %s", t)
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	decl.name, decl.scope = name.text, scoped(outer, name.text)
+
+	if p.peek().is("<") {
+		if decl.typeParams, err = p.typeParams(); err != nil {
+			return nil, err
+		}
+	}
+	if decl.kind == "record" {
+		if decl.components, _, err = p.params(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		switch {
+		case p.got("extends"):
+			if decl.extends, err = p.typeList(); err != nil {
+				return nil, err
+			}
+			continue
+		case p.got("implements"):
+			if decl.implements, err = p.typeList(); err != nil {
+				return nil, err
+			}
+			continue
+		case p.peek().is("permits"):
+			p.next()
+			if _, err = p.typeList(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		break
+	}
+	if err := p.classBody(decl); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+// countTokens returns how many tokens the modifiers mods span, which were
+// read right before pos.
+func countTokens(p *parser, mods modifiers) int {
+	for i := p.pos; i >= 0; i-- {
+		if p.toks[i].line == mods.first.line && p.toks[i].col == mods.first.col {
+			return p.pos - i
+		}
+	}
+	return 0
+}
+
+func scoped(outer, name string) string {
+	if outer == "" {
+		return name
+	}
+	return outer + "." + name
+}
+
+// classBody reads the members of decl, from its opening brace to the
+// closing one.
+func (p *parser) classBody(decl *typeDecl) error {
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+	if decl.kind == "enum" {
+		if err := p.enumConstants(decl); err != nil {
+			return err
+		}
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.is("}"):
+			decl.endLine, decl.end = t.line, p.pos+1
+			p.next()
+			return nil
+		case t.kind == tokEOF:
+			return p.errorf(t, "expected \"}\", found end of file")
+		case t.is(";"):
+			p.next()
+			continue
+		}
+		if err := p.member(decl); err != nil {
+			return err
+		}
+	}
+}
+
+// enumConstants reads the constants starting the body of an enum, up to
+// the semicolon ending them, if any.
+func (p *parser) enumConstants(decl *typeDecl) error {
+	for {
+		mods, err := p.modifiers()
+		if err != nil {
+			return err
+		}
+		if p.peek().is(";") || p.peek().is("}") {
+			p.got(";")
+			return nil
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if p.peek().is("(") {
+			if err := p.scanBracketed(decl, decl.scope); err != nil {
+				return err
+			}
+		}
+		if p.peek().is("{") {
+			body := &typeDecl{
+				kind: "class", name: name.text, scope: scoped(decl.scope, name.text),
+				anonymous: true, mods: mods, extends: []string{decl.name},
+				line: mods.first.line, start: p.pos - countTokens(p, mods),
+			}
+			if err := p.classBody(body); err != nil {
+				return err
+			}
+			decl.types = append(decl.types, body)
+		}
+		if !p.got(",") {
+			if !p.peek().is("}") {
+				_, err := p.expect(";")
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// member reads a member of decl other than an empty declaration.
+func (p *parser) member(decl *typeDecl) error {
+	start := p.pos
+	if p.peek().is("static") && p.peekAt(1).is("{") {
+		p.next()
+	}
+	if p.peek().is("{") {
+		// Initializer
+		_, err := p.block(decl, decl.scope)
+		return err
+	}
+	mods, err := p.modifiers()
+	if err != nil {
+		return err
+	}
+	if p.typeAhead() {
+		nested, err := p.typeDecl(mods, decl.scope)
+		if err != nil {
+			return err
+		}
+		decl.types = append(decl.types, nested)
+		return nil
+	}
+
+	m := &methodDecl{owner: decl, mods: mods, line: mods.first.line, start: start}
+	if p.peek().is("<") {
+		if m.typeParams, err = p.typeParams(); err != nil {
+			return err
+		}
+	}
+	t := p.peek()
+	switch {
+	case t.text == decl.name && p.peekAt(1).is("("):
+		m.constructor = true
+	case t.text == decl.name && decl.kind == "record" && p.peekAt(1).is("{"):
+		// Compact canonical constructor
+		m.constructor, m.name = true, p.next().text
+		m.params = decl.components
+		return p.methodBody(m)
+	default:
+		if m.result, err = p.typeName(); err != nil {
+			return err
+		}
+	}
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if !p.peek().is("(") {
+		return p.fields(decl, mods, m.result, name)
+	}
+	m.name = name.text
+	if m.params, m.variadic, err = p.params(); err != nil {
+		return err
+	}
+	for p.peek().is("[") && p.peekAt(1).is("]") {
+		// Old style array result, int f()[]
+		p.pos += 2
+		m.result += "[]"
+	}
+	if p.got("throws") {
+		if m.throws, err = p.typeList(); err != nil {
+			return err
+		}
+	}
+	if p.got("default") {
+		// Default value of an annotation element
+		if err := p.skipUntil(";"); err != nil {
+			return err
+		}
+	}
+	return p.methodBody(m)
+}
+
+// methodBody reads the body of m, or the semicolon standing for it, and adds
+// m to its owner.
+func (p *parser) methodBody(m *methodDecl) error {
+	if t := p.peek(); t.is(";") {
+		m.endLine, m.end = t.line, p.pos+1
+		p.next()
+	} else {
+		body, err := p.block(m.owner, m.scope())
+		if err != nil {
+			return err
+		}
+		m.body = body
+		m.endLine, m.end = p.toks[p.pos-1].line, p.pos
+	}
+	m.owner.methods = append(m.owner.methods, m)
+	return nil
+}
+
+// fields reads the variables of a field declaration, the first of which is
+// named by name.
+func (p *parser) fields(decl *typeDecl, mods modifiers, typ string, name token) error {
+	for {
+		f := &fieldDecl{name: name.text, typ: typ, mods: mods, line: name.line}
+		for p.peek().is("[") && p.peekAt(1).is("]") {
+			p.pos += 2
+			f.typ += "[]"
+		}
+		if p.got("=") {
+			from := p.pos
+			if err := p.scan(decl, decl.scope, ",", ";"); err != nil {
+				return err
+			}
+			f.init = p.toks[from:p.pos]
+		}
+		decl.fields = append(decl.fields, f)
+		if !p.got(",") {
+			_, err := p.expect(";")
+			return err
+		}
+		var err error
+		if name, err = p.ident(); err != nil {
+			return err
+		}
+	}
+}
+
+// typeAhead reports whether a type declaration starts at pos, modifiers read.
+func (p *parser) typeAhead() bool {
+	t := p.peek()
+	switch {
+	case t.is("class") || t.is("interface") || t.is("enum"):
+		return true
+	case t.is("@"):
+		return p.peekAt(1).is("interface")
+	case t.is("record"):
+		return p.peekAt(1).kind == tokIdent && (p.peekAt(2).is("(") || p.peekAt(2).is("<"))
+	}
+	return false
+}
+
+func (p *parser) typeParams() ([]models.Param, error) {
+	p.next() // <
+	var params []models.Param
+	for {
+		if _, err := p.modifiers(); err != nil {
+			return nil, err
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		param := models.Param{Name: name.text}
+		if p.got("extends") {
+			var bounds []string
+			for {
+				bound, err := p.typeName()
+				if err != nil {
+					return nil, err
+				}
+				bounds = append(bounds, bound)
+				if !p.got("&") {
+					break
+				}
+			}
+			param.Type = strings.Join(bounds, " & ")
+		}
+		params = append(params, param)
+		if !p.got(",") {
+			break
+		}
+	}
+	if _, err := p.expect(">"); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+func (p *parser) typeList() ([]string, error) {
+	var types []string
+	for {
+		typ, err := p.typeName()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+		if !p.got(",") {
+			return types, nil
+		}
+	}
+}
+
+// typeName reads a type, e.g. Map<String, List<Foo>>[], and renders it.
+// Type annotations are left out.
+func (p *parser) typeName() (string, error) {
+	if err := p.typeAnnotations(); err != nil {
+		return "", err
+	}
+	t, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	name := t.text
+	for {
+		if p.peek().is("<") {
+			args, err := p.typeArgs()
+			if err != nil {
+				return "", err
+			}
+			name += args
+		}
+		if !p.peek().is(".") || !(p.peekAt(1).kind == tokIdent || p.peekAt(1).is("@")) {
+			break
+		}
+		p.next()
+		if err := p.typeAnnotations(); err != nil {
+			return "", err
+		}
+		t, err := p.ident()
+		if err != nil {
+			return "", err
+		}
+		name += "." + t.text
+	}
+	for {
+		if err := p.typeAnnotations(); err != nil {
+			return "", err
+		}
+		if !p.peek().is("[") || !p.peekAt(1).is("]") {
+			return name, nil
+		}
+		p.pos += 2
+		name += "[]"
+	}
+}
+
+func (p *parser) typeAnnotations() error {
+	for p.peek().is("@") && !p.peekAt(1).is("interface") {
+		if _, err := p.annotation(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeArgs reads type arguments, the diamond included, and renders them.
+func (p *parser) typeArgs() (string, error) {
+	p.next() // <
+	if p.got(">") {
+		return "<>", nil
+	}
+	var args []string
+	for {
+		if err := p.typeAnnotations(); err != nil {
+			return "", err
+		}
+		var arg string
+		if p.got("?") {
+			arg = "?"
+			for _, bound := range []string{"extends", "super"} {
+				if p.got(bound) {
+					typ, err := p.typeName()
+					if err != nil {
+						return "", err
+					}
+					arg += " " + bound + " " + typ
+				}
+			}
+		} else {
+			typ, err := p.typeName()
+			if err != nil {
+				return "", err
+			}
+			arg = typ
+		}
+		args = append(args, arg)
+		if !p.got(",") {
+			break
+		}
+	}
+	if _, err := p.expect(">"); err != nil {
+		return "", err
+	}
+	return "<" + strings.Join(args, ", ") + ">", nil
+}
+
+// params reads a parameter list, reporting whether the last parameter is
+// variadic, whose type is then recorded as an array.
+func (p *parser) params() ([]models.Param, bool, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, false, err
+	}
+	params := []models.Param{}
+	variadic := false
+	for !p.peek().is(")") {
+		if _, err := p.modifiers(); err != nil {
+			return nil, false, err
+		}
+		typ, err := p.typeName()
+		if err != nil {
+			return nil, false, err
+		}
+		if p.got("...") {
+			typ += "[]"
+			variadic = true
+		}
+		if p.peek().is("this") {
+			// Receiver parameter, which is not a parameter
+			p.next()
+		} else if t := p.peek(); t.kind == tokIdent && p.peekAt(1).is(".") && p.peekAt(2).is("this") {
+			p.pos += 3
+		} else {
+			name, err := p.ident()
+			if err != nil {
+				return nil, false, err
+			}
+			for p.peek().is("[") && p.peekAt(1).is("]") {
+				p.pos += 2
+				typ += "[]"
+			}
+			params = append(params, models.Param{Name: name.text, Type: typ})
+		}
+		if !p.got(",") {
+			break
+		}
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, false, err
+	}
+	return params, variadic, nil
+}
+
+// block reads a block of code, in decl and the scope of the declaration
+// holding it, returning the tokens inside the braces.
+func (p *parser) block(decl *typeDecl, scope string) ([]token, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	from := p.pos
+	if err := p.scan(decl, scope, "}"); err != nil {
+		return nil, err
+	}
+	body := p.toks[from:p.pos]
+	p.next()
+	return body, nil
+}
+
+// scanBracketed moves past the parenthesised code at pos, like scan.
+func (p *parser) scanBracketed(decl *typeDecl, scope string) error {
+	p.next() // (
+	if err := p.scan(decl, scope, ")"); err != nil {
+		return err
+	}
+	p.next()
+	return nil
+}
+
+// scan moves to the next of stops outside brackets, over code whose
+// statements and expressions are not parsed, except for the classes they
+// declare: local classes and anonymous classes, which are added to decl with
+// names in scope.
+func (p *parser) scan(decl *typeDecl, scope string, stops ...string) error {
+	depth := 0
+	for {
+		t := p.peek()
+		if depth == 0 {
+			for _, s := range stops {
+				if t.is(s) {
+					return nil
+				}
+			}
+		}
+		switch {
+		case t.kind == tokEOF:
+			return p.errorf(t, "unexpected end of file")
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			if depth == 0 {
+				return p.errorf(t, "unexpected %s", t)
+			}
+			depth--
+		case t.is("new"):
+			p.next()
+			if err := p.creation(decl, scope); err != nil {
+				return err
+			}
+			continue
+		case p.statementStart() && p.localTypeAhead():
+			mods, err := p.modifiers()
+			if err != nil {
+				return err
+			}
+			local, err := p.typeDecl(mods, scope)
+			if err != nil {
+				return err
+			}
+			decl.types = append(decl.types, local)
+			continue
+		}
+		p.next()
+	}
+}
+
+// statementStart reports whether a statement may start at pos.
+func (p *parser) statementStart() bool {
+	if p.pos == 0 {
+		return true
+	}
+	prev := p.toks[p.pos-1]
+	return prev.is(";") || prev.is("{") || prev.is("}") || prev.is(":") && !p.peek().is(":")
+}
+
+// localTypeAhead reports whether a class declaration starts at pos, within
+// a block.
+func (p *parser) localTypeAhead() bool {
+	save := p.pos
+	defer func() { p.pos = save }()
+	p.pos = p.skipAnnotations(p.pos)
+	return p.typeAhead()
+}
+
+// creation moves past the instance creation following the "new" at pos-1,
+// reading the body of an anonymous class.
+func (p *parser) creation(decl *typeDecl, scope string) error {
+	start := p.pos - 1
+	if p.peek().kind != tokIdent && !p.peek().is("@") {
+		// e.g. new <T>Foo(), which is rare enough to leave out
+		return nil
+	}
+	typ, err := p.typeName()
+	if err != nil {
+		return err
+	}
+	if !p.peek().is("(") {
+		// Array creation
+		return nil
+	}
+	if err := p.scanBracketed(decl, scope); err != nil {
+		return err
+	}
+	if !p.peek().is("{") {
+		return nil
+	}
+	p.anonymous[scope]++
+	name := "$" + strconv.Itoa(p.anonymous[scope])
+	anon := &typeDecl{
+		kind: "class", name: name, scope: scoped(scope, name), anonymous: true,
+		extends: []string{typ}, line: p.toks[start].line, start: start,
+	}
+	if err := p.classBody(anon); err != nil {
+		return err
+	}
+	decl.types = append(decl.types, anon)
+	return nil
+}
+
+// joinTokens renders tokens as source, with spaces only where needed.
+func joinTokens(toks []token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && needSpace(toks[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func needSpace(prev, t token) bool {
+	word := func(t token) bool { return t.kind == tokIdent || t.kind == tokNumber }
+	switch {
+	case word(prev) && word(t):
+		return true
+	case prev.is(",") || prev.is("=") || t.is("="):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,408 @@
+package java
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Frameworks recognised by the packages their annotations are imported from.
+// Annotations are interpreted according to the frameworks the file imports.
+const (
+	frameworkSpring    = "spring"
+	frameworkJAXRS     = "jax-rs"
+	frameworkMicronaut = "micronaut"
+)
+
+var frameworkImports = []struct {
+	prefix    string
+	framework string
+}{
+	{"org.springframework.web.bind.annotation", frameworkSpring},
+	{"javax.ws.rs", frameworkJAXRS},
+	{"jakarta.ws.rs", frameworkJAXRS},
+	{"io.micronaut.http.annotation", frameworkMicronaut},
+}
+
+// methodAny marks routes that accept every HTTP method.
+const methodAny = "ANY"
+
+// Spring names its mapping annotations after the HTTP method, except for
+// RequestMapping, whose methods are given by its method element.
+var springMappings = map[string]string{
+	"RequestMapping": methodAny, "GetMapping": "GET", "PostMapping": "POST",
+	"PutMapping": "PUT", "DeleteMapping": "DELETE", "PatchMapping": "PATCH",
+}
+
+// JAX-RS marks resource methods with an annotation per HTTP method.
+var jaxrsMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true,
+	"HEAD": true, "OPTIONS": true,
+}
+
+// Micronaut uses Java-style names for the same.
+var micronautMethods = map[string]string{
+	"Get": "GET", "Post": "POST", "Put": "PUT", "Delete": "DELETE", "Patch": "PATCH",
+	"Head": "HEAD", "Options": "OPTIONS", "Trace": "TRACE",
+}
+
+// routeSite is an HTTP route served by a method of a controller.
+type routeSite struct {
+	method    string
+	path      string // full path, including the prefix of the controller
+	handler   *methodDecl
+	line      int // of the annotation mapping the route
+	framework string
+}
+
+func (r routeSite) name() string {
+	return r.method + " " + r.path
+}
+
+// routeFinder reads the routes declared by the annotations of a file.
+type routeFinder struct {
+	frameworks map[string]bool
+	consts     map[string][]token // initializers of the constants of the file, by name
+	sites      []routeSite
+}
+
+// findRoutes returns the routes served by the controllers of unit, in source
+// order: Spring @RestController and @Controller classes, JAX-RS resources
+// and Micronaut controllers. The paths of the mapping annotations of a class
+// prefix those of its methods.
+func findRoutes(unit *compilationUnit) []routeSite {
+	rf := newRouteFinder(unit)
+	if len(rf.frameworks) == 0 {
+		return nil
+	}
+	var walk func(decls []*typeDecl)
+	walk = func(decls []*typeDecl) {
+		for _, decl := range decls {
+			rf.controller(decl)
+			walk(decl.types)
+		}
+	}
+	walk(unit.types)
+	return rf.sites
+}
+
+// newRouteFinder returns a routeFinder knowing the frameworks and constants
+// of unit.
+func newRouteFinder(unit *compilationUnit) *routeFinder {
+	rf := &routeFinder{frameworks: make(map[string]bool), consts: make(map[string][]token)}
+	for _, imp := range unit.imports {
+		for _, fi := range frameworkImports {
+			if imp == fi.prefix || strings.HasPrefix(imp, fi.prefix+".") {
+				rf.frameworks[fi.framework] = true
+			}
+		}
+	}
+	var walk func(decls []*typeDecl)
+	walk = func(decls []*typeDecl) {
+		for _, decl := range decls {
+			for _, f := range decl.fields {
+				if f.mods.has("static") && f.mods.has("final") || decl.kind == "interface" {
+					rf.consts[f.name] = f.init
+				}
+			}
+			walk(decl.types)
+		}
+	}
+	walk(unit.types)
+	return rf
+}
+
+// annotation returns the first of the annotations of mods named name, either
+// simply or qualified by one of the packages of framework.
+func (rf *routeFinder) annotation(mods modifiers, framework, name string) *annotation {
+	for _, a := range mods.annotations {
+		if rf.is(a, framework, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// is reports whether a is the annotation of framework named name.
+func (rf *routeFinder) is(a *annotation, framework, name string) bool {
+	pkg, simple := "", a.name
+	if i := strings.LastIndexByte(a.name, '.'); i >= 0 {
+		pkg, simple = a.name[:i], a.name[i+1:]
+	}
+	if simple != name {
+		return false
+	}
+	if pkg == "" {
+		return rf.frameworks[framework]
+	}
+	for _, fi := range frameworkImports {
+		if fi.framework == framework && (pkg == fi.prefix || strings.HasPrefix(pkg, fi.prefix+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// controller adds the routes of decl, if it is a controller.
+func (rf *routeFinder) controller(decl *typeDecl) {
+	switch {
+	case rf.annotation(decl.mods, frameworkSpring, "RestController") != nil ||
+		rf.annotation(decl.mods, frameworkSpring, "Controller") != nil && !rf.frameworks[frameworkMicronaut]:
+		rf.springRoutes(decl)
+	case rf.annotation(decl.mods, frameworkMicronaut, "Controller") != nil:
+		rf.micronautRoutes(decl)
+	case rf.annotation(decl.mods, frameworkJAXRS, "Path") != nil && annotationNamed(decl.mods, "RegisterRestClient") == nil:
+		// MicroProfile REST clients are declared like resources, but call them
+		rf.jaxrsRoutes(decl)
+	}
+}
+
+// annotationNamed returns the first of the annotations of mods named name,
+// whatever package qualifies it.
+func annotationNamed(mods modifiers, name string) *annotation {
+	for _, a := range mods.annotations {
+		if a.name == name || strings.HasSuffix(a.name, "."+name) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (rf *routeFinder) springRoutes(decl *typeDecl) {
+	prefixes, classMethods := []string{""}, []string{methodAny}
+	if a := rf.annotation(decl.mods, frameworkSpring, "RequestMapping"); a != nil {
+		if paths, ok := rf.paths(a, "value", "path"); ok && len(paths) > 0 {
+			prefixes = paths
+		}
+		if methods := requestMethods(a); len(methods) > 0 {
+			classMethods = methods
+		}
+	}
+	for _, m := range decl.methods {
+		for _, a := range m.mods.annotations {
+			var method string
+			for name, verb := range springMappings {
+				if rf.is(a, frameworkSpring, name) {
+					method = verb
+				}
+			}
+			if method == "" {
+				continue
+			}
+			paths, ok := rf.paths(a, "value", "path")
+			if !ok {
+				continue
+			}
+			methods := []string{method}
+			if method == methodAny {
+				if methods = requestMethods(a); len(methods) == 0 {
+					methods = classMethods
+				}
+			}
+			rf.add(frameworkSpring, m, a, prefixes, paths, methods)
+		}
+	}
+}
+
+func (rf *routeFinder) jaxrsRoutes(decl *typeDecl) {
+	prefixes := []string{""}
+	if paths, ok := rf.paths(rf.annotation(decl.mods, frameworkJAXRS, "Path"), "value"); ok && len(paths) > 0 {
+		prefixes = paths
+	}
+	for _, m := range decl.methods {
+		var methods []string
+		var line int
+		for _, a := range m.mods.annotations {
+			for verb := range jaxrsMethods {
+				if rf.is(a, frameworkJAXRS, verb) {
+					methods, line = append(methods, verb), a.line
+				}
+			}
+		}
+		if len(methods) == 0 {
+			// Sub-resource locators serve no route of their own
+			continue
+		}
+		paths := []string{""}
+		a := rf.annotation(m.mods, frameworkJAXRS, "Path")
+		if a != nil {
+			var ok bool
+			if paths, ok = rf.paths(a, "value"); !ok {
+				continue
+			}
+		} else {
+			a = &annotation{line: line}
+		}
+		rf.add(frameworkJAXRS, m, a, prefixes, paths, methods)
+	}
+}
+
+func (rf *routeFinder) micronautRoutes(decl *typeDecl) {
+	prefixes := []string{""}
+	if paths, ok := rf.paths(rf.annotation(decl.mods, frameworkMicronaut, "Controller"), "value"); ok && len(paths) > 0 {
+		prefixes = paths
+	}
+	for _, m := range decl.methods {
+		for _, a := range m.mods.annotations {
+			var method string
+			for name, verb := range micronautMethods {
+				if rf.is(a, frameworkMicronaut, name) {
+					method = verb
+				}
+			}
+			if method == "" {
+				continue
+			}
+			paths, ok := rf.paths(a, "value", "uri", "uris")
+			if !ok {
+				continue
+			}
+			rf.add(frameworkMicronaut, m, a, prefixes, paths, []string{method})
+		}
+	}
+}
+
+// add records a route for every combination of prefix, path and method.
+func (rf *routeFinder) add(framework string, m *methodDecl, a *annotation, prefixes, paths, methods []string) {
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	for _, prefix := range prefixes {
+		for _, p := range paths {
+			for _, method := range methods {
+				rf.sites = append(rf.sites, routeSite{
+					method:    method,
+					path:      joinPath(prefix, p),
+					handler:   m,
+					line:      a.line,
+					framework: framework,
+				})
+			}
+		}
+	}
+}
+
+// paths evaluates the first of the named elements of a that is set, a path
+// or an array of them. It reports false when one of them is not a constant.
+func (rf *routeFinder) paths(a *annotation, names ...string) ([]string, bool) {
+	if a == nil {
+		return nil, true
+	}
+	for _, name := range names {
+		for _, arg := range a.args {
+			if arg.name != name {
+				continue
+			}
+			var paths []string
+			for _, expr := range elements(arg.value) {
+				p, ok := rf.stringValue(expr, 0)
+				if !ok {
+					return nil, false
+				}
+				paths = append(paths, p)
+			}
+			return paths, true
+		}
+	}
+	return nil, true
+}
+
+// requestMethods returns the HTTP methods named by the method element of a
+// RequestMapping, e.g. {RequestMethod.GET, RequestMethod.POST}.
+func requestMethods(a *annotation) []string {
+	var methods []string
+	for _, arg := range a.args {
+		if arg.name != "method" {
+			continue
+		}
+		for _, t := range arg.value {
+			if t.kind == tokIdent && jaxrsMethods[t.text] {
+				methods = append(methods, t.text)
+			}
+		}
+	}
+	return methods
+}
+
+// elements splits the value of an annotation element into the expressions
+// of its array, or the single expression it is.
+func elements(value []token) [][]token {
+	if len(value) < 2 || !value[0].is("{") || !value[len(value)-1].is("}") {
+		return [][]token{value}
+	}
+	var exprs [][]token
+	depth, start := 0, 1
+	for i := 1; i < len(value)-1; i++ {
+		switch t := value[i]; {
+		case t.is("(") || t.is("{") || t.is("["):
+			depth++
+		case t.is(")") || t.is("}") || t.is("]"):
+			depth--
+		case t.is(",") && depth == 0:
+			exprs = append(exprs, value[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(value)-1 {
+		exprs = append(exprs, value[start:len(value)-1])
+	}
+	return exprs
+}
+
+// stringValue evaluates string literals, constants declared in the same file
+// and concatenations of them.
+func (rf *routeFinder) stringValue(expr []token, depth int) (string, bool) {
+	if len(expr) == 0 || depth > 8 {
+		return "", false
+	}
+	var b strings.Builder
+	for len(expr) > 0 {
+		t := expr[0]
+		switch {
+		case t.kind == tokString && !strings.HasPrefix(t.text, `"""`):
+			s, err := strconv.Unquote(t.text)
+			if err != nil {
+				s = t.text[1 : len(t.text)-1]
+			}
+			b.WriteString(s)
+			expr = expr[1:]
+		case t.kind == tokIdent:
+			// A constant, possibly qualified by its class
+			n := 1
+			for n+1 < len(expr) && expr[n].is(".") && expr[n+1].kind == tokIdent {
+				n += 2
+			}
+			init, ok := rf.consts[expr[n-1].text]
+			if !ok {
+				return "", false
+			}
+			s, ok := rf.stringValue(init, depth+1)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(s)
+			expr = expr[n:]
+		default:
+			return "", false
+		}
+		if len(expr) > 0 {
+			if !expr[0].is("+") {
+				return "", false
+			}
+			expr = expr[1:]
+			if len(expr) == 0 {
+				return "", false
+			}
+		}
+	}
+	return b.String(), true
+}
+
+// joinPath appends p to prefix with exactly one slash between them. Paths
+// of Java frameworks need not start with a slash, but served ones do.
+func joinPath(prefix, p string) string {
+	path := "/" + strings.Trim(prefix, "/")
+	if p = strings.Trim(p, "/"); p != "" {
+		path = strings.TrimSuffix(path, "/") + "/" + p
+	}
+	return path
+}
@@ -0,0 +1,229 @@
+package java
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want maps each route to its framework and the handler linked to it,
+		// in "framework handler line" form; a method starts at its first
+		// annotation.
+		want map[string]string
+	}{
+		{
+			name: "spring",
+			src: `package com.example.web;
+
+import org.springframework.web.bind.annotation.*;
+
+@RestController
+@RequestMapping(UserController.BASE)
+public class UserController {
+    static final String BASE = "/api/" + "users";
+    private static final String BY_ID = "/{id}";
+
+    @GetMapping(BY_ID)
+    public User get(@PathVariable long id) { return null; }
+
+    @PostMapping
+    public User create(@RequestBody User u) { return u; }
+
+    @RequestMapping(value = {"/search", "find"}, method = {RequestMethod.GET, RequestMethod.POST})
+    public java.util.List<User> search(String q) { return null; }
+
+    @DeleteMapping(path = BY_ID)
+    public void delete(long id) {}
+
+    @GetMapping(dynamicPath())
+    public void skipped() {}
+
+    public void helper() {}
+}
+`,
+			want: map[string]string{
+				"GET /api/users/{id}":    "spring com.example.web.UserController.get 11",
+				"POST /api/users":        "spring com.example.web.UserController.create 14",
+				"GET /api/users/search":  "spring com.example.web.UserController.search 17",
+				"POST /api/users/search": "spring com.example.web.UserController.search 17",
+				"GET /api/users/find":    "spring com.example.web.UserController.search 17",
+				"POST /api/users/find":   "spring com.example.web.UserController.search 17",
+				"DELETE /api/users/{id}": "spring com.example.web.UserController.delete 20",
+			},
+		},
+		{
+			name: "spring class mapping restricting methods",
+			src: `import org.springframework.web.bind.annotation.RequestMapping;
+import org.springframework.web.bind.annotation.RequestMethod;
+import org.springframework.stereotype.Controller;
+
+@Controller
+@RequestMapping(path = "legacy/", method = RequestMethod.PUT)
+class Legacy {
+    @RequestMapping("/item")
+    void item() {}
+
+    @RequestMapping
+    void index() {}
+}
+`,
+			want: map[string]string{
+				"PUT /legacy/item": "spring Legacy.item 8",
+				"PUT /legacy":      "spring Legacy.index 11",
+			},
+		},
+		{
+			name: "jax-rs",
+			src: `package shop;
+
+import jakarta.ws.rs.*;
+
+@Path("/orders")
+public class OrderResource {
+    @GET
+    public java.util.List<Order> list() { return null; }
+
+    @GET
+    @Path("{id}")
+    public Order get(@PathParam("id") String id) { return null; }
+
+    @POST
+    @PUT
+    @Path("/{id}")
+    public Order save(Order o) { return o; }
+
+    @Path("{id}/items")
+    public ItemResource items() { return null; }
+}
+`,
+			want: map[string]string{
+				"GET /orders":       "jax-rs shop.OrderResource.list 7",
+				"GET /orders/{id}":  "jax-rs shop.OrderResource.get 10",
+				"POST /orders/{id}": "jax-rs shop.OrderResource.save 14",
+				"PUT /orders/{id}":  "jax-rs shop.OrderResource.save 14",
+			},
+		},
+		{
+			name: "jax-rs client interface",
+			src: `import javax.ws.rs.GET;
+import javax.ws.rs.Path;
+import org.eclipse.microprofile.rest.client.inject.RegisterRestClient;
+
+@Path("/remote")
+@RegisterRestClient
+interface RemoteApi {
+    @GET
+    String fetch();
+}
+`,
+		},
+		{
+			name: "micronaut",
+			src: `import io.micronaut.http.annotation.Controller;
+import io.micronaut.http.annotation.Get;
+import io.micronaut.http.annotation.Post;
+
+@Controller("/hello")
+class HelloController {
+    @Get
+    String index() { return "hi"; }
+
+    @Post(uri = "/{name}")
+    String greet(String name) { return name; }
+}
+`,
+			want: map[string]string{
+				"GET /hello":         "micronaut HelloController.index 7",
+				"POST /hello/{name}": "micronaut HelloController.greet 10",
+			},
+		},
+		{
+			name: "overloaded handlers",
+			src: `import org.springframework.web.bind.annotation.*;
+
+@RestController
+class Files {
+    @GetMapping("/files")
+    String read() { return ""; }
+
+    @GetMapping("/files/{name}")
+    String read(
+            @PathVariable String name) { return name; }
+}
+`,
+			want: map[string]string{
+				"GET /files":        "spring Files.read 5",
+				"GET /files/{name}": "spring Files.read 8",
+			},
+		},
+		{
+			name: "annotations of unknown frameworks",
+			src: `import com.acme.GetMapping;
+
+@RestController
+class NotSpring {
+    @GetMapping("/x")
+    void x() {}
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := scan(t, "Controller.java", tt.src)
+			graph, err := NewJavaLinker().Link(context.Background(), scanner.Tree{}, nodes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byID := make(map[string]*models.CodeNode)
+			for _, n := range nodes {
+				byID[n.ID] = n
+			}
+			handlers := make(map[string][]string) // route ID -> "handler line"
+			if graph != nil {
+				for _, e := range graph.Edges {
+					if e.Kind != models.EdgeHandlesRoute {
+						t.Errorf("unexpected %s edge", e.Kind)
+						continue
+					}
+					h := byID[e.Source]
+					handlers[e.Target] = append(handlers[e.Target], h.Name+" "+strconv.Itoa(h.LineNumber))
+				}
+			}
+
+			got := make(map[string]string)
+			for _, n := range nodes {
+				if n.Type != models.NodeRoute {
+					continue
+				}
+				if _, dup := got[n.Name]; dup {
+					t.Errorf("route %s found twice", n.Name)
+				}
+				if want := n.Metadata["method"].(string) + " " + n.Metadata["path"].(string); want != n.Name {
+					t.Errorf("route %s has method and path %q", n.Name, want)
+				}
+				sort.Strings(handlers[n.ID])
+				got[n.Name] = n.Metadata["framework"].(string) + " " + strings.Join(handlers[n.ID], ", ")
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("route %s = %q, want %q", name, got[name], want)
+				}
+			}
+			for name := range got {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("unexpected route %s = %q", name, got[name])
+				}
+			}
+		})
+	}
+}
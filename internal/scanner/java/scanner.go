@@ -1,14 +1,13 @@
 package java
 
 import (
-	"bufio"
-	"bytes"
-	"fmt"
-	"regexp"
+	"context"
+	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/chinmay-sawant/gosourcemapper/internal/models"
-	"github.com/google/uuid"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
 )
 
 type JavaScanner struct{}
@@ -17,98 +16,303 @@ func NewJavaScanner() *JavaScanner {
 	return &JavaScanner{}
 }
 
-func (s *JavaScanner) Scan(filePath string, content []byte) ([]*models.CodeNode, error) {
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	var nodes []*models.CodeNode
+// Scan returns a node for every class, interface, enum, record and
+// annotation type of the file, nested, local and anonymous ones included,
+// and for every method and constructor, named after their package and
+// enclosing types: pkg.Outer.Inner.method. Routes served by Spring, JAX-RS
+// and Micronaut controllers get a ROUTE node each. The javadoc of types and
+// methods is recorded in their metadata: summary, param_docs, return_doc,
+// throws_docs and deprecated.
+func (s *JavaScanner) Scan(ctx context.Context, root, filePath string, content []byte) ([]*models.CodeNode, error) {
+	unit, toks, err := parse(string(content))
+	var syntax *syntaxError
+	if errors.As(err, &syntax) {
+		return nil, &scanner.ParseError{File: filePath, Line: syntax.line, Column: syntax.col, Msg: syntax.msg}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// Heuristics (Regex)
-	// Spring Components
-	reClass := regexp.MustCompile(`(public|protected|private)?\s*(class|interface)\s+(\w+)`)
-	reMethod := regexp.MustCompile(`(public|protected|private)\s+[\w<>]+\s+(\w+)\s*\(.*\)`)
-	reHTTP := regexp.MustCompile(`(RestTemplate|WebClient|HttpClient|MockMvc)`) // Usage
+	f := &file{path: filePath, unit: unit, toks: toks, ids: models.NewIDAllocator("java", unit.pkg, models.IDPath(root, filePath))}
+	for _, decl := range unit.types {
+		f.typeNodes(decl)
+	}
+	f.httpCalls()
+	f.routes()
+	return f.nodes, nil
+}
 
-	var currentClassName string
-	var comments []string
-	lineNumber := 0
+// file gathers the nodes of a parsed source file.
+type file struct {
+	path  string
+	unit  *compilationUnit
+	toks  []token
+	ids   *models.IDAllocator
+	nodes []*models.CodeNode
+	// scopes are the token ranges of the declarations found so far.
+	scopes []scopeRange
+}
 
-	for scanner.Scan() {
-		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+type scopeRange struct {
+	start, end int
+	name       string
+	method     *methodDecl // nil for types
+}
 
-		if strings.HasPrefix(line, "//") || strings.HasPrefix(line, "*") || strings.HasPrefix(line, "/*") {
-			comments = append(comments, line)
-			continue
-		}
+// qualified prefixes scope, a name nested in the types of the file, with
+// the package.
+func (f *file) qualified(scope string) string {
+	if f.unit.pkg == "" {
+		return scope
+	}
+	return f.unit.pkg + "." + scope
+}
 
-		if match := reClass.FindStringSubmatch(line); len(match) > 3 {
-			nodeType := models.NodeClass
-			if match[2] == "interface" {
-				nodeType = models.NodeInterface
-			}
-			currentClassName = match[3]
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       nodeType,
-				Name:       currentClassName,
-				Language:   "java",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-				Comments:   cloneAndReverse(comments),
-			})
-			comments = nil // Reset
-			continue
+// typeNodes adds the nodes of decl, its methods and the types declared in it.
+func (f *file) typeNodes(decl *typeDecl) {
+	nodeType := models.NodeClass
+	if decl.kind == "interface" || decl.kind == "annotation" {
+		nodeType = models.NodeInterface
+	}
+	name := f.qualified(decl.scope)
+	meta := map[string]interface{}{
+		"package":     f.unit.pkg,
+		"kind":        decl.kind,
+		"end_line":    decl.endLine,
+		"modifiers":   keywords(decl.mods),
+		"annotations": annotationTexts(decl.mods),
+		"anonymous":   decl.anonymous,
+	}
+	if len(decl.typeParams) > 0 {
+		meta["type_params"] = decl.typeParams
+	}
+	if len(decl.extends) > 0 {
+		meta["extends"] = decl.extends
+	}
+	if len(decl.implements) > 0 {
+		meta["implements"] = decl.implements
+	}
+	if decl.kind == "record" {
+		meta["components"] = decl.components
+	}
+	comments, doc := f.comments(decl.start)
+	docMetadata(meta, decl.mods, doc)
+	f.nodes = append(f.nodes, &models.CodeNode{
+		ID:         f.ids.ID(name, nodeType),
+		Type:       nodeType,
+		Name:       name,
+		Language:   "java",
+		FilePath:   f.path,
+		LineNumber: decl.line,
+		Comments:   comments,
+		Metadata:   meta,
+	})
+	f.scopes = append(f.scopes, scopeRange{start: decl.start, end: decl.end, name: name})
+
+	for _, m := range decl.methods {
+		f.methodNode(m)
+	}
+	for _, nested := range decl.types {
+		f.typeNodes(nested)
+	}
+}
+
+func (f *file) methodNode(m *methodDecl) {
+	name := f.qualified(m.scope())
+	results := []models.Param{}
+	if m.result != "" && m.result != "void" {
+		results = append(results, models.Param{Type: m.result})
+	}
+	meta := map[string]interface{}{
+		"package":     f.unit.pkg,
+		"receiver":    f.qualified(m.owner.scope),
+		"params":      m.params,
+		"results":     results,
+		"variadic":    m.variadic,
+		"constructor": m.constructor,
+		"visibility":  visibility(m),
+		"end_line":    m.endLine,
+		"modifiers":   keywords(m.mods),
+		"annotations": annotationTexts(m.mods),
+	}
+	if len(m.typeParams) > 0 {
+		meta["type_params"] = m.typeParams
+	}
+	if len(m.throws) > 0 {
+		meta["throws"] = m.throws
+	}
+	comments, doc := f.comments(m.start)
+	docMetadata(meta, m.mods, doc)
+	f.nodes = append(f.nodes, &models.CodeNode{
+		// Overloads are identified by their parameter types rather than by
+		// their order, which adding an overload would change
+		ID:         f.ids.ID(f.qualified(m.identity()), models.NodeFunction),
+		Type:       models.NodeFunction,
+		Name:       name,
+		Language:   "java",
+		FilePath:   f.path,
+		LineNumber: m.line,
+		Signature:  m.signature(),
+		Comments:   comments,
+		Metadata:   meta,
+	})
+	f.scopes = append(f.scopes, scopeRange{start: m.start, end: m.end, name: name, method: m})
+}
+
+// visibility is the access level of m: public, protected, private or
+// package.
+func visibility(m *methodDecl) string {
+	for _, k := range []string{"public", "protected", "private"} {
+		if m.mods.has(k) {
+			return k
 		}
+	}
+	if m.owner.kind == "interface" || m.owner.kind == "annotation" {
+		return "public"
+	}
+	return "package"
+}
+
+func keywords(mods modifiers) []string {
+	if mods.keywords == nil {
+		return []string{}
+	}
+	return mods.keywords
+}
+
+func annotationTexts(mods modifiers) []string {
+	texts := []string{}
+	for _, a := range mods.annotations {
+		texts = append(texts, a.text)
+	}
+	return texts
+}
+
+// comments returns the comments of the declaration starting at token start,
+// closest first: those among its annotations and modifiers, and those right
+// above it, up to the first blank line. Each entry is the text of a comment,
+// adjacent line comments forming one, without comment markers. doc is the
+// closest javadoc, if any.
+func (f *file) comments(start int) (texts []string, doc *javadoc) {
+	if start >= len(f.toks) {
+		return nil, nil
+	}
+	lead := f.toks[start].comments
+	line, k := f.toks[start].line, len(lead)
+	for k > 0 && lead[k-1].endLine >= line-1 {
+		k--
+		line = lead[k].line
+	}
+	cs := append([]comment(nil), lead[k:]...)
+	p := &parser{toks: f.toks}
+	for i := start + 1; i <= p.skipAnnotations(start) && i < len(f.toks); i++ {
+		cs = append(cs, f.toks[i].comments...)
+	}
 
-		if match := reMethod.FindStringSubmatch(line); len(match) > 2 {
-			methodName := match[2]
-			fullName := methodName
-			if currentClassName != "" {
-				fullName = fmt.Sprintf("%s.%s", currentClassName, methodName)
+	var groups [][]comment
+	for _, c := range cs {
+		if n := len(groups); n > 0 {
+			last := groups[n-1][len(groups[n-1])-1]
+			if isLineComment(c) && isLineComment(last) && c.line == last.endLine+1 {
+				groups[n-1] = append(groups[n-1], c)
+				continue
 			}
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       models.NodeFunction,
-				Name:       fullName,
-				Language:   "java",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-				Comments:   cloneAndReverse(comments),
-			})
-			comments = nil
+		}
+		groups = append(groups, []comment{c})
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		var lines []string
+		for _, c := range groups[i] {
+			lines = append(lines, commentLines(c)...)
+		}
+		if len(lines) == 0 {
 			continue
 		}
-
-		if match := reHTTP.FindStringSubmatch(line); len(match) > 1 {
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       models.NodeHTTPCall,
-				Name:       match[1],
-				Language:   "java",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-			})
+		texts = append(texts, strings.Join(lines, "\n")+"\n")
+		if doc == nil && isJavadoc(groups[i][0]) {
+			doc = parseJavadoc(groups[i][0])
 		}
+	}
+	return texts, doc
+}
+
+func isLineComment(c comment) bool {
+	return strings.HasPrefix(c.text, "//")
+}
 
-		if line != "" && !strings.HasPrefix(line, "@") {
-			// Reset comments if we hit code that isn't a class/method
-			comments = nil
+// docMetadata records the javadoc of a declaration in meta, and whether it
+// is deprecated, by a @deprecated tag or a @Deprecated annotation.
+func docMetadata(meta map[string]interface{}, mods modifiers, doc *javadoc) {
+	deprecated := false
+	if doc != nil {
+		if doc.summary != "" {
+			meta["summary"] = doc.summary
+		}
+		if len(doc.params) > 0 {
+			meta["param_docs"] = doc.params
+		}
+		if doc.returns != "" {
+			meta["return_doc"] = doc.returns
+		}
+		if len(doc.throws) > 0 {
+			meta["throws_docs"] = doc.throws
+		}
+		if doc.deprecated {
+			deprecated = true
+			if doc.deprecation != "" {
+				meta["deprecation"] = doc.deprecation
+			}
 		}
 	}
-
-	return nodes, nil
+	if a := annotationNamed(mods, "Deprecated"); a != nil {
+		deprecated = true
+		for _, arg := range a.args {
+			switch {
+			case arg.name == "since" && len(arg.value) == 1 && arg.value[0].kind == tokString:
+				if since, err := strconv.Unquote(arg.value[0].text); err == nil {
+					meta["deprecated_since"] = since
+				}
+			case arg.name == "forRemoval" && len(arg.value) == 1:
+				meta["for_removal"] = arg.value[0].is("true")
+			}
+		}
+	}
+	meta["deprecated"] = deprecated
 }
 
-func cloneAndReverse(input []string) []string {
-	if len(input) == 0 {
-		return nil
+// routes adds a ROUTE node for every route served by a controller of the
+// file. The handler is recorded by name; JavaLinker links it to its node.
+func (f *file) routes() {
+	for _, site := range findRoutes(f.unit) {
+		name := site.name()
+		f.nodes = append(f.nodes, &models.CodeNode{
+			ID:         f.ids.ID(name, models.NodeRoute),
+			Type:       models.NodeRoute,
+			Name:       name,
+			Language:   "java",
+			FilePath:   f.path,
+			LineNumber: site.line,
+			Metadata: map[string]interface{}{
+				"method":    site.method,
+				"path":      site.path,
+				"framework": site.framework,
+				"handler":   f.qualified(site.handler.scope()),
+			},
+		})
 	}
-	output := make([]string, len(input))
-	// Regular copy. The prompt requirement was "going up from the function", which implies simple preceeding order
-	// or reverse order. My Go implementation does closest-first.
-	// For this stream implementation, the last comment added is the closest.
-	// So reversing it makes it closest-first (bottom-up).
-	for i, v := range input {
-		output[len(input)-1-i] = v
+}
+
+// scopeAt returns the innermost declaration holding token i, if any.
+func (f *file) scopeAt(i int) (scopeRange, bool) {
+	var inner scopeRange
+	found := false
+	for _, s := range f.scopes {
+		if i >= s.start && i < s.end && (!found || s.end-s.start < inner.end-inner.start) {
+			inner, found = s, true
+		}
 	}
-	return output
+	return inner, found
 }
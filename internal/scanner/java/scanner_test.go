@@ -0,0 +1,288 @@
+package java
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// scan scans src as the file at path and fails the test on error.
+func scan(t *testing.T, path, src string) []*models.CodeNode {
+	t.Helper()
+	nodes, err := NewJavaScanner().Scan(context.Background(), "", path, []byte(src))
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	return nodes
+}
+
+// nodesOf returns the nodes of type typ in "name line" form.
+func nodesOf(nodes []*models.CodeNode, typ models.NodeType) []string {
+	var got []string
+	for _, n := range nodes {
+		if n.Type == typ {
+			got = append(got, fmt.Sprintf("%s %d", n.Name, n.LineNumber))
+		}
+	}
+	return got
+}
+
+func TestScanDeclarations(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		types     []string // "name line" of classes and interfaces
+		functions []string // "name line" of methods and constructors
+	}{
+		{
+			name: "nested, local and anonymous types",
+			src: `package com.example;
+
+import java.util.List;
+
+public class Outer<T extends Comparable<T>> {
+    interface Visitor { void visit(); }
+
+    static class Inner {
+        Inner(int x) {}
+    }
+
+    void run() {
+        class Local {}
+        Runnable r = new Runnable() {
+            public void run() {}
+        };
+    }
+}
+`,
+			types: []string{
+				"com.example.Outer 5",
+				"com.example.Outer.Visitor 6",
+				"com.example.Outer.Inner 8",
+				"com.example.Outer.run.Local 13",
+				"com.example.Outer.run.$1 14",
+			},
+			functions: []string{
+				"com.example.Outer.run 12",
+				"com.example.Outer.Visitor.visit 6",
+				"com.example.Outer.Inner.Inner 9",
+				"com.example.Outer.run.$1.run 15",
+			},
+		},
+		{
+			name: "enums, records and annotation types",
+			src: `package p;
+
+enum Color {
+    RED { int code() { return 1; } },
+    GREEN;
+
+    int code() { return 0; }
+}
+
+record Point(int x, int y) {
+    Point {
+        if (x < 0) throw new IllegalArgumentException();
+    }
+}
+
+@interface Audited {
+    String value() default "";
+}
+`,
+			types: []string{
+				"p.Color 3",
+				"p.Color.RED 4",
+				"p.Point 10",
+				"p.Audited 16",
+			},
+			functions: []string{
+				"p.Color.code 7",
+				"p.Color.RED.code 4",
+				"p.Point.Point 11",
+				"p.Audited.value 17",
+			},
+		},
+		{
+			name: "lexically tricky bodies",
+			src: `class Tricky {
+    String block = """
+        class NotAType { void no() {} }
+        """;
+    char brace = '{';
+    String s = "}\"{";
+    /* void commented() {} */
+    // void lineCommented() {}
+    int[] values = {1, 2};
+
+    <R> R generic(java.util.function.Function<String, R> f) { return f.apply(s); }
+    void lambda() { Runnable r = () -> { int x = 1; }; }
+}
+`,
+			types: []string{"Tricky 1"},
+			functions: []string{
+				"Tricky.generic 11",
+				"Tricky.lambda 12",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := scan(t, "Test.java", tt.src)
+			var types []string
+			for _, typ := range []models.NodeType{models.NodeClass, models.NodeInterface} {
+				types = append(types, nodesOf(nodes, typ)...)
+			}
+			if got := sortedLines(types); got != sortedLines(tt.types) {
+				t.Errorf("types:\n%s\nwant:\n%s", got, sortedLines(tt.types))
+			}
+			if got, want := sortedLines(nodesOf(nodes, models.NodeFunction)), sortedLines(tt.functions); got != want {
+				t.Errorf("functions:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func sortedLines(lines []string) string {
+	sorted := append([]string(nil), lines...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\n")
+}
+
+func TestScanMethodMetadata(t *testing.T) {
+	src := `package p;
+
+public abstract class Repo<E> {
+    protected abstract <K extends Comparable<K>> java.util.List<E> find(K key, String... tags) throws java.io.IOException;
+    static void helper() {}
+    Repo(int size) {}
+}
+
+interface Api {
+    int count();
+}
+`
+	tests := []struct {
+		name        string
+		signature   string
+		visibility  string
+		constructor bool
+		variadic    bool
+		results     int
+	}{
+		{"p.Repo.find", "protected abstract <K extends Comparable<K>> java.util.List<E> find(K key, String... tags) throws java.io.IOException", "protected", false, true, 1},
+		{"p.Repo.helper", "static void helper()", "package", false, false, 0},
+		{"p.Repo.Repo", "Repo(int size)", "package", true, false, 0},
+		{"p.Api.count", "int count()", "public", false, false, 1},
+	}
+	byName := make(map[string]*models.CodeNode)
+	for _, n := range scan(t, "Repo.java", src) {
+		byName[n.Name] = n
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := byName[tt.name]
+			if n == nil {
+				t.Fatalf("no node %s", tt.name)
+			}
+			if n.Signature != tt.signature {
+				t.Errorf("signature = %q, want %q", n.Signature, tt.signature)
+			}
+			if got := n.Metadata["visibility"]; got != tt.visibility {
+				t.Errorf("visibility = %v, want %s", got, tt.visibility)
+			}
+			if got := n.Metadata["constructor"]; got != tt.constructor {
+				t.Errorf("constructor = %v, want %v", got, tt.constructor)
+			}
+			if got := n.Metadata["variadic"]; got != tt.variadic {
+				t.Errorf("variadic = %v, want %v", got, tt.variadic)
+			}
+			if got := len(n.Metadata["results"].([]models.Param)); got != tt.results {
+				t.Errorf("%d results, want %d", got, tt.results)
+			}
+		})
+	}
+}
+
+func TestScanSyntaxError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"unterminated string", "class A {\n  String s = \"open;\n}\n", 2},
+		{"unterminated comment", "class A {}\n/* never closed\n", 2},
+		{"unbalanced braces", "class A {\n  void f() {\n}\n", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJavaScanner().Scan(context.Background(), "", "A.java", []byte(tt.src))
+			var perr *scanner.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("error = %v, want a *scanner.ParseError", err)
+			}
+			if perr.File != "A.java" || perr.Line != tt.line {
+				t.Errorf("error at %s:%d, want A.java:%d", perr.File, perr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestOverloadIDs(t *testing.T) {
+	before := `import org.springframework.web.client.RestTemplate;
+
+class Client {
+    RestTemplate rest;
+
+    void send(String body) { rest.postForObject("http://a/x", body, Void.class); }
+
+    void send(java.util.List<String> bodies, int... retries) {}
+}
+`
+	// A new overload ahead of the others, and another spacing of the types
+	after := `import org.springframework.web.client.RestTemplate;
+
+class Client {
+    RestTemplate rest;
+
+    void send() { rest.postForObject("http://a/y", null, Void.class); }
+
+    void send(String body) { rest.postForObject("http://a/x", body, Void.class); }
+
+    void send(java.util.List< String > bodies, int ... retries) {}
+}
+`
+	// ids returns the IDs of the methods by number of parameters, and of the
+	// calls by URL.
+	ids := func(src string) map[string]string {
+		byKey := make(map[string]string)
+		for _, n := range scan(t, "Client.java", src) {
+			switch n.Type {
+			case models.NodeFunction:
+				byKey[fmt.Sprintf("send/%d", len(n.Metadata["params"].([]models.Param)))] = n.ID
+			case models.NodeHTTPCall:
+				byKey[n.Metadata["url"].(string)] = n.ID
+			}
+		}
+		return byKey
+	}
+	old, new := ids(before), ids(after)
+	for _, key := range []string{"send/1", "send/2", "http://a/x"} {
+		if old[key] == "" || old[key] != new[key] {
+			t.Errorf("%s: ID %q, then %q after adding an overload", key, old[key], new[key])
+		}
+	}
+	distinct := make(map[string]bool)
+	for _, id := range new {
+		distinct[id] = true
+	}
+	if len(new) != 5 || len(distinct) != 5 {
+		t.Errorf("IDs %v, want 5 distinct ones", new)
+	}
+}
@@ -0,0 +1,377 @@
+package python
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF  tokenKind = iota
+	tokName           // identifiers and keywords
+	tokNumber
+	tokString // string literals, prefix and quotes included
+	tokOp
+	tokNewline // end of a logical line
+	tokIndent
+	tokDedent
+)
+
+// token is a lexeme of a Python source file. Comments are not tokens: those
+// before a token are attached to it, and a comment ending a line to the
+// tokNewline token of that line.
+type token struct {
+	kind     tokenKind
+	text     string
+	line     int
+	col      int
+	comments []comment
+}
+
+// comment is a comment as written, # included.
+type comment struct {
+	text string
+	line int
+}
+
+func (t token) is(text string) bool {
+	return (t.kind == tokName || t.kind == tokOp) && t.text == text
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "end of line"
+	case tokIndent:
+		return "indent"
+	case tokDedent:
+		return "dedent"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// Operators of more than one character, longest first.
+var operators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"->", "**", "//", ":=", "==", "!=", "<=", ">=", "<<", ">>",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=",
+}
+
+// lexer splits Python source into tokens, the way the Python tokenizer
+// does: indentation becomes tokIndent and tokDedent tokens, and line breaks
+// end logical lines unless escaped by a backslash or inside brackets.
+type lexer struct {
+	src     string
+	pos     int
+	line    int
+	col     int
+	depth   int   // of open brackets
+	open    token // outermost open bracket
+	indents []int // columns of the enclosing blocks, 0 first
+	tokens  []token
+	pending []comment // comments not yet attached
+}
+
+// lex returns the tokens of src, ending with a tokEOF token that carries the
+// trailing comments.
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, col: 1, indents: []int{0}}
+	atLineStart := true
+	for {
+		if atLineStart && l.depth == 0 {
+			blank, err := l.indentation()
+			if err != nil {
+				return nil, err
+			}
+			if blank {
+				continue
+			}
+			atLineStart = false
+		}
+		l.skipSpace()
+		if l.pos >= len(l.src) {
+			break
+		}
+		switch c := l.src[l.pos]; {
+		case c == '\\' && strings.HasPrefix(l.src[l.pos+1:], "\n"), c == '\\' && strings.HasPrefix(l.src[l.pos+1:], "\r\n"):
+			// An explicit line continuation
+			l.advance(1)
+			if l.src[l.pos] == '\r' {
+				l.advance(1)
+			}
+			l.newline()
+		case c == '\n':
+			if l.depth == 0 {
+				l.emit(token{kind: tokNewline, text: "\n", line: l.line, col: l.col})
+				atLineStart = true
+			}
+			l.newline()
+		default:
+			t, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			l.emit(t)
+		}
+	}
+
+	if l.depth > 0 {
+		return nil, l.errorf(l.open.line, l.open.col, "%q was never closed", l.open.text)
+	}
+	// The last line need not end with a line break, and its blocks end there
+	if n := len(l.tokens); n > 0 && l.tokens[n-1].kind != tokNewline && l.tokens[n-1].kind != tokDedent {
+		l.emit(token{kind: tokNewline, line: l.line, col: l.col})
+	}
+	for len(l.indents) > 1 {
+		l.indents = l.indents[:len(l.indents)-1]
+		l.emit(token{kind: tokDedent, line: l.line, col: l.col})
+	}
+	l.emit(token{kind: tokEOF, line: l.line, col: l.col})
+	return l.tokens, nil
+}
+
+// emit appends t, attaching the pending comments to it unless it is an
+// indentation token.
+func (l *lexer) emit(t token) {
+	if t.kind != tokIndent && t.kind != tokDedent {
+		t.comments, l.pending = l.pending, nil
+	}
+	l.tokens = append(l.tokens, t)
+}
+
+// syntaxError is a malformed token or declaration.
+type syntaxError struct {
+	line, col int
+	msg       string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.line, e.col, e.msg)
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...interface{}) error {
+	return &syntaxError{line: line, col: col, msg: fmt.Sprintf(format, args...)}
+}
+
+// advance moves past n bytes, none of which is a newline.
+func (l *lexer) advance(n int) {
+	l.pos += n
+	l.col += n
+}
+
+// newline moves past the newline at pos.
+func (l *lexer) newline() {
+	l.pos++
+	l.line++
+	l.col = 1
+}
+
+// indentation reads the indentation of the line starting at pos, emitting
+// the tokIndent or tokDedent tokens it implies. Lines holding nothing but
+// white space and comments have no indentation: they are consumed whole and
+// reported blank.
+func (l *lexer) indentation() (blank bool, err error) {
+	width := 0
+measure:
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ':
+			width++
+		case '\t':
+			// Tabs move to the next multiple of 8, as in the Python tokenizer
+			width = (width/8 + 1) * 8
+		case '\f':
+			width = 0
+		case '\r':
+		default:
+			break measure
+		}
+		l.advance(1)
+	}
+	switch {
+	case l.pos >= len(l.src):
+		return false, nil
+	case l.src[l.pos] == '#':
+		l.comment()
+		if l.pos < len(l.src) {
+			l.newline()
+		}
+		return true, nil
+	case l.src[l.pos] == '\n':
+		l.newline()
+		return true, nil
+	case strings.HasPrefix(l.src[l.pos:], "\\\n"):
+		// A continuation on an otherwise blank line joins it to the next one
+		l.advance(1)
+		l.newline()
+		return true, nil
+	}
+
+	top := l.indents[len(l.indents)-1]
+	switch {
+	case width > top:
+		l.indents = append(l.indents, width)
+		l.emit(token{kind: tokIndent, line: l.line, col: l.col})
+	case width < top:
+		for width < l.indents[len(l.indents)-1] {
+			l.indents = l.indents[:len(l.indents)-1]
+			l.emit(token{kind: tokDedent, line: l.line, col: l.col})
+		}
+		if width != l.indents[len(l.indents)-1] {
+			return false, l.errorf(l.line, l.col, "unindent does not match any outer indentation level")
+		}
+	}
+	return false, nil
+}
+
+// skipSpace moves past white space and comments within a line, and past
+// line breaks inside brackets.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			l.advance(1)
+		case c == '\n' && l.depth > 0:
+			l.newline()
+		case c == '#':
+			l.comment()
+		default:
+			return
+		}
+	}
+}
+
+// comment moves past the comment at pos, up to the end of its line.
+func (l *lexer) comment() {
+	end := strings.IndexByte(l.src[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.src) - l.pos
+	}
+	text := strings.TrimRight(l.src[l.pos:l.pos+end], "\r")
+	l.pending = append(l.pending, comment{text: text, line: l.line})
+	l.advance(end)
+}
+
+func (l *lexer) next() (token, error) {
+	t := token{line: l.line, col: l.col}
+	start := l.pos
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	switch {
+	case stringPrefix(l.src[l.pos:]) >= 0:
+		l.advance(stringPrefix(l.src[l.pos:]))
+		if err := l.str(); err != nil {
+			return t, err
+		}
+		t.kind = tokString
+	case r == '_' || unicode.IsLetter(r):
+		for l.pos < len(l.src) {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.advance(size)
+		}
+		t.kind = tokName
+	case r >= '0' && r <= '9' || r == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		l.number()
+		t.kind = tokNumber
+	default:
+		t.kind = tokOp
+		n := size
+		for _, op := range operators {
+			if strings.HasPrefix(l.src[l.pos:], op) {
+				n = len(op)
+				break
+			}
+		}
+		switch r {
+		case '(', '[', '{':
+			if l.depth == 0 {
+				l.open = token{text: string(r), line: t.line, col: t.col}
+			}
+			l.depth++
+		case ')', ']', '}':
+			if l.depth > 0 {
+				l.depth--
+			}
+		}
+		l.advance(n)
+	}
+	t.text = l.src[start:l.pos]
+	return t, nil
+}
+
+// stringPrefix returns the length of the prefix of the string literal
+// starting s, such as r, b or f, or -1 if s does not start with one.
+func stringPrefix(s string) int {
+	n := 0
+	for n < len(s) && n < 2 && strings.IndexByte("rRbBuUfFtT", s[n]) >= 0 {
+		n++
+	}
+	if n < len(s) && (s[n] == '"' || s[n] == '\'') {
+		return n
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// number moves past a numeric literal, loosely: digits, letters, '_' and
+// '.', with a sign after an exponent marker.
+func (l *lexer) number() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case isDigit(c) || c == '.' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			l.advance(1)
+			if (c == 'e' || c == 'E') && l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+				l.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// str moves past the quotes and contents of a string literal, triple quoted
+// ones spanning lines included. Backslashes escape the character after them,
+// even in raw strings, where they are kept.
+func (l *lexer) str() error {
+	line, col := l.line, l.col
+	quote := l.src[l.pos : l.pos+1]
+	if strings.HasPrefix(l.src[l.pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	l.advance(len(quote))
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\\':
+			l.advance(1)
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.newline()
+			} else if l.pos < len(l.src) {
+				_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+				l.advance(size)
+			}
+		case l.src[l.pos] == '\n':
+			if len(quote) == 1 {
+				return l.errorf(line, col, "string literal not terminated")
+			}
+			l.newline()
+		case strings.HasPrefix(l.src[l.pos:], quote):
+			l.advance(len(quote))
+			return nil
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.advance(size)
+		}
+	}
+	return l.errorf(line, col, "string literal not terminated")
+}
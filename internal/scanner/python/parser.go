@@ -0,0 +1,466 @@
+package python
+
+import (
+	"fmt"
+	"strings"
+)
+
+// decl is a class or function definition, possibly nested in another.
+type decl struct {
+	kind string // class or def
+	name string
+	// scope is name qualified by the enclosing classes and functions, e.g.
+	// Service.fetch or handler.inner.
+	scope      string
+	owner      *decl // enclosing definition, nil at module level
+	async      bool
+	decorators []decorator
+	params     []param
+	returns    string   // return annotation, of functions
+	bases      []string // base classes and keywords, e.g. metaclass=ABCMeta
+	doc        string   // docstring
+	line       int      // of the def or class keyword
+	endLine    int
+	start, end int // token range, from the first decorator to the end of the body
+
+	decls []*decl // definitions in the body
+}
+
+// decorator is a decorator applied to a definition.
+type decorator struct {
+	name string // the decorator expression without its arguments, e.g. app.get
+	text string // source form, e.g. @app.get("/x")
+	line int
+}
+
+// param is a parameter of a function. The bare * and / separators are kept
+// as parameters named so, for the signature.
+type param struct {
+	name       string // prefixed with * or ** for variadic ones
+	annotation string
+	value      string // default value
+}
+
+func (p param) String() string {
+	s := p.name
+	if p.annotation != "" {
+		s += ": " + p.annotation
+	}
+	if p.value != "" {
+		if p.annotation != "" {
+			s += " = " + p.value
+		} else {
+			s += "=" + p.value
+		}
+	}
+	return s
+}
+
+// isMethod reports whether d is a function defined in a class body.
+func (d *decl) isMethod() bool {
+	return d.kind == "def" && d.owner != nil && d.owner.kind == "class"
+}
+
+// signature is the header of a function, e.g.
+// "async def fetch(self, url: str, retries: int = 3) -> Response".
+func (d *decl) signature() string {
+	var b strings.Builder
+	if d.async {
+		b.WriteString("async ")
+	}
+	b.WriteString("def " + d.name + "(")
+	for i, p := range d.params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(p.String())
+	}
+	b.WriteString(")")
+	if d.returns != "" {
+		b.WriteString(" -> " + d.returns)
+	}
+	return b.String()
+}
+
+// parser reads the class and function definitions of a module, skipping
+// the other statements but for the blocks they open.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// parse returns the definitions at module level of src, the others nested
+// in them, along with the tokens of src.
+func parse(src string) ([]*decl, []token, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser{toks: toks}
+	decls, err := p.block(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decls, toks, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+n]
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &syntaxError{line: t.line, col: t.col, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.peek()
+	if !t.is(text) {
+		return t, p.errorf(t, "expected %q, found %s", text, t)
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) ident() (token, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return t, p.errorf(t, "expected a name, found %s", t)
+	}
+	p.pos++
+	return t, nil
+}
+
+// block reads statements up to the end of the block they are in, or of the
+// file, returning the definitions among them, those in nested blocks of
+// if, for, try... statements included.
+func (p *parser) block(owner *decl) ([]*decl, error) {
+	var decls []*decl
+	for {
+		switch t := p.peek(); t.kind {
+		case tokEOF:
+			return decls, nil
+		case tokDedent:
+			p.pos++
+			return decls, nil
+		case tokNewline:
+			p.pos++
+		case tokIndent:
+			return nil, p.errorf(t, "unexpected indent")
+		default:
+			found, err := p.statement(owner)
+			if err != nil {
+				return nil, err
+			}
+			decls = append(decls, found...)
+		}
+	}
+}
+
+// statement reads a statement and the block it opens, if any.
+func (p *parser) statement(owner *decl) ([]*decl, error) {
+	start := p.pos
+	var decorators []decorator
+	for p.peek().is("@") {
+		t := p.peek()
+		end := p.lineEnd()
+		toks := p.toks[p.pos:end]
+		d := decorator{text: joinTokens(toks), line: t.line}
+		for _, t := range toks[1:] {
+			if t.kind != tokName && !t.is(".") {
+				break
+			}
+			d.name += t.text
+		}
+		decorators = append(decorators, d)
+		p.nextLine(end)
+	}
+
+	async := false
+	if p.peek().is("async") && (p.peekAt(1).is("def") || p.peekAt(1).is("for") || p.peekAt(1).is("with")) {
+		async = true
+		p.pos++
+	}
+	t := p.peek()
+	if len(decorators) > 0 && !t.is("def") && !t.is("class") {
+		return nil, p.errorf(t, "expected a definition after decorator, found %s", t)
+	}
+	if t.is("def") || t.is("class") {
+		d, err := p.definition(owner, async, decorators)
+		if err != nil {
+			return nil, err
+		}
+		d.start = start
+		return []*decl{d}, nil
+	}
+
+	// Any other statement: its block, if it opens one, is in the same scope
+	end := p.lineEnd()
+	opens := end > p.pos && p.toks[end-1].is(":")
+	p.nextLine(end)
+	if opens && p.peek().kind == tokIndent {
+		p.pos++
+		return p.block(owner)
+	}
+	return nil, nil
+}
+
+// lineEnd returns the position of the tokNewline ending the logical line
+// at pos.
+func (p *parser) lineEnd() int {
+	i := p.pos
+	for p.toks[i].kind != tokNewline && p.toks[i].kind != tokEOF {
+		i++
+	}
+	return i
+}
+
+// nextLine moves to the line after the one ending at end, a tokNewline, or
+// stays at end if it is the tokEOF.
+func (p *parser) nextLine(end int) {
+	p.pos = end
+	if p.toks[end].kind == tokNewline {
+		p.pos++
+	}
+}
+
+// definition reads a def or class statement and its body.
+func (p *parser) definition(owner *decl, async bool, decorators []decorator) (*decl, error) {
+	kw := p.peek()
+	p.pos++
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d := &decl{kind: kw.text, name: name.text, scope: name.text, owner: owner, async: async, decorators: decorators, line: kw.line}
+	if owner != nil {
+		d.scope = owner.scope + "." + name.text
+	}
+	if p.peek().is("[") {
+		// Type parameters, as in def first[T](xs: list[T]) -> T
+		p.pos = p.closing(p.pos) + 1
+	}
+
+	if d.kind == "def" {
+		if _, err := p.expect("("); err != nil {
+			return nil, err
+		}
+		end := p.closing(p.pos - 1)
+		for _, part := range split(p.toks[p.pos:end]) {
+			d.params = append(d.params, newParam(part))
+		}
+		p.pos = end + 1
+		if p.peek().is("->") {
+			p.pos++
+			i := p.pos
+			for !p.peek().is(":") && p.peek().kind != tokNewline && p.peek().kind != tokEOF {
+				if t := p.peek(); t.is("(") || t.is("[") || t.is("{") {
+					p.pos = p.closing(p.pos)
+				}
+				p.pos++
+			}
+			d.returns = joinTokens(p.toks[i:p.pos])
+		}
+	} else if p.peek().is("(") {
+		end := p.closing(p.pos)
+		for _, part := range split(p.toks[p.pos+1 : end]) {
+			d.bases = append(d.bases, joinTokens(part))
+		}
+		p.pos = end + 1
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokNewline {
+		// A body on the same line, as in def close(self): pass
+		end := p.lineEnd()
+		d.endLine = p.toks[end].line
+		p.nextLine(end)
+		d.end = p.pos
+		return d, nil
+	}
+	p.pos++
+	if p.peek().kind != tokIndent {
+		return nil, p.errorf(p.peek(), "expected an indented block after %s %s", d.kind, d.name)
+	}
+	p.pos++
+	d.doc = p.docstring()
+	if d.decls, err = p.block(d); err != nil {
+		return nil, err
+	}
+	// The body ends with the line break before its dedent, and those of the
+	// blocks nested in it
+	i := p.pos - 1
+	for i > 0 && p.toks[i].kind == tokDedent {
+		i--
+	}
+	d.endLine = p.toks[i].line
+	d.end = p.pos
+	return d, nil
+}
+
+// closing returns the position of the bracket closing the one at open. The
+// lexer made sure there is one.
+func (p *parser) closing(open int) int {
+	depth := 0
+	for i := open; i < len(p.toks); i++ {
+		switch t := p.toks[i]; {
+		case t.kind == tokEOF:
+			return i
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(p.toks) - 1
+}
+
+// docstring returns the value of the string literal starting a body, if it
+// is a statement of its own.
+func (p *parser) docstring() string {
+	i := p.pos
+	for p.toks[i].kind == tokString {
+		i++
+	}
+	if i == p.pos || p.toks[i].kind != tokNewline {
+		return ""
+	}
+	var b strings.Builder
+	for _, t := range p.toks[p.pos:i] {
+		b.WriteString(stringValue(t.text))
+	}
+	return cleanDoc(b.String())
+}
+
+// split splits toks at the commas outside brackets, dropping a trailing
+// comma.
+func split(toks []token) [][]token {
+	var parts [][]token
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch {
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			depth--
+		case t.is(",") && depth == 0:
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		parts = append(parts, toks[start:])
+	}
+	return parts
+}
+
+// newParam reads a parameter: name, *name or **name, optionally annotated
+// and with a default value.
+func newParam(toks []token) param {
+	var p param
+	i := 0
+	for i < len(toks) && (toks[i].is("*") || toks[i].is("**") || toks[i].is("/")) {
+		p.name += toks[i].text
+		i++
+	}
+	if i < len(toks) && toks[i].kind == tokName {
+		p.name += toks[i].text
+		i++
+	}
+	rest := toks[i:]
+	eq := len(rest)
+	depth := 0
+	for j, t := range rest {
+		switch {
+		case t.is("(") || t.is("[") || t.is("{"):
+			depth++
+		case t.is(")") || t.is("]") || t.is("}"):
+			depth--
+		case t.is("=") && depth == 0:
+			eq = j
+		}
+		if eq < len(rest) {
+			break
+		}
+	}
+	if len(rest) > 0 && rest[0].is(":") {
+		p.annotation = joinTokens(rest[1:eq])
+	}
+	if eq < len(rest) {
+		p.value = joinTokens(rest[eq+1:])
+	}
+	return p
+}
+
+// stringValue returns the contents of a string literal, without its prefix
+// and quotes. Escape sequences are left as written.
+func stringValue(lit string) string {
+	lit = lit[max(stringPrefix(lit), 0):]
+	quote := lit[:1]
+	if strings.HasPrefix(lit, strings.Repeat(quote, 3)) && len(lit) >= 6 {
+		quote = strings.Repeat(quote, 3)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(lit, quote), quote)
+}
+
+// cleanDoc removes the indentation of the lines of a docstring, but for
+// the first, and the blank lines around them, as inspect.cleandoc does.
+func cleanDoc(doc string) string {
+	lines := strings.Split(strings.ReplaceAll(doc, "\t", "        "), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " "); trimmed != "" {
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	lines[0] = strings.TrimSpace(lines[0])
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) >= indent && indent > 0 {
+			lines[i] = lines[i][indent:]
+		}
+		lines[i] = strings.TrimRight(lines[i], " \r")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinTokens prints toks on a single line, spaced as in PEP 8.
+func joinTokens(toks []token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && needSpace(toks[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func needSpace(prev, t token) bool {
+	word := func(t token) bool { return t.kind == tokName || t.kind == tokNumber || t.kind == tokString }
+	binary := func(t token) bool { return t.is("|") || t.is("->") || t.is("==") || t.is(":=") }
+	switch {
+	case word(prev) && word(t):
+		return true
+	case prev.is(",") || prev.is(":") || binary(prev) || binary(t):
+		return true
+	}
+	return false
+}
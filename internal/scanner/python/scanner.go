@@ -1,13 +1,13 @@
 package python
 
 import (
-	"bufio"
-	"bytes"
-	"regexp"
+	"context"
+	"errors"
+	"path/filepath"
 	"strings"
 
 	"github.com/chinmay-sawant/gosourcemapper/internal/models"
-	"github.com/google/uuid"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
 )
 
 type PythonScanner struct{}
@@ -16,92 +16,224 @@ func NewPythonScanner() *PythonScanner {
 	return &PythonScanner{}
 }
 
-func (s *PythonScanner) Scan(filePath string, content []byte) ([]*models.CodeNode, error) {
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	var nodes []*models.CodeNode
+// Scan returns a node for every class and function of the file, methods and
+// nested functions included, named after the definitions enclosing them:
+// Service.fetch, handler.inner. Calls made through requests, urllib and
+// httpx get an HTTP_CALL node each, and commands run through subprocess.run,
+// os.system and exec a CMD_EXEC node.
+func (s *PythonScanner) Scan(ctx context.Context, root, filePath string, content []byte) ([]*models.CodeNode, error) {
+	decls, toks, err := parse(string(content))
+	var syntax *syntaxError
+	if errors.As(err, &syntax) {
+		return nil, &scanner.ParseError{File: filePath, Line: syntax.line, Column: syntax.col, Msg: syntax.msg}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	reDef := regexp.MustCompile(`^def\s+(\w+)`)
-	reClass := regexp.MustCompile(`^class\s+(\w+)`)
-	reHTTP := regexp.MustCompile(`(requests\.(get|post|put|delete|patch)|urllib|httpx)`)
-	reCMD := regexp.MustCompile(`(subprocess\.run|os\.system|exec)`)
+	// Python has no package declaration; the module is named after the file.
+	module := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	f := &file{path: filePath, toks: toks, ids: models.NewIDAllocator("python", module, models.IDPath(root, filePath))}
+	for _, d := range decls {
+		f.declNodes(d)
+	}
+	f.calls()
+	return f.nodes, nil
+}
 
-	lineNumber := 0
-	var comments []string
+// file gathers the nodes of a parsed source file.
+type file struct {
+	path  string
+	toks  []token
+	ids   *models.IDAllocator
+	nodes []*models.CodeNode
+	// decls are the definitions found so far.
+	decls []*decl
+}
+
+// declNodes adds the nodes of d and of the definitions nested in it.
+func (f *file) declNodes(d *decl) {
+	meta := map[string]interface{}{
+		"end_line":   d.endLine,
+		"decorators": decoratorTexts(d),
+	}
+	if d.doc != "" {
+		meta["docstring"] = d.doc
+	}
+	node := &models.CodeNode{
+		Name:       d.scope,
+		Language:   "python",
+		FilePath:   f.path,
+		LineNumber: d.line,
+		Comments:   f.comments(d.start),
+		Metadata:   meta,
+	}
+	if d.kind == "class" {
+		node.Type = models.NodeClass
+		if len(d.bases) > 0 {
+			meta["bases"] = d.bases
+		}
+	} else {
+		node.Type = models.NodeFunction
+		node.Signature = d.signature()
+		params := []models.Param{}
+		for _, p := range d.params {
+			if p.name != "*" && p.name != "/" {
+				params = append(params, models.Param{Name: p.name, Type: p.annotation})
+			}
+		}
+		results := []models.Param{}
+		if d.returns != "" {
+			results = append(results, models.Param{Type: d.returns})
+		}
+		meta["params"] = params
+		meta["results"] = results
+		meta["async"] = d.async
+		if d.isMethod() {
+			meta["receiver"] = d.owner.scope
+		}
+	}
+	node.ID = f.ids.ID(node.Name, node.Type)
+	f.nodes = append(f.nodes, node)
+	f.decls = append(f.decls, d)
+
+	for _, nested := range d.decls {
+		f.declNodes(nested)
+	}
+}
+
+func decoratorTexts(d *decl) []string {
+	texts := []string{}
+	for _, dec := range d.decorators {
+		texts = append(texts, dec.text)
+	}
+	return texts
+}
 
-	for scanner.Scan() {
-		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+// comments returns the comments of the definition starting at token start,
+// closest first: those among its decorators, and those right above it, up
+// to the first blank line. Adjacent comment lines form one entry, without
+// the # markers.
+func (f *file) comments(start int) []string {
+	lead := f.toks[start].comments
+	line, k := f.toks[start].line, len(lead)
+	for k > 0 && lead[k-1].line == line-1 {
+		k--
+		line = lead[k].line
+	}
+	cs := append([]comment(nil), lead[k:]...)
+	for i := start + 1; i < len(f.toks) && !f.toks[i].is("def") && !f.toks[i].is("class"); i++ {
+		cs = append(cs, f.toks[i].comments...)
+	}
 
-		if strings.HasPrefix(line, "#") {
-			comments = append(comments, line)
+	var groups [][]string
+	for i, c := range cs {
+		text := strings.TrimSpace(strings.TrimPrefix(c.text, "#"))
+		if i > 0 && c.line == cs[i-1].line+1 {
+			groups[len(groups)-1] = append(groups[len(groups)-1], text)
 			continue
 		}
+		groups = append(groups, []string{text})
+	}
+	var texts []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		texts = append(texts, strings.Join(groups[i], "\n")+"\n")
+	}
+	return texts
+}
 
-		if match := reClass.FindStringSubmatch(line); len(match) > 1 {
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       models.NodeClass,
-				Name:       match[1],
-				Language:   "python",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-				Comments:   cloneAndReverse(comments),
-			})
-			comments = nil
+// requestsVerbs are the functions of the requests package sending a request.
+var requestsVerbs = map[string]bool{
+	"get": true, "post": true, "put": true, "delete": true, "patch": true,
+}
+
+// commands are the functions running a command.
+var commands = map[string]bool{
+	"subprocess.run": true, "os.system": true, "exec": true,
+}
+
+// calls adds the HTTP_CALL and CMD_EXEC nodes of the file. Import statements
+// name the modules without calling them.
+func (f *file) calls() {
+	type site struct {
+		line int
+		name string
+		typ  models.NodeType
+	}
+	seen := make(map[site]bool)
+	for i := 0; i < len(f.toks); i++ {
+		t := f.toks[i]
+		if f.statementStart(i) && (t.is("import") || t.is("from")) {
+			for i < len(f.toks) && f.toks[i].kind != tokNewline {
+				i++
+			}
 			continue
 		}
-
-		if match := reDef.FindStringSubmatch(line); len(match) > 1 {
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       models.NodeFunction,
-				Name:       match[1],
-				Language:   "python",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-				Comments:   cloneAndReverse(comments),
-			})
-			comments = nil
+		if t.kind != tokName || i > 0 && f.toks[i-1].is(".") {
 			continue
 		}
-
-		if match := reHTTP.FindStringSubmatch(line); len(match) > 1 {
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       models.NodeHTTPCall,
-				Name:       match[1], // e.g. requests.get
-				Language:   "python",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-			})
+		parts := []string{t.text}
+		for j := i + 1; j+1 < len(f.toks) && f.toks[j].is(".") && f.toks[j+1].kind == tokName; j += 2 {
+			parts = append(parts, f.toks[j+1].text)
 		}
 
-		if match := reCMD.FindStringSubmatch(line); len(match) > 1 {
-			nodes = append(nodes, &models.CodeNode{
-				ID:         uuid.New().String(),
-				Type:       "CMD_EXEC", // Custom type for Python CMD
-				Name:       match[1],
-				Language:   "python",
-				FilePath:   filePath,
-				LineNumber: lineNumber,
-			})
+		var s site
+		switch dotted := strings.Join(parts, "."); {
+		case parts[0] == "requests" && len(parts) > 1 && requestsVerbs[parts[1]]:
+			s = site{name: "requests." + parts[1], typ: models.NodeHTTPCall}
+		case parts[0] == "urllib" || parts[0] == "httpx":
+			s = site{name: parts[0], typ: models.NodeHTTPCall}
+		case dotted == "exec" && !f.toks[i+1].is("("):
+			continue
+		case commands[dotted]:
+			s = site{name: dotted, typ: "CMD_EXEC"}
+		default:
+			continue
+		}
+		if s.line = t.line; seen[s] {
+			continue
 		}
+		seen[s] = true
 
-		if line != "" {
-			comments = nil
+		caller := ""
+		if d := f.declAt(i); d != nil {
+			caller = d.scope
 		}
+		f.nodes = append(f.nodes, &models.CodeNode{
+			ID:         f.ids.ID(caller+">"+s.name, s.typ),
+			Type:       s.typ,
+			Name:       s.name, // e.g. requests.get
+			Language:   "python",
+			FilePath:   f.path,
+			LineNumber: t.line,
+			Metadata:   map[string]interface{}{"caller": caller},
+		})
 	}
-
-	return nodes, nil
 }
 
-func cloneAndReverse(input []string) []string {
-	if len(input) == 0 {
-		return nil
+// statementStart reports whether token i starts a statement.
+func (f *file) statementStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	switch prev := f.toks[i-1]; prev.kind {
+	case tokNewline, tokIndent, tokDedent:
+		return true
+	default:
+		return prev.is(";")
 	}
-	output := make([]string, len(input))
-	for i, v := range input {
-		output[len(input)-1-i] = v
+}
+
+// declAt returns the innermost definition holding token i, if any.
+func (f *file) declAt(i int) *decl {
+	var inner *decl
+	for _, d := range f.decls {
+		if i >= d.start && i < d.end && (inner == nil || d.end-d.start < inner.end-inner.start) {
+			inner = d
+		}
 	}
-	return output
+	return inner
 }
@@ -0,0 +1,247 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want lists the nodes in the order they are returned, in
+		// "TYPE name line [receiver]" form, or "TYPE name line (caller)" for
+		// calls.
+		want []string
+	}{
+		{
+			name: "methods nested in classes",
+			src: `import requests
+
+
+class Service(Base, metaclass=ABCMeta):
+    """Talks to the orders service."""
+
+    timeout = 3
+
+    def fetch(self, order_id: int) -> dict:
+        return requests.get(f"{BASE}/orders/{order_id}").json()
+
+    @staticmethod
+    async def ping():
+        pass
+
+    class Config:
+        def load(self):
+            def parse(raw):
+                return raw
+            return parse
+
+
+def helper():
+    requests.post(URL, json={})
+`,
+			want: []string{
+				"CLASS Service 4",
+				"FUNCTION Service.fetch 9 [Service]",
+				"FUNCTION Service.ping 13 [Service]",
+				"CLASS Service.Config 16",
+				"FUNCTION Service.Config.load 17 [Service.Config]",
+				"FUNCTION Service.Config.load.parse 18",
+				"FUNCTION helper 23",
+				"HTTP_CALL requests.get 10 (Service.fetch)",
+				"HTTP_CALL requests.post 24 (helper)",
+			},
+		},
+		{
+			name: "blocks that are not definitions",
+			src: `if DEBUG:
+    def debug_handler():
+        pass
+else:
+    try:
+        import httpx
+    except ImportError:
+        httpx = None
+
+for name in ("a", "b"):
+    pass
+
+def after():
+    text = """
+def not_a_function():
+    pass
+"""
+    os.system("ls")
+    return (1,
+  2)
+`,
+			want: []string{
+				"FUNCTION debug_handler 2",
+				"FUNCTION after 13",
+				"HTTP_CALL httpx 8 ()",
+				"CMD_EXEC os.system 18 (after)",
+			},
+		},
+		{
+			name: "tabs and line continuations",
+			src:  "class A:\n\tdef f(self, \\\n\t\t\tx):\n\t\turllib.request.urlopen(x)\n\n\tdef g(self): subprocess.run(['ls']); exec('x')\n",
+			want: []string{
+				"CLASS A 1",
+				"FUNCTION A.f 2 [A]",
+				"FUNCTION A.g 6 [A]",
+				"HTTP_CALL urllib 4 (A.f)",
+				"CMD_EXEC subprocess.run 6 (A.g)",
+				"CMD_EXEC exec 6 (A.g)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := NewPythonScanner().Scan(context.Background(), "", "service.py", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range nodes {
+				s := fmt.Sprintf("%s %s %d", n.Type, n.Name, n.LineNumber)
+				if r, ok := n.Metadata["receiver"]; ok {
+					s += fmt.Sprintf(" [%s]", r)
+				}
+				if c, ok := n.Metadata["caller"]; ok {
+					s += fmt.Sprintf(" (%s)", c)
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("nodes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestScanMetadata(t *testing.T) {
+	src := `# The service entry point.
+# Runs forever.
+@app.get("/items/{id}")
+async def get_item(id: int, *, q: str | None = None, **extra) -> Item:
+    """
+    Return the item.
+
+        Indented detail.
+    """
+    return Item()
+`
+	nodes, err := NewPythonScanner().Scan(context.Background(), "", "app.py", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("%d nodes, want 1", len(nodes))
+	}
+	n := nodes[0]
+	tests := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"signature", n.Signature, "async def get_item(id: int, *, q: str | None = None, **extra) -> Item"},
+		{"comments", strings.Join(n.Comments, "|"), "The service entry point.\nRuns forever.\n"},
+		{"line", n.LineNumber, 4},
+		{"end_line", n.Metadata["end_line"], 10},
+		{"decorators", fmt.Sprint(n.Metadata["decorators"]), `[@app.get("/items/{id}")]`},
+		{"docstring", n.Metadata["docstring"], "Return the item.\n\n    Indented detail."},
+		{"params", fmt.Sprint(n.Metadata["params"]), fmt.Sprint([]models.Param{{Name: "id", Type: "int"}, {Name: "q", Type: "str | None"}, {Name: "**extra"}})},
+		{"results", fmt.Sprint(n.Metadata["results"]), fmt.Sprint([]models.Param{{Type: "Item"}})},
+		{"async", n.Metadata["async"], true},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestScanSyntaxError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"unterminated string", "def f():\n    return 'open\n", 2},
+		{"inconsistent dedent", "class A:\n        def f(self):\n            pass\n    def g(self):\n        pass\n", 4},
+		{"unclosed bracket", "x = 0\ny = [1,\n  2\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPythonScanner().Scan(context.Background(), "", "bad.py", []byte(tt.src))
+			var perr *scanner.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("error = %v, want a *scanner.ParseError", err)
+			}
+			if perr.File != "bad.py" || perr.Line != tt.line {
+				t.Errorf("error at %s:%d, want bad.py:%d", perr.File, perr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestScanTruncated(t *testing.T) {
+	// Editors save files half-written: every prefix of a module must scan or
+	// fail with a ParseError.
+	src := `import requests
+
+@app.route("/items", methods=["GET"])
+@login_required
+async def items(q: str = "x", *args, **kw) -> list[int]:
+    """Items."""
+    return requests.get(URL + q)
+
+
+class Service(Base):
+    @staticmethod
+    def ping(): pass
+
+    @property
+    def name(self) -> str:
+        if self._name:
+            return self._name
+        return (
+            "anonymous"
+        )
+`
+	for i := 0; i <= len(src); i++ {
+		_, err := NewPythonScanner().Scan(context.Background(), "", "t.py", []byte(src[:i]))
+		var perr *scanner.ParseError
+		if err != nil && !errors.As(err, &perr) {
+			t.Fatalf("prefix %q: error = %v, want a *scanner.ParseError", src[:i], err)
+		}
+	}
+
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"@", 1},
+		{"class A:\n    @staticmethod\n", 3},
+		{"@app.get('/x')\nx = 1\n", 2},
+		{"def f() -> int", 1},
+	}
+	for _, tt := range tests {
+		_, err := NewPythonScanner().Scan(context.Background(), "", "t.py", []byte(tt.src))
+		var perr *scanner.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("Scan(%q) error = %v, want a *scanner.ParseError", tt.src, err)
+			continue
+		}
+		if perr.Line != tt.line {
+			t.Errorf("Scan(%q) error at line %d, want %d", tt.src, perr.Line, tt.line)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// BoundaryLinker discovers the services and modules of a tree from their
+// manifests (go.mod, pom.xml, build.gradle, pyproject.toml, setup.py, and
+// Makefiles where nothing else applies). It adds SERVICE and MODULE nodes,
+// tags every node with the "service" and "module" it belongs to, and links
+// them with DEFINED_IN edges (node -> module -> service) and IMPORTS edges
+// between modules of the tree that depend on each other.
+type BoundaryLinker struct{}
+
+func NewBoundaryLinker() *BoundaryLinker {
+	return &BoundaryLinker{}
+}
+
+// kindLanguage is the language recorded on module and service nodes.
+var kindLanguage = map[string]string{
+	KindGo:     "go",
+	KindMaven:  "java",
+	KindGradle: "java",
+	KindPython: "python",
+}
+
+func (l *BoundaryLinker) Link(ctx context.Context, tree scanner.Tree, nodes []*models.CodeNode) (*models.Graph, error) {
+	lay, err := detect(tree.FS, tree.Root)
+	if err != nil {
+		return nil, err
+	}
+	if len(lay.modules) == 0 {
+		return nil, nil
+	}
+
+	graph := &models.Graph{}
+	serviceNodes := make(map[*Service]*models.CodeNode)
+	moduleNodes := make(map[*Module]*models.CodeNode)
+	byName := make(map[string]*models.CodeNode)
+	for _, svc := range lay.services {
+		root := svc.Modules[0]
+		node := newBoundaryNode(tree, models.NodeService, svc.Name, root, map[string]interface{}{
+			"root":    filepath.Join(tree.Root, filepath.FromSlash(svc.Root)),
+			"modules": len(svc.Modules),
+		})
+		serviceNodes[svc] = node
+		graph.Nodes = append(graph.Nodes, node)
+		for _, m := range svc.Modules {
+			node := newBoundaryNode(tree, models.NodeModule, m.Name, m, map[string]interface{}{
+				"root":     filepath.Join(tree.Root, filepath.FromSlash(m.Root)),
+				"requires": m.Requires,
+			})
+			moduleNodes[m] = node
+			byName[m.Name] = node
+			graph.Nodes = append(graph.Nodes, node)
+			addEdge(graph, node, serviceNodes[svc], models.EdgeDefinedIn, nil)
+		}
+	}
+
+	// Dependencies on other modules of the tree; Gradle refers to projects by
+	// directory name, so those are looked up by the last element of the root.
+	for _, m := range lay.modules {
+		for _, req := range m.Requires {
+			target, ok := byName[req]
+			if !ok && m.Kind == KindGradle {
+				target = gradleProject(lay, req, moduleNodes)
+			}
+			if target != nil && target != moduleNodes[m] {
+				addEdge(graph, moduleNodes[m], target, models.EdgeImports, nil)
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		rel, err := filepath.Rel(tree.Root, n.FilePath)
+		if err != nil {
+			continue
+		}
+		m := lay.moduleOf(filepath.ToSlash(rel))
+		if m == nil {
+			continue
+		}
+		tag(n, m)
+		addEdge(graph, n, moduleNodes[m], models.EdgeDefinedIn, nil)
+	}
+	for _, n := range graph.Nodes {
+		rel, _ := filepath.Rel(tree.Root, n.FilePath)
+		tag(n, lay.moduleOf(filepath.ToSlash(rel)))
+	}
+	return graph, nil
+}
+
+// newBoundaryNode creates the node of a service or module, located at the
+// manifest of m.
+func newBoundaryNode(tree scanner.Tree, kind models.NodeType, name string, m *Module, meta map[string]interface{}) *models.CodeNode {
+	filePath := filepath.Join(tree.Root, filepath.FromSlash(m.Manifest))
+	meta["kind"] = m.Kind
+	language := kindLanguage[m.Kind]
+	return &models.CodeNode{
+		ID:         models.NewNodeID(language, m.Name, m.Manifest, name, kind),
+		Type:       kind,
+		Name:       name,
+		Language:   language,
+		FilePath:   filePath,
+		LineNumber: 1,
+		Metadata:   meta,
+	}
+}
+
+// tag records the service and module owning n in its metadata.
+func tag(n *models.CodeNode, m *Module) {
+	if m == nil {
+		return
+	}
+	if n.Metadata == nil {
+		n.Metadata = make(map[string]interface{})
+	}
+	n.Metadata["service"] = m.Service.Name
+	n.Metadata["module"] = m.Name
+}
+
+func gradleProject(lay *layout, name string, nodes map[*Module]*models.CodeNode) *models.CodeNode {
+	for _, m := range lay.modules {
+		if m.Kind == KindGradle && filepath.Base(m.Root) == name {
+			return nodes[m]
+		}
+	}
+	return nil
+}
+
+func addEdge(graph *models.Graph, source, target *models.CodeNode, kind models.EdgeKind, attrs map[string]interface{}) {
+	edge := models.NewEdge(source.ID, target.ID, kind)
+	edge.Attributes = attrs
+	graph.Edges = append(graph.Edges, edge)
+}
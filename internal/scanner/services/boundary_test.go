@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+func TestBoundaryLinker(t *testing.T) {
+	tree := newTree(map[string]string{
+		"services/users/go.mod": `module example.com/users
+
+require (
+	example.com/shared v0.0.0
+	github.com/gin-gonic/gin v1.9.1
+)
+`,
+		"services/users/Makefile": "build:\n\tgo build ./...\n",
+		"libs/shared/go.mod":      "module example.com/shared\n",
+		"services/orders/pom.xml": `<project>
+  <groupId>com.acme</groupId>
+  <artifactId>orders</artifactId>
+</project>`,
+		"services/orders/api/pom.xml": `<project>
+  <parent><groupId>com.acme</groupId></parent>
+  <artifactId>orders-api</artifactId>
+  <dependencies>
+    <dependency><groupId>com.acme</groupId><artifactId>orders-model</artifactId></dependency>
+  </dependencies>
+</project>`,
+		"services/orders/model/pom.xml":      "<project><groupId>com.acme</groupId><artifactId>orders-model</artifactId></project>",
+		"services/reports/settings.gradle":   "rootProject.name = 'reports'\ninclude 'core', 'app'\n",
+		"services/reports/build.gradle":      "plugins { id 'java' }\n",
+		"services/reports/core/build.gradle": "plugins { id 'java-library' }\n",
+		"services/reports/app/build.gradle":  "dependencies { implementation project(':core') }\n",
+		"tools/etl/pyproject.toml": `[project]
+name = "etl"
+dependencies = ["requests>=2"]
+`,
+		"scripts/Makefile":          "all:\n",
+		".git/go.mod":               "module hidden\n",
+		"node_modules/pkg/setup.py": "setup(name='vendored')\n",
+	})
+
+	handler := newNode(models.NodeFunction, "getUser", "services/users/handlers/user.go", nil)
+	model := newNode(models.NodeClass, "Order", "services/orders/model/src/main/java/Order.java", map[string]interface{}{})
+	loose := newNode(models.NodeFunction, "main", "docs/example.go", nil)
+	graph, err := NewBoundaryLinker().Link(context.Background(), tree, []*models.CodeNode{handler, model, loose})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byID := make(map[string]*models.CodeNode)
+	for _, n := range append(graph.Nodes, handler, model, loose) {
+		byID[n.ID] = n
+	}
+	// Each service with its modules, by name
+	services := make(map[string][]string)
+	for _, e := range graph.Edges {
+		if e.Kind == models.EdgeDefinedIn && byID[e.Source].Type == models.NodeModule {
+			svc := byID[e.Target]
+			services[svc.Name] = append(services[svc.Name], byID[e.Source].Name)
+		}
+	}
+	for _, modules := range services {
+		sort.Strings(modules)
+	}
+	wantServices := map[string]string{
+		"users":   "example.com/users",
+		"shared":  "example.com/shared",
+		"orders":  "com.acme:orders, com.acme:orders-api, com.acme:orders-model",
+		"reports": "app, core, reports",
+		"etl":     "etl",
+		"scripts": "scripts",
+	}
+	for name, want := range wantServices {
+		if got := strings.Join(services[name], ", "); got != want {
+			t.Errorf("service %s has modules %q, want %q", name, got, want)
+		}
+	}
+	for name := range services {
+		if _, ok := wantServices[name]; !ok {
+			t.Errorf("unexpected service %s", name)
+		}
+	}
+
+	var imports, owners []string
+	for _, e := range graph.Edges {
+		switch src, dst := byID[e.Source], byID[e.Target]; {
+		case e.Kind == models.EdgeImports:
+			imports = append(imports, src.Name+" -> "+dst.Name)
+		case e.Kind == models.EdgeDefinedIn && dst.Type == models.NodeModule && src.Type != models.NodeModule:
+			owners = append(owners, src.Name+" -> "+dst.Name)
+		}
+	}
+	sort.Strings(imports)
+	if got, want := strings.Join(imports, "\n"), "app -> core\ncom.acme:orders-api -> com.acme:orders-model\nexample.com/users -> example.com/shared"; got != want {
+		t.Errorf("imports:\n%s\nwant:\n%s", got, want)
+	}
+	sort.Strings(owners)
+	if got, want := strings.Join(owners, "\n"), "Order -> com.acme:orders-model\ngetUser -> example.com/users"; got != want {
+		t.Errorf("owners:\n%s\nwant:\n%s", got, want)
+	}
+
+	tests := []struct {
+		node            *models.CodeNode
+		service, module string
+	}{
+		{handler, "users", "example.com/users"},
+		{model, "orders", "com.acme:orders-model"},
+		{loose, "", ""},
+	}
+	for _, tt := range tests {
+		service, _ := tt.node.Metadata["service"].(string)
+		module, _ := tt.node.Metadata["module"].(string)
+		if service != tt.service || module != tt.module {
+			t.Errorf("%s tagged %q/%q, want %q/%q", tt.node.Name, service, module, tt.service, tt.module)
+		}
+	}
+	for _, n := range graph.Nodes {
+		if n.Metadata["service"] == nil || n.Metadata["module"] == nil {
+			t.Errorf("%s %s is not tagged", n.Type, n.Name)
+		}
+	}
+}
+
+func TestBoundaryLinkerRootService(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"go module path", map[string]string{"go.mod": "module github.com/acme/inventory\n"}, "inventory"},
+		{"maven coordinates", map[string]string{"pom.xml": "<project><groupId>com.acme</groupId><artifactId>billing</artifactId></project>"}, "billing"},
+		{"unnamed manifest", map[string]string{"Makefile": "all:\n"}, "repo"},
+		{"makefile beside a module", map[string]string{"Makefile": "all:\n", "api/go.mod": "module api\n"}, "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := NewBoundaryLinker().Link(context.Background(), newTree(tt.files), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range graph.Nodes {
+				if n.Type == models.NodeService {
+					got = append(got, n.Name)
+				}
+			}
+			if strings.Join(got, ", ") != tt.want {
+				t.Errorf("services = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
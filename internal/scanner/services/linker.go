@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+	"github.com/chinmay-sawant/gosourcemapper/internal/utils"
+)
+
+// Confidence of a CALLS_SERVICE edge, by how the target service was found.
+const (
+	confidenceURL      = 0.95 // the call URL names the service's host and port
+	confidenceEnv      = 0.85 // the base URL is the default of a matching env var
+	confidencePathOnly = 0.6  // only path and method match; split between candidates
+	confidenceNameHint = 0.15 // added when the call's names mention the service
+	penaltyAnyMethod   = 0.05 // subtracted when the route accepts every method
+)
+
+// ServiceLinker matches outbound HTTP_CALL nodes to the ROUTE nodes of the
+// other services in the tree, adding CALLS_SERVICE edges with a confidence
+// score. Base URLs are resolved through the defaults of ENV_VAR nodes, such as
+// ORDER_SERVICE_URL=http://localhost:8081, and services are told apart by the
+// port their PORT variable defaults to. It runs after the language linkers,
+// which derive the URL templates and environment variables it relies on.
+type ServiceLinker struct{}
+
+func NewServiceLinker() *ServiceLinker {
+	return &ServiceLinker{}
+}
+
+// serviceInfo gathers what the linker knows about one service.
+type serviceInfo struct {
+	*Service
+	port    string
+	urlEnvs []*models.CodeNode // variables whose default is a URL
+	routes  []*models.CodeNode
+}
+
+func (l *ServiceLinker) Link(ctx context.Context, tree scanner.Tree, nodes []*models.CodeNode) (*models.Graph, error) {
+	lay, err := detect(tree.FS, tree.Root)
+	if err != nil {
+		return nil, err
+	}
+	if len(lay.services) < 2 {
+		return nil, nil
+	}
+
+	infos := make(map[string]*serviceInfo)
+	serviceOf := func(n *models.CodeNode) *serviceInfo {
+		rel, err := filepath.Rel(tree.Root, n.FilePath)
+		if err != nil {
+			return nil
+		}
+		svc := lay.serviceOf(filepath.ToSlash(rel))
+		if svc == nil {
+			return nil
+		}
+		info, ok := infos[svc.Root]
+		if !ok {
+			info = &serviceInfo{Service: svc}
+			infos[svc.Root] = info
+		}
+		return info
+	}
+
+	var calls []*models.CodeNode
+	for _, n := range nodes {
+		info := serviceOf(n)
+		if info == nil {
+			continue
+		}
+		switch n.Type {
+		case models.NodeRoute:
+			info.routes = append(info.routes, n)
+		case models.NodeHTTPCall:
+			calls = append(calls, n)
+		case models.NodeEnvVar:
+			def, _ := n.Metadata["default"].(string)
+			switch {
+			case isPortVar(n.Name) && isPort(def):
+				info.port = strings.TrimPrefix(def, ":")
+			case strings.Contains(def, "://"):
+				info.urlEnvs = append(info.urlEnvs, n)
+			}
+		}
+	}
+
+	graph := &models.Graph{}
+	for _, call := range calls {
+		if from := serviceOf(call); from != nil {
+			l.linkCall(graph, call, from, infos)
+		}
+	}
+	return graph, nil
+}
+
+// target is a resolved destination of a call.
+type target struct {
+	service    *serviceInfo
+	baseURL    string
+	env        string
+	confidence float64
+	resolution string
+}
+
+func (l *ServiceLinker) linkCall(graph *models.Graph, call *models.CodeNode, from *serviceInfo, infos map[string]*serviceInfo) {
+	tmpl, _ := call.Metadata["url"].(string)
+	base, path := splitURL(tmpl)
+	if path == "" {
+		return
+	}
+	method, _ := call.Metadata["method"].(string)
+	if strings.Contains(method, "{") {
+		method = "" // not a constant
+	}
+	hints := callTokens(call, base)
+
+	var targets []target
+	switch {
+	case strings.Contains(base, "://"):
+		if svc := byAddress(base, from, infos); svc != nil {
+			targets = append(targets, target{service: svc, baseURL: base, confidence: confidenceURL, resolution: "url"})
+		}
+	case base != "":
+		if env := matchEnv(from.urlEnvs, hints); env != nil {
+			def := env.Metadata["default"].(string)
+			if svc := byAddress(def, from, infos); svc != nil {
+				targets = append(targets, target{service: svc, baseURL: def, env: env.Name, confidence: confidenceEnv, resolution: "env_default"})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		// Unknown base URL: any other service serving the path may be meant.
+		var candidates []*serviceInfo
+		for _, info := range sortedInfos(infos) {
+			if info != from && bestRoute(info.routes, method, path) != nil {
+				candidates = append(candidates, info)
+			}
+		}
+		for _, svc := range candidates {
+			c := confidencePathOnly / float64(len(candidates))
+			if overlaps(hints, tokens(svc.Name)) {
+				c += confidenceNameHint
+			}
+			targets = append(targets, target{service: svc, confidence: c, resolution: "path_only"})
+		}
+	}
+
+	for _, t := range targets {
+		route := bestRoute(t.service.routes, method, path)
+		if route == nil {
+			continue
+		}
+		confidence := t.confidence
+		if m, _ := route.Metadata["method"].(string); m == "ANY" {
+			confidence -= penaltyAnyMethod
+		}
+		attrs := map[string]interface{}{
+			"confidence": math.Round(confidence*100) / 100,
+			"service":    t.service.Name,
+			"path":       strings.SplitN(path, "?", 2)[0],
+			"resolution": t.resolution,
+		}
+		if t.baseURL != "" {
+			attrs["base_url"] = t.baseURL
+			call.Metadata["resolved_url"] = strings.TrimSuffix(t.baseURL, "/") + path
+		}
+		if t.env != "" {
+			attrs["env"] = t.env
+		}
+		edge := models.NewEdge(call.ID, route.ID, models.EdgeCallsService)
+		edge.Attributes = attrs
+		graph.Edges = append(graph.Edges, edge)
+	}
+}
+
+// splitURL separates a URL template into its base and its path:
+// "{c.baseURL}/v1/orders?id={id}" gives "{c.baseURL}" and "/v1/orders?id={id}".
+// The path is empty when the template does not contain one.
+func splitURL(tmpl string) (base, path string) {
+	switch {
+	case strings.Contains(tmpl, "://"):
+		scheme := strings.Index(tmpl, "://") + len("://")
+		i := strings.IndexByte(tmpl[scheme:], '/')
+		if i < 0 {
+			return tmpl, ""
+		}
+		base, path = tmpl[:scheme+i], tmpl[scheme+i:]
+	case strings.HasPrefix(tmpl, "{"):
+		i := strings.IndexByte(tmpl, '}')
+		if i < 0 {
+			return tmpl, ""
+		}
+		base, path = tmpl[:i+1], tmpl[i+1:]
+	default:
+		path = tmpl
+	}
+	if !strings.HasPrefix(path, "/") {
+		return base, ""
+	}
+	return base, path
+}
+
+// byAddress finds the service a base URL points to: by port for local hosts,
+// by name for hosts such as "order-service" in container networks.
+func byAddress(rawURL string, from *serviceInfo, infos map[string]*serviceInfo) *serviceInfo {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host, port := u.Hostname(), u.Port()
+	for _, info := range sortedInfos(infos) {
+		if info == from {
+			continue
+		}
+		switch host {
+		case "localhost", "127.0.0.1", "0.0.0.0", "::1", "":
+			if port != "" && port == info.port {
+				return info
+			}
+		default:
+			if strings.EqualFold(host, info.Name) {
+				return info
+			}
+		}
+	}
+	return nil
+}
+
+// matchEnv picks the URL variable whose name shares the most words with the
+// call, e.g. ORDER_SERVICE_URL for s.orderClient.Get(...).
+func matchEnv(envs []*models.CodeNode, hints map[string]bool) *models.CodeNode {
+	var best *models.CodeNode
+	bestScore, tie := 0, false
+	for _, env := range envs {
+		score := 0
+		for t := range tokens(env.Name) {
+			if hints[t] {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = env, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return best
+}
+
+// bestRoute returns the most specific route serving method and path.
+func bestRoute(routes []*models.CodeNode, method, path string) *models.CodeNode {
+	var best *models.CodeNode
+	bestScore := -1
+	for _, r := range routes {
+		m, _ := r.Metadata["method"].(string)
+		p, _ := r.Metadata["path"].(string)
+		if method != "" && m != "ANY" && !strings.EqualFold(m, method) {
+			continue
+		}
+		if !utils.MatchRoutePath(p, path) {
+			continue
+		}
+		if score := utils.RouteSpecificity(p); score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
+
+// callTokens returns the words naming a call's target: those of the wrapper
+// receiver (s.orderClient) and of the base URL expression.
+func callTokens(call *models.CodeNode, base string) map[string]bool {
+	words := tokens(base)
+	if receiver, ok := call.Metadata["receiver"].(string); ok {
+		for t := range tokens(receiver) {
+			words[t] = true
+		}
+	}
+	return words
+}
+
+// genericWords carry no information about which service is meant.
+var genericWords = map[string]bool{
+	"service": true, "svc": true, "client": true, "url": true, "uri": true, "base": true,
+	"http": true, "https": true, "api": true, "host": true, "addr": true, "address": true,
+	"endpoint": true, "server": true, "c": true, "s": true, "h": true,
+}
+
+// tokens splits identifiers into lower-case words: "s.orderClient" and
+// "ORDER_SERVICE_URL" both contain "order".
+func tokens(s string) map[string]bool {
+	words := make(map[string]bool)
+	var cur []rune
+	flush := func() {
+		if w := strings.ToLower(string(cur)); w != "" && !genericWords[w] {
+			words[w] = true
+		}
+		cur = cur[:0]
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+func overlaps(a, b map[string]bool) bool {
+	for w := range a {
+		if b[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func isPortVar(name string) bool {
+	switch strings.ToUpper(name) {
+	case "PORT", "HTTP_PORT", "SERVER_PORT", "APP_PORT", "LISTEN_PORT":
+		return true
+	}
+	return false
+}
+
+func isPort(s string) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, ":"))
+	return err == nil && n > 0 && n < 65536
+}
+
+func sortedInfos(infos map[string]*serviceInfo) []*serviceInfo {
+	sorted := make([]*serviceInfo, 0, len(infos))
+	for _, info := range infos {
+		sorted = append(sorted, info)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Root < sorted[j].Root })
+	return sorted
+}
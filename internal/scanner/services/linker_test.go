@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+const treeRoot = "/repo"
+
+// newTree returns a tree rooted at treeRoot holding files, keyed by
+// slash-separated path.
+func newTree(files map[string]string) scanner.Tree {
+	fsys := make(fstest.MapFS)
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return scanner.Tree{FS: fsys, Root: treeRoot}
+}
+
+// newNode returns a node of the file at the slash-separated path file,
+// identified by the file and its name.
+func newNode(typ models.NodeType, name, file string, meta map[string]interface{}) *models.CodeNode {
+	return &models.CodeNode{
+		ID:       file + ">" + name,
+		Type:     typ,
+		Name:     name,
+		FilePath: path.Join(treeRoot, file),
+		Metadata: meta,
+	}
+}
+
+func route(method, p, file string) *models.CodeNode {
+	return newNode(models.NodeRoute, method+" "+p, file, map[string]interface{}{"method": method, "path": p})
+}
+
+func env(name, def, file string) *models.CodeNode {
+	return newNode(models.NodeEnvVar, name, file, map[string]interface{}{"default": def})
+}
+
+func TestServiceLinker(t *testing.T) {
+	tree := newTree(map[string]string{
+		"users/go.mod":  "module example.com/users\n",
+		"orders/go.mod": "module example.com/orders\n",
+		"billing/pom.xml": `<project>
+  <groupId>com.acme</groupId>
+  <artifactId>billing</artifactId>
+</project>`,
+	})
+	nodes := []*models.CodeNode{
+		env("PORT", "8080", "users/main.go"),
+		env("ORDER_SERVICE_URL", "http://localhost:8081", "users/config.go"),
+		route("GET", "/users/{id}", "users/main.go"),
+		env("PORT", ":8081", "orders/main.go"),
+		route("GET", "/orders/:id", "orders/main.go"),
+		route("GET", "/orders/new", "orders/main.go"),
+		route("ANY", "/health", "orders/main.go"),
+		route("POST", "/invoices", "billing/src/main/java/Billing.java"),
+		route("ANY", "/health", "billing/src/main/java/Billing.java"),
+	}
+	tests := []struct {
+		name string
+		file string
+		meta map[string]interface{}
+		// want lists the edges of the call, in
+		// "service route resolution confidence" form.
+		want        []string
+		resolvedURL string
+	}{
+		{
+			name:        "local URL",
+			file:        "users/client.go",
+			meta:        map[string]interface{}{"method": "GET", "url": "http://localhost:8081/orders/42"},
+			want:        []string{"orders GET /orders/:id url 0.95"},
+			resolvedURL: "http://localhost:8081/orders/42",
+		},
+		{
+			name:        "most specific route",
+			file:        "users/client.go",
+			meta:        map[string]interface{}{"method": "GET", "url": "http://localhost:8081/orders/new?x=1"},
+			want:        []string{"orders GET /orders/new url 0.95"},
+			resolvedURL: "http://localhost:8081/orders/new?x=1",
+		},
+		{
+			name:        "base URL from an environment variable",
+			file:        "users/client.go",
+			meta:        map[string]interface{}{"method": "GET", "url": "{s.baseURL}/orders/42", "receiver": "s.orderClient"},
+			want:        []string{"orders GET /orders/:id env_default 0.85"},
+			resolvedURL: "http://localhost:8081/orders/42",
+		},
+		{
+			name:        "host named after the service",
+			file:        "orders/client.go",
+			meta:        map[string]interface{}{"method": "GET", "url": "http://users:8080/users/7"},
+			want:        []string{"users GET /users/{id} url 0.95"},
+			resolvedURL: "http://users:8080/users/7",
+		},
+		{
+			name: "path served by several services",
+			file: "users/client.go",
+			meta: map[string]interface{}{"method": "GET", "url": "{cfg.Endpoint}/health"},
+			want: []string{"billing ANY /health path_only 0.25", "orders ANY /health path_only 0.25"},
+		},
+		{
+			name: "path and name hint",
+			file: "users/client.go",
+			meta: map[string]interface{}{"url": "{billingURL}/invoices"},
+			want: []string{"billing POST /invoices path_only 0.75"},
+		},
+		{
+			name: "method mismatch",
+			file: "users/client.go",
+			meta: map[string]interface{}{"method": "DELETE", "url": "{billingURL}/invoices"},
+		},
+		{
+			name: "own routes",
+			file: "users/client.go",
+			meta: map[string]interface{}{"method": "GET", "url": "http://localhost:8080/users/1"},
+		},
+		{
+			name: "no path",
+			file: "users/client.go",
+			meta: map[string]interface{}{"method": "GET", "url": "{target}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := newNode(models.NodeHTTPCall, "call", tt.file, tt.meta)
+			graph, err := NewServiceLinker().Link(context.Background(), tree, append(nodes[:len(nodes):len(nodes)], call))
+			if err != nil {
+				t.Fatal(err)
+			}
+			byID := make(map[string]*models.CodeNode)
+			for _, n := range nodes {
+				byID[n.ID] = n
+			}
+			var got []string
+			for _, e := range graph.Edges {
+				if e.Kind != models.EdgeCallsService || e.Source != call.ID {
+					t.Errorf("unexpected edge %s %s -> %s", e.Kind, e.Source, e.Target)
+					continue
+				}
+				a := e.Attributes
+				if dir := path.Join(treeRoot, a["service"].(string)) + "/"; !strings.HasPrefix(byID[e.Target].FilePath, dir) {
+					t.Errorf("edge to %s, outside service %s", byID[e.Target].FilePath, a["service"])
+				}
+				got = append(got, fmt.Sprintf("%s %s %s %v", a["service"], byID[e.Target].Name, a["resolution"], a["confidence"]))
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("edges:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if got, _ := call.Metadata["resolved_url"].(string); got != tt.resolvedURL {
+				t.Errorf("resolved_url = %q, want %q", got, tt.resolvedURL)
+			}
+		})
+	}
+}
+
+func TestServiceLinkerSingleService(t *testing.T) {
+	tree := newTree(map[string]string{"go.mod": "module example.com/app\n"})
+	nodes := []*models.CodeNode{
+		route("GET", "/items", "main.go"),
+		newNode(models.NodeHTTPCall, "call", "client.go", map[string]interface{}{"method": "GET", "url": "/items"}),
+	}
+	graph, err := NewServiceLinker().Link(context.Background(), tree, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph != nil && len(graph.Edges) > 0 {
+		t.Errorf("edges within a single service: %v", graph.Edges)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/xml"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/mod/modfile"
+)
+
+// Module kinds, by the manifest that defines them.
+const (
+	KindGo     = "go"
+	KindMaven  = "maven"
+	KindGradle = "gradle"
+	KindPython = "python"
+	KindMake   = "make"
+)
+
+// manifestKinds maps manifest file names to the kind of module they define.
+// Makefiles only mark a boundary where no other manifest does.
+var manifestKinds = map[string]string{
+	"go.mod":           KindGo,
+	"pom.xml":          KindMaven,
+	"build.gradle":     KindGradle,
+	"build.gradle.kts": KindGradle,
+	"pyproject.toml":   KindPython,
+	"setup.py":         KindPython,
+	"Makefile":         KindMake,
+	"makefile":         KindMake,
+	"GNUmakefile":      KindMake,
+}
+
+// IsManifest reports whether a file of that name may define a service or
+// module boundary, so that changing it can move nodes between them.
+func IsManifest(name string) bool {
+	_, ok := manifestKinds[name]
+	return ok || name == "settings.gradle" || name == "settings.gradle.kts"
+}
+
+// manifest is what a manifest file says about its module.
+type manifest struct {
+	name     string   // module path, e.g. "user-service" or "com.acme:orders"
+	requires []string // names of modules it depends on
+}
+
+// parseManifest reads a manifest. file is its slash path within the tree; the
+// directory name is used when the manifest does not name the module.
+func parseManifest(kind, file string, data []byte) manifest {
+	dir := path.Base(path.Dir(file))
+	var m manifest
+	switch kind {
+	case KindGo:
+		m = parseGoMod(file, data)
+	case KindMaven:
+		m = parsePOM(data)
+	case KindGradle:
+		m = parseGradle(data)
+	case KindPython:
+		m = parsePython(path.Base(file), data)
+	}
+	if m.name == "" {
+		m.name = dir
+	}
+	return m
+}
+
+func parseGoMod(file string, data []byte) manifest {
+	f, err := modfile.ParseLax(file, data, nil)
+	if err != nil || f.Module == nil {
+		return manifest{}
+	}
+	m := manifest{name: f.Module.Mod.Path}
+	for _, r := range f.Require {
+		m.requires = append(m.requires, r.Mod.Path)
+	}
+	return m
+}
+
+type pom struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+	} `xml:"parent"`
+	Dependencies []struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+	} `xml:"dependencies>dependency"`
+}
+
+func parsePOM(data []byte) manifest {
+	var p pom
+	if err := xml.Unmarshal(data, &p); err != nil || p.ArtifactID == "" {
+		return manifest{}
+	}
+	group := p.GroupID
+	if group == "" {
+		group = p.Parent.GroupID
+	}
+	m := manifest{name: mavenName(group, p.ArtifactID)}
+	for _, d := range p.Dependencies {
+		m.requires = append(m.requires, mavenName(d.GroupID, d.ArtifactID))
+	}
+	return m
+}
+
+func mavenName(group, artifact string) string {
+	if group == "" {
+		return artifact
+	}
+	return group + ":" + artifact
+}
+
+var (
+	gradleProjectDep = regexp.MustCompile(`project\(\s*(?:path\s*[:=]\s*)?["']:?([\w\-.:]+)["']\s*\)`)
+	gradleRootName   = regexp.MustCompile(`rootProject\.name\s*=\s*["']([\w\-.]+)["']`)
+)
+
+// parseGradle reads a build script; the project is named after its directory
+// unless the script or settings.gradle next to it names it. Project
+// dependencies are recorded by the last element of their project path.
+func parseGradle(data []byte) manifest {
+	var m manifest
+	if match := gradleRootName.FindSubmatch(data); match != nil {
+		m.name = string(match[1])
+	}
+	for _, match := range gradleProjectDep.FindAllSubmatch(data, -1) {
+		m.requires = append(m.requires, gradleProjectName(string(match[1])))
+	}
+	return m
+}
+
+// gradleSettingsName returns the root project name set in settings.gradle.
+func gradleSettingsName(data []byte) string {
+	if match := gradleRootName.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// gradleProjectName returns the last element of a Gradle project path (":libs:core" gives "core").
+func gradleProjectName(p string) string {
+	return p[strings.LastIndex(p, ":")+1:]
+}
+
+type pyproject struct {
+	Project struct {
+		Name         string   `toml:"name"`
+		Dependencies []string `toml:"dependencies"`
+	} `toml:"project"`
+	Tool struct {
+		Poetry struct {
+			Name         string                 `toml:"name"`
+			Dependencies map[string]interface{} `toml:"dependencies"`
+		} `toml:"poetry"`
+	} `toml:"tool"`
+}
+
+var (
+	setupName   = regexp.MustCompile(`name\s*=\s*["']([\w\-.]+)["']`)
+	requirement = regexp.MustCompile(`^\s*([A-Za-z0-9][\w\-.]*)`)
+)
+
+func parsePython(base string, data []byte) manifest {
+	if base == "setup.py" {
+		if match := setupName.FindSubmatch(data); match != nil {
+			return manifest{name: string(match[1])}
+		}
+		return manifest{}
+	}
+	var p pyproject
+	if err := toml.Unmarshal(data, &p); err != nil {
+		return manifest{}
+	}
+	m := manifest{name: p.Project.Name}
+	for _, dep := range p.Project.Dependencies {
+		if match := requirement.FindStringSubmatch(dep); match != nil {
+			m.requires = append(m.requires, match[1])
+		}
+	}
+	if m.name == "" {
+		m.name = p.Tool.Poetry.Name
+		for dep := range p.Tool.Poetry.Dependencies {
+			if dep != "python" {
+				m.requires = append(m.requires, dep)
+			}
+		}
+	}
+	return m
+}
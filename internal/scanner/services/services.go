@@ -0,0 +1,198 @@
+package services
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// Module is a unit defined by a manifest: a Go module, a Maven or Gradle
+// project, a Python package, or a directory with only a Makefile.
+type Module struct {
+	Name     string // module path, e.g. "user-service" or "com.acme:orders"
+	Kind     string // KindGo, KindMaven, ...
+	Root     string // slash-separated directory within the tree, "." for the tree itself
+	Manifest string // slash-separated path of the manifest within the tree
+	Requires []string
+	Service  *Service
+}
+
+// Service is a separately built unit of a scanned tree: a module together
+// with the subprojects of its build.
+type Service struct {
+	Name    string
+	Root    string
+	Modules []*Module // the root module first
+}
+
+// Module kinds in order of precedence, for directories with several manifests.
+var kindOrder = []string{KindGo, KindMaven, KindGradle, KindPython, KindMake}
+
+// layout is the module and service structure of a tree.
+type layout struct {
+	modules  []*Module // deepest roots first, so lookups find the innermost module
+	services []*Service
+}
+
+// detect finds the modules of a tree by their manifests and groups them into
+// services. treeRoot names the service at the root of the tree, if any.
+func detect(fsys fs.FS, treeRoot string) (*layout, error) {
+	found := make(map[string]map[string]string) // dir -> kind -> manifest
+	gradleNames := make(map[string]string)      // dir -> name from settings.gradle
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && scanner.SkipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dir := path.Dir(p)
+		if d.Name() == "settings.gradle" || d.Name() == "settings.gradle.kts" {
+			if data, err := fs.ReadFile(fsys, p); err == nil {
+				gradleNames[dir] = gradleSettingsName(data)
+			}
+			return nil
+		}
+		if kind, ok := manifestKinds[d.Name()]; ok {
+			if found[dir] == nil {
+				found[dir] = make(map[string]string)
+			}
+			if _, dup := found[dir][kind]; !dup {
+				found[dir][kind] = p
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &layout{}
+	for dir, kinds := range found {
+		for _, kind := range kindOrder {
+			file, ok := kinds[kind]
+			if !ok {
+				continue
+			}
+			if kind == KindMake && hasModuleAround(found, dir) {
+				break
+			}
+			data, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			m := parseManifest(kind, file, data)
+			if kind == KindGradle && gradleNames[dir] != "" && m.name == path.Base(dir) {
+				m.name = gradleNames[dir]
+			}
+			sort.Strings(m.requires)
+			l.modules = append(l.modules, &Module{Name: m.name, Kind: kind, Root: dir, Manifest: file, Requires: m.requires})
+			break
+		}
+	}
+	sort.Slice(l.modules, func(i, j int) bool {
+		if di, dj := depth(l.modules[i].Root), depth(l.modules[j].Root); di != dj {
+			return di > dj
+		}
+		return l.modules[i].Root < l.modules[j].Root
+	})
+
+	// Multi-project Maven and Gradle builds form one service; any other
+	// module, such as a go.mod nested in a monorepo, is a service of its own.
+	for i := len(l.modules) - 1; i >= 0; i-- {
+		m := l.modules[i]
+		if outer := l.enclosing(m.Root); outer != nil && jvmBuild(outer.Kind) && jvmBuild(m.Kind) {
+			m.Service = outer.Service
+		} else {
+			name := path.Base(m.Root)
+			if m.Root == "." {
+				name = rootName(treeRoot, m.Name)
+			}
+			m.Service = &Service{Name: name, Root: m.Root}
+			l.services = append(l.services, m.Service)
+		}
+		m.Service.Modules = append(m.Service.Modules, m)
+	}
+	sort.Slice(l.services, func(i, j int) bool { return l.services[i].Root < l.services[j].Root })
+	return l, nil
+}
+
+// hasModuleAround reports whether a directory other than dir, above or below
+// it, holds a manifest other than a Makefile.
+func hasModuleAround(found map[string]map[string]string, dir string) bool {
+	for other, kinds := range found {
+		if other == dir || (len(kinds) == 1 && kinds[KindMake] != "") {
+			continue
+		}
+		if within(other, dir) || within(dir, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosing returns the innermost module strictly containing dir.
+func (l *layout) enclosing(dir string) *Module {
+	for _, m := range l.modules {
+		if m.Root != dir && within(dir, m.Root) {
+			return m
+		}
+	}
+	return nil
+}
+
+// moduleOf returns the innermost module containing the file at fsPath, or nil.
+func (l *layout) moduleOf(fsPath string) *Module {
+	for _, m := range l.modules {
+		if within(fsPath, m.Root) {
+			return m
+		}
+	}
+	return nil
+}
+
+// serviceOf returns the service containing the file at fsPath, or nil.
+func (l *layout) serviceOf(fsPath string) *Service {
+	if m := l.moduleOf(fsPath); m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func jvmBuild(kind string) bool {
+	return kind == KindMaven || kind == KindGradle
+}
+
+// within reports whether p is root or lies below it.
+func within(p, root string) bool {
+	return root == "." || p == root || strings.HasPrefix(p, root+"/")
+}
+
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// rootName names a service at the root of the tree after the last element of
+// its module path, or after the tree's directory.
+func rootName(treeRoot, module string) string {
+	if i := strings.LastIndexAny(module, "/:"); i >= 0 && i < len(module)-1 {
+		return module[i+1:]
+	}
+	if module != "" && module != "." {
+		return module
+	}
+	if abs, err := filepath.Abs(treeRoot); err == nil {
+		return filepath.Base(abs)
+	}
+	return filepath.Base(treeRoot)
+}
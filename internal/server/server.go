@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/handlers"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/chinmay-sawant/gosourcemapper/internal/router"
+	"github.com/chinmay-sawant/gosourcemapper/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take once the server is stopping.
+const shutdownTimeout = 10 * time.Second
+
+// ConfigureLogging installs the default slog logger and picks the Gin mode for cfg.
+func ConfigureLogging(cfg config.LogConfig) {
+	level, _ := config.ParseLevel(cfg.Level)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	if level > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
+
+// Run wires the repository, service, handlers and router together and serves
+// the API until ctx is cancelled. With watch mode on, it also keeps the graph
+// of the watched directory current meanwhile.
+func Run(ctx context.Context, cfg *config.Config) error {
+	repo, err := repository.Open(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	if closer, ok := repo.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				slog.Error("close graph store", "err", err)
+			}
+		}()
+	}
+	jobs := service.NewJobManager(cfg.Jobs)
+	defer jobs.Close()
+	projectService := service.NewProjectService(repo, cfg.Scan)
+	projectHandler := handlers.NewProjectHandler(projectService)
+	scanHandler := handlers.NewScanHandler(projectService, jobs, cfg.Scan)
+	jobHandler := handlers.NewJobHandler(jobs)
+	var watcher service.Watcher
+	if cfg.Watch.Dir != "" {
+		watcher = service.NewWatcher(projectService, cfg.Watch)
+	}
+	watchHandler := handlers.NewWatchHandler(watcher)
+	diffHandler := handlers.NewDiffHandler(service.NewDiffService(projectService, cfg.Scan))
+
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: router.SetupRouter(projectHandler, scanHandler, jobHandler, watchHandler, diffHandler),
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", cfg.Server.Addr, "temp_dir", cfg.Scan.TempDir, "storage", cfg.Storage.Driver)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	// The watcher writes to the graph, so it is stopped before the store is closed.
+	var watching sync.WaitGroup
+	defer watching.Wait()
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	watchErr := make(chan error, 1)
+	if watcher != nil {
+		watching.Add(1)
+		go func() {
+			defer watching.Done()
+			slog.Info("watching", "dir", cfg.Watch.Dir, "project", watcher.Status().Project, "debounce", cfg.Watch.Debounce)
+			if err := watcher.Run(ctx); err != nil {
+				watchErr <- fmt.Errorf("watch mode: %w", err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case err := <-errCh:
+		return err
+	case runErr = <-watchErr:
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+// DiffService compares graphs, to tell what changed architecturally between
+// two versions of the code: endpoints, calls between services, signatures.
+// Nodes and edges are matched by ID, which is derived from the path of a
+// symbol's file relative to the root of its scan, so graphs scanned from
+// different checkouts or uploads of the same code compare equal.
+type DiffService interface {
+	// DiffProjects compares the graphs of two projects.
+	DiffProjects(from, to string) (*GraphDiff, error)
+	// DiffRefs scans two commits of a git repository, as ScanService.ScanGit
+	// does but without storing them, and compares their graphs.
+	DiffRefs(ctx context.Context, repo, from, to string) (*GraphDiff, error)
+}
+
+// GraphDiff is what changed from one graph to another, grouped by service.
+type GraphDiff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// FromCommit and ToCommit are the SHAs of the commits compared, if any.
+	FromCommit string        `json:"from_commit,omitempty"`
+	ToCommit   string        `json:"to_commit,omitempty"`
+	Summary    DiffSummary   `json:"summary"`
+	Services   []ServiceDiff `json:"services"`
+}
+
+// DiffSummary counts the changes of a GraphDiff.
+type DiffSummary struct {
+	NodesAdded    int `json:"nodes_added"`
+	NodesRemoved  int `json:"nodes_removed"`
+	NodesModified int `json:"nodes_modified"`
+	EdgesAdded    int `json:"edges_added"`
+	EdgesRemoved  int `json:"edges_removed"`
+	EdgesModified int `json:"edges_modified"`
+}
+
+// ServiceDiff holds the changes to the nodes of one service, and to the edges
+// starting at them. Service is empty for nodes outside any service.
+type ServiceDiff struct {
+	Service       string             `json:"service"`
+	AddedNodes    []*models.CodeNode `json:"added_nodes"`
+	RemovedNodes  []*models.CodeNode `json:"removed_nodes"`
+	ModifiedNodes []NodeChange       `json:"modified_nodes"`
+	AddedEdges    []DiffEdge         `json:"added_edges"`
+	RemovedEdges  []DiffEdge         `json:"removed_edges"`
+	ModifiedEdges []EdgeChange       `json:"modified_edges"`
+}
+
+// NodeChange is a node found in both graphs with different details. Changed
+// names what differs: "type", "name", "signature", "comments",
+// "dependencies" or "metadata.<key>". Moving within its file does not count.
+type NodeChange struct {
+	From    *models.CodeNode `json:"from"`
+	To      *models.CodeNode `json:"to"`
+	Changed []string         `json:"changed"`
+}
+
+// DiffEdge is an edge along with the names of the nodes it joins.
+type DiffEdge struct {
+	*models.Edge
+	SourceName string `json:"source_name"`
+	TargetName string `json:"target_name"`
+}
+
+// EdgeChange is an edge found in both graphs with different attributes,
+// named in Changed as "attributes.<key>".
+type EdgeChange struct {
+	From    DiffEdge `json:"from"`
+	To      DiffEdge `json:"to"`
+	Changed []string `json:"changed"`
+}
+
+// Empty reports whether nothing changed.
+func (d *GraphDiff) Empty() bool {
+	return d.Summary == DiffSummary{}
+}
+
+// ignoredMetadata lists the metadata keys that tell where a graph comes from
+// rather than what it holds: the commit scanned and the directory of a
+// service or module on disk.
+var ignoredMetadata = map[string]bool{"commit": true, "root": true}
+
+// DiffGraphs compares two graphs, matching their nodes and edges by ID.
+func DiffGraphs(from, to *models.Graph) *GraphDiff {
+	fromNodes, toNodes := nodeIndex(from.Nodes), nodeIndex(to.Nodes)
+	services := make(map[string]*ServiceDiff)
+	serviceOf := func(n *models.CodeNode) *ServiceDiff {
+		name := metaString(n, "service")
+		sd, ok := services[name]
+		if !ok {
+			sd = &ServiceDiff{
+				Service:       name,
+				AddedNodes:    []*models.CodeNode{},
+				RemovedNodes:  []*models.CodeNode{},
+				ModifiedNodes: []NodeChange{},
+				AddedEdges:    []DiffEdge{},
+				RemovedEdges:  []DiffEdge{},
+				ModifiedEdges: []EdgeChange{},
+			}
+			services[name] = sd
+		}
+		return sd
+	}
+
+	d := &GraphDiff{Services: []ServiceDiff{}}
+	for _, n := range to.Nodes {
+		prev, ok := fromNodes[n.ID]
+		if !ok {
+			sd := serviceOf(n)
+			sd.AddedNodes = append(sd.AddedNodes, n)
+			d.Summary.NodesAdded++
+			continue
+		}
+		if changed := nodeChanges(prev, n); len(changed) > 0 {
+			sd := serviceOf(n)
+			sd.ModifiedNodes = append(sd.ModifiedNodes, NodeChange{From: prev, To: n, Changed: changed})
+			d.Summary.NodesModified++
+		}
+	}
+	for _, n := range from.Nodes {
+		if _, ok := toNodes[n.ID]; !ok {
+			sd := serviceOf(n)
+			sd.RemovedNodes = append(sd.RemovedNodes, n)
+			d.Summary.NodesRemoved++
+		}
+	}
+
+	fromEdges, toEdges := edgeIndex(from.Edges), edgeIndex(to.Edges)
+	for _, e := range to.Edges {
+		de := diffEdge(e, toNodes)
+		prev, ok := fromEdges[e.ID]
+		if !ok {
+			sd := serviceOf(sourceNode(e, toNodes))
+			sd.AddedEdges = append(sd.AddedEdges, de)
+			d.Summary.EdgesAdded++
+			continue
+		}
+		if changed := mapChanges("attributes", prev.Attributes, e.Attributes); len(changed) > 0 {
+			sd := serviceOf(sourceNode(e, toNodes))
+			sd.ModifiedEdges = append(sd.ModifiedEdges, EdgeChange{From: diffEdge(prev, fromNodes), To: de, Changed: changed})
+			d.Summary.EdgesModified++
+		}
+	}
+	for _, e := range from.Edges {
+		if _, ok := toEdges[e.ID]; !ok {
+			sd := serviceOf(sourceNode(e, fromNodes))
+			sd.RemovedEdges = append(sd.RemovedEdges, diffEdge(e, fromNodes))
+			d.Summary.EdgesRemoved++
+		}
+	}
+
+	for _, sd := range services {
+		sortDiffNodes(sd.AddedNodes)
+		sortDiffNodes(sd.RemovedNodes)
+		sort.SliceStable(sd.ModifiedNodes, func(i, j int) bool {
+			return lessDiffNode(sd.ModifiedNodes[i].To, sd.ModifiedNodes[j].To)
+		})
+		sortDiffEdges(sd.AddedEdges)
+		sortDiffEdges(sd.RemovedEdges)
+		sort.SliceStable(sd.ModifiedEdges, func(i, j int) bool {
+			return lessDiffEdge(sd.ModifiedEdges[i].To, sd.ModifiedEdges[j].To)
+		})
+		d.Services = append(d.Services, *sd)
+	}
+	sort.Slice(d.Services, func(i, j int) bool { return d.Services[i].Service < d.Services[j].Service })
+	return d
+}
+
+func nodeIndex(nodes []*models.CodeNode) map[string]*models.CodeNode {
+	index := make(map[string]*models.CodeNode, len(nodes))
+	for _, n := range nodes {
+		index[n.ID] = n
+	}
+	return index
+}
+
+func edgeIndex(edges []*models.Edge) map[string]*models.Edge {
+	index := make(map[string]*models.Edge, len(edges))
+	for _, e := range edges {
+		index[e.ID] = e
+	}
+	return index
+}
+
+// sourceNode returns the node e starts at, or an empty node when it is not
+// in the graph.
+func sourceNode(e *models.Edge, nodes map[string]*models.CodeNode) *models.CodeNode {
+	if n, ok := nodes[e.Source]; ok {
+		return n
+	}
+	return &models.CodeNode{}
+}
+
+func diffEdge(e *models.Edge, nodes map[string]*models.CodeNode) DiffEdge {
+	de := DiffEdge{Edge: e, SourceName: e.Source, TargetName: e.Target}
+	if n, ok := nodes[e.Source]; ok {
+		de.SourceName = n.Name
+	}
+	if n, ok := nodes[e.Target]; ok {
+		de.TargetName = n.Name
+	}
+	return de
+}
+
+// nodeChanges lists what differs between two versions of a node.
+func nodeChanges(from, to *models.CodeNode) []string {
+	var changed []string
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{"type", from.Type, to.Type},
+		{"name", from.Name, to.Name},
+		{"signature", from.Signature, to.Signature},
+		{"comments", from.Comments, to.Comments},
+		{"dependencies", from.Dependencies, to.Dependencies},
+	}
+	for _, f := range fields {
+		if !sameValue(f.from, f.to) {
+			changed = append(changed, f.name)
+		}
+	}
+	return append(changed, mapChanges("metadata", from.Metadata, to.Metadata)...)
+}
+
+// mapChanges lists the keys whose values differ between two maps of node
+// metadata or edge attributes, prefixed with the name of the map.
+func mapChanges(name string, from, to map[string]interface{}) []string {
+	var changed []string
+	for k, v := range to {
+		if w, ok := from[k]; (!ok || !sameValue(v, w)) && !ignoredMetadata[k] {
+			changed = append(changed, name+"."+k)
+		}
+	}
+	for k := range from {
+		if _, ok := to[k]; !ok && !ignoredMetadata[k] {
+			changed = append(changed, name+"."+k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// sameValue compares values by their JSON form, so that a graph decoded from
+// JSON compares equal to the graph it was encoded from.
+func sameValue(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	// Nil and empty lists both stand for "none"
+	empty := func(j []byte) bool { return string(j) == "null" || string(j) == "[]" }
+	return bytes.Equal(ja, jb) || empty(ja) && empty(jb)
+}
+
+func lessDiffNode(a, b *models.CodeNode) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.FilePath < b.FilePath
+}
+
+func sortDiffNodes(nodes []*models.CodeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool { return lessDiffNode(nodes[i], nodes[j]) })
+}
+
+func lessDiffEdge(a, b DiffEdge) bool {
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.SourceName != b.SourceName {
+		return a.SourceName < b.SourceName
+	}
+	return a.TargetName < b.TargetName
+}
+
+func sortDiffEdges(edges []DiffEdge) {
+	sort.SliceStable(edges, func(i, j int) bool { return lessDiffEdge(edges[i], edges[j]) })
+}
+
+// WriteMarkdown writes d as a Markdown report, one section per service.
+func (d *GraphDiff) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Graph diff: %s → %s\n\n", diffSide(d.From, d.FromCommit), diffSide(d.To, d.ToCommit))
+	if d.Empty() {
+		b.WriteString("No changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	s := d.Summary
+	b.WriteString("| | Added | Removed | Modified |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| Nodes | %d | %d | %d |\n", s.NodesAdded, s.NodesRemoved, s.NodesModified)
+	fmt.Fprintf(&b, "| Edges | %d | %d | %d |\n", s.EdgesAdded, s.EdgesRemoved, s.EdgesModified)
+
+	for _, sd := range d.Services {
+		name := sd.Service
+		if name == "" {
+			name = "Outside any service"
+		}
+		fmt.Fprintf(&b, "\n## %s\n", name)
+		if len(sd.AddedNodes)+len(sd.RemovedNodes)+len(sd.ModifiedNodes) > 0 {
+			b.WriteString("\n### Nodes\n\n")
+			for _, n := range sd.AddedNodes {
+				fmt.Fprintf(&b, "- **added** %s\n", markdownNode(n))
+			}
+			for _, n := range sd.RemovedNodes {
+				fmt.Fprintf(&b, "- **removed** %s\n", markdownNode(n))
+			}
+			for _, c := range sd.ModifiedNodes {
+				fmt.Fprintf(&b, "- **modified** %s: %s\n", markdownNode(c.To), strings.Join(c.Changed, ", "))
+				if slices.Contains(c.Changed, "signature") {
+					fmt.Fprintf(&b, "  - `%s` → `%s`\n", c.From.Signature, c.To.Signature)
+				}
+			}
+		}
+		if len(sd.AddedEdges)+len(sd.RemovedEdges)+len(sd.ModifiedEdges) > 0 {
+			b.WriteString("\n### Edges\n\n")
+			for _, e := range sd.AddedEdges {
+				fmt.Fprintf(&b, "- **added** %s\n", markdownEdge(e))
+			}
+			for _, e := range sd.RemovedEdges {
+				fmt.Fprintf(&b, "- **removed** %s\n", markdownEdge(e))
+			}
+			for _, c := range sd.ModifiedEdges {
+				fmt.Fprintf(&b, "- **modified** %s: %s\n", markdownEdge(c.To), strings.Join(c.Changed, ", "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func diffSide(name, commit string) string {
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit == "" || commit == name {
+		return fmt.Sprintf("`%s`", name)
+	}
+	return fmt.Sprintf("`%s` (%s)", name, commit)
+}
+
+func markdownNode(n *models.CodeNode) string {
+	return fmt.Sprintf("%s `%s` (%s:%d)", n.Type, n.Name, n.FilePath, n.LineNumber)
+}
+
+func markdownEdge(e DiffEdge) string {
+	return fmt.Sprintf("%s `%s` → `%s`", e.Kind, e.SourceName, e.TargetName)
+}
+
+type diffService struct {
+	projects ProjectService
+	cfg      config.ScanConfig
+}
+
+func NewDiffService(projects ProjectService, cfg config.ScanConfig) DiffService {
+	return &diffService{projects: projects, cfg: cfg}
+}
+
+func (s *diffService) DiffProjects(from, to string) (*GraphDiff, error) {
+	var graphs [2]*models.Graph
+	for i, id := range []string{from, to} {
+		scans, err := s.projects.Scans(id)
+		if err != nil {
+			return nil, err
+		}
+		graphs[i] = &models.Graph{Nodes: scans.GetAllNodes(), Edges: scans.GetAllEdges()}
+	}
+	d := DiffGraphs(graphs[0], graphs[1])
+	d.From, d.To = from, to
+	return d, nil
+}
+
+func (s *diffService) DiffRefs(ctx context.Context, repo, from, to string) (*GraphDiff, error) {
+	var results [2]*ScanResult
+	for i, ref := range []string{from, to} {
+		scans := NewScanService(repository.NewInMemoryGraphRepository(), s.cfg)
+		result, err := scans.ScanGit(ctx, repo, ref, ScanOptions{Strict: s.cfg.Strict}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", refName(ref), err)
+		}
+		results[i] = result
+	}
+	d := DiffGraphs(
+		&models.Graph{Nodes: results[0].Nodes, Edges: results[0].Edges},
+		&models.Graph{Nodes: results[1].Nodes, Edges: results[1].Edges},
+	)
+	d.From, d.To = refName(from), refName(to)
+	d.FromCommit, d.ToCommit = results[0].Commit, results[1].Commit
+	return d, nil
+}
+
+// refName names ref, an empty ref meaning HEAD.
+func refName(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+// testTree is a small multi-language project: a Go service serving and
+// calling routes, a Java controller and a Python script.
+var testTree = map[string]string{
+	"orders/go.mod": "module example.com/orders\n\ngo 1.23\n",
+	"orders/main.go": `package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// listOrders returns every order.
+func listOrders(w http.ResponseWriter, r *http.Request) {
+	http.Get(os.Getenv("USERS_URL") + "/users")
+}
+
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders", listOrders)
+	http.ListenAndServe(":8080", mux)
+}
+`,
+	"users/go.mod": "module example.com/users\n\ngo 1.23\n",
+	"users/main.go": `package main
+
+import "net/http"
+
+func listUsers(w http.ResponseWriter, r *http.Request) {}
+
+func main() {
+	http.HandleFunc("/users", listUsers)
+	http.ListenAndServe(":8081", nil)
+}
+`,
+	"billing/src/main/java/com/example/Billing.java": `package com.example;
+
+@RestController
+public class Billing {
+    @GetMapping("/invoices")
+    public String list() {
+        return "";
+    }
+}
+`,
+	"tools/report.py": `import requests
+
+def report():
+    return requests.get("http://orders/orders")
+`,
+}
+
+// writeTree writes files, keyed by slash-separated path, under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// scanTree scans a fresh copy of files into a new in-memory graph.
+func scanTree(t *testing.T, files map[string]string) (ScanService, *ScanResult) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	result, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, nil)
+	if err != nil {
+		t.Fatalf("ScanDirectory: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("ScanDirectory errors: %v", result.Errors)
+	}
+	return scans, result
+}
+
+func TestDiffGraphsOfCopiesIsEmpty(t *testing.T) {
+	from, _ := scanTree(t, testTree)
+	to, _ := scanTree(t, testTree)
+	d := DiffGraphs(
+		&models.Graph{Nodes: from.GetAllNodes(), Edges: from.GetAllEdges()},
+		&models.Graph{Nodes: to.GetAllNodes(), Edges: to.GetAllEdges()},
+	)
+	if len(from.GetAllNodes()) == 0 || len(from.GetAllEdges()) == 0 {
+		t.Fatal("fixture scanned to an empty graph")
+	}
+	if !d.Empty() {
+		t.Errorf("diff of two copies of a tree = %+v, want empty", d.Summary)
+	}
+}
+
+func TestDiffGraphs(t *testing.T) {
+	changed := make(map[string]string, len(testTree))
+	for name, content := range testTree {
+		changed[name] = content
+	}
+	delete(changed, "tools/report.py")
+	changed["users/main.go"] = `package main
+
+import "net/http"
+
+func listUsers(w http.ResponseWriter, r *http.Request, limit int) {}
+
+func main() {
+	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) { listUsers(w, r, 10) })
+	http.ListenAndServe(":8081", nil)
+}
+`
+	from, _ := scanTree(t, testTree)
+	to, _ := scanTree(t, changed)
+	d := DiffGraphs(
+		&models.Graph{Nodes: from.GetAllNodes(), Edges: from.GetAllEdges()},
+		&models.Graph{Nodes: to.GetAllNodes(), Edges: to.GetAllEdges()},
+	)
+
+	removed := make(map[string]bool)
+	modified := make(map[string][]string)
+	for _, sd := range d.Services {
+		for _, n := range sd.RemovedNodes {
+			removed[n.Name] = true
+		}
+		for _, c := range sd.ModifiedNodes {
+			modified[c.To.Name] = c.Changed
+		}
+	}
+	for _, name := range []string{"report", "requests.get"} {
+		if !removed[name] {
+			t.Errorf("node %s not reported removed; removed: %v", name, removed)
+		}
+	}
+	if got := modified["listUsers"]; len(got) == 0 || got[0] != "signature" {
+		t.Errorf("listUsers changes = %v, want signature first", got)
+	}
+	if d.Summary.NodesAdded != 0 {
+		t.Errorf("NodesAdded = %d, want 0", d.Summary.NodesAdded)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import "github.com/chinmay-sawant/gosourcemapper/internal/models"
+
+// Phases of a directory scan, in order.
+const (
+	PhaseScanning = "scanning" // walking the tree and parsing the files found
+	PhaseLinking  = "linking"  // resolving relations across files
+	PhaseSaving   = "saving"   // storing the graph
+	PhaseDone     = "done"
+)
+
+// Types of ScanEvent.
+const (
+	// EventPhase starts a new phase of the scan.
+	EventPhase = "phase"
+	// EventFileStarted is sent before a file is parsed.
+	EventFileStarted = "file_started"
+	// EventFileScanned carries the nodes discovered in a file.
+	EventFileScanned = "file_scanned"
+	// EventFileFailed tells why a file could not be scanned; the scan goes on.
+	EventFileFailed = "file_failed"
+	// EventLinked carries the nodes and edges one linker added.
+	EventLinked = "linked"
+	// EventLinkFailed tells why a linker failed; the scan goes on.
+	EventLinkFailed = "link_failed"
+	// EventSummary ends the events of a job, successful or not.
+	EventSummary = "summary"
+
+	// EventFileRemoved carries the nodes the scanner had found in a file
+	// deleted since the last scan, once they are removed.
+	EventFileRemoved = "file_removed"
+	// EventDirScanned carries the outcome of a directory scan run by watch
+	// mode, when it starts and after each batch of changes.
+	EventDirScanned = "dir_scanned"
+)
+
+// Progress counts what a directory scan has done so far.
+type Progress struct {
+	Phase          string `json:"phase"`
+	FilesTotal     int    `json:"files_total"` // known once walking is done
+	FilesProcessed int    `json:"files_processed"`
+	NodesFound     int    `json:"nodes_found"`
+	EdgesFound     int    `json:"edges_found"`
+	Errors         int    `json:"errors"`
+}
+
+// ScanEvent is a step of a running directory scan. Its nodes and edges are
+// copies, which stay as they are while the scan goes on enriching the graph,
+// so events may be read, and encoded, from other goroutines.
+type ScanEvent struct {
+	Type     string             `json:"type"`
+	Progress Progress           `json:"progress"`
+	File     string             `json:"file,omitempty"`
+	Nodes    []*models.CodeNode `json:"nodes,omitempty"`
+	Edges    []*models.Edge     `json:"edges,omitempty"`
+	Linker   string             `json:"linker,omitempty"`
+	// Failure tells why a file or linker was skipped, on EventFileFailed and
+	// EventLinkFailed.
+	Failure *ScanError `json:"failure,omitempty"`
+	// Error tells why the job failed as a whole, on EventSummary.
+	Error string `json:"error,omitempty"`
+	// Timings is set once the scan is done, on the EventPhase of PhaseDone.
+	Timings *Timings `json:"timings,omitempty"`
+	// Status is the final status of the job, set on EventSummary.
+	Status JobStatus `json:"status,omitempty"`
+}
+
+// Timings tells how long each stage of a directory scan took, in
+// milliseconds. Walking and parsing overlap: Parse runs from the start of the
+// walk until the last file is parsed.
+type Timings struct {
+	Walk  int64 `json:"walk_ms"`
+	Parse int64 `json:"parse_ms"`
+	Link  int64 `json:"link_ms"`
+	Store int64 `json:"store_ms"`
+}
+
+// EventFunc receives the events of a scan, in order, from the goroutine
+// running it. A nil EventFunc ignores them.
+type EventFunc func(ScanEvent)
+
+func (f EventFunc) emit(e ScanEvent) {
+	if f != nil {
+		f(e)
+	}
+}
+
+// cloneNodes copies nodes for an event.
+func cloneNodes(nodes []*models.CodeNode) []*models.CodeNode {
+	clones := make([]*models.CodeNode, len(nodes))
+	for i, n := range nodes {
+		clones[i] = n.Clone()
+	}
+	return clones
+}
+
+// cloneEdges copies edges for an event.
+func cloneEdges(edges []*models.Edge) []*models.Edge {
+	clones := make([]*models.Edge, len(edges))
+	for i, e := range edges {
+		clones[i] = e.Clone()
+	}
+	return clones
+}
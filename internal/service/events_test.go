@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+func TestJobEvents(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, testTree)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	m := NewJobManager(config.JobsConfig{Workers: 1, QueueSize: 1})
+	defer m.Close()
+
+	// The job waits for the subscriber to read its first and last events:
+	// those of a job are dropped once it ends, and the subscriber is to see
+	// every one.
+	subscribed, caughtUp := make(chan struct{}), make(chan struct{})
+	job, err := m.Submit("p", JobScanDir, dir, func(ctx context.Context, events EventFunc) (*ScanResult, error) {
+		events(ScanEvent{Type: EventPhase, Progress: Progress{Phase: PhaseScanning}})
+		<-subscribed
+		result, err := scans.ScanDirectory(ctx, dir, ScanOptions{}, events)
+		<-caughtUp
+		return result, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []ScanEvent
+	next := 0
+	err = m.Events(context.Background(), job.ID, 0, func(seq int, e ScanEvent) bool {
+		if seq != next {
+			t.Errorf("event %d received as %d", next, seq)
+		}
+		next++
+		switch {
+		case seq == 0:
+			close(subscribed)
+		case e.Type == EventPhase && e.Progress.Phase == PhaseDone:
+			close(caughtUp)
+		}
+		// Encoding while the scan goes on would race with linkers enriching
+		// nodes, were the events to share them with the graph.
+		if _, err := json.Marshal(e); err != nil {
+			t.Error(err)
+		}
+		events = append(events, e)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	for _, e := range events {
+		counts[e.Type]++
+	}
+	if counts[EventFileScanned] != 4 || counts[EventLinked] == 0 {
+		t.Errorf("event counts = %v, want 4 files scanned and some linked", counts)
+	}
+	if last := events[len(events)-1]; last.Type != EventSummary || last.Status != JobSucceeded {
+		t.Errorf("last event = %s %s, want a summary of success", last.Type, last.Status)
+	}
+
+	// The graph was enriched after the files were scanned; the events kept
+	// the nodes as they were then.
+	for _, e := range events {
+		if e.Type != EventFileScanned {
+			continue
+		}
+		for _, n := range e.Nodes {
+			stored, ok := scans.(*scanService).repo.GetNode(n.ID)
+			if !ok {
+				continue
+			}
+			if stored == n {
+				t.Fatalf("event shares node %s with the graph", n.Name)
+			}
+			if _, tagged := n.Metadata["service"]; tagged {
+				t.Errorf("node %s of a %s event changed by linking", n.Name, e.Type)
+			}
+		}
+	}
+
+	// Once the job ended only its summary is replayed.
+	var late []ScanEvent
+	err = m.Events(context.Background(), job.ID, 0, func(seq int, e ScanEvent) bool {
+		late = append(late, e)
+		return true
+	})
+	if err != nil || len(late) != 1 || late[0].Type != EventSummary {
+		t.Errorf("late subscriber got %d events (%v), want the summary", len(late), err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// NodeFilter selects nodes by their attributes. Empty fields match every node.
+type NodeFilter struct {
+	Type      string `form:"type"`       // exact node type, e.g. FUNCTION
+	Language  string `form:"language"`   // exact language, e.g. go
+	Name      string `form:"name"`       // case-insensitive substring of the name
+	File      string `form:"file"`       // substring of the file path
+	ParamType string `form:"param_type"` // substring of any parameter type, e.g. gin.Context
+	Service   string `form:"service"`    // exact owning service, e.g. order-service
+	Module    string `form:"module"`     // exact owning module path
+}
+
+// Match reports whether n satisfies every field of the filter.
+func (f NodeFilter) Match(n *models.CodeNode) bool {
+	if f.Type != "" && !strings.EqualFold(string(n.Type), f.Type) {
+		return false
+	}
+	if f.Language != "" && !strings.EqualFold(n.Language, f.Language) {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(n.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.File != "" && !strings.Contains(n.FilePath, f.File) {
+		return false
+	}
+	if f.ParamType != "" && !hasParamType(n, f.ParamType) {
+		return false
+	}
+	if f.Service != "" && !strings.EqualFold(metaString(n, "service"), f.Service) {
+		return false
+	}
+	if f.Module != "" && metaString(n, "module") != f.Module {
+		return false
+	}
+	return true
+}
+
+// hasParamType reports whether any parameter of n has a type containing typ.
+// Parameters are []models.Param when scanned and []interface{} once decoded
+// from JSON, so both shapes are handled.
+func hasParamType(n *models.CodeNode, typ string) bool {
+	switch params := n.Metadata["params"].(type) {
+	case []models.Param:
+		for _, p := range params {
+			if strings.Contains(p.Type, typ) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, p := range params {
+			if m, ok := p.(map[string]interface{}); ok {
+				if t, _ := m["type"].(string); strings.Contains(t, typ) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func metaString(n *models.CodeNode, key string) string {
+	s, _ := n.Metadata[key].(string)
+	return s
+}
+
+// Filter returns the nodes matching f.
+func (f NodeFilter) Filter(nodes []*models.CodeNode) []*models.CodeNode {
+	var matched []*models.CodeNode
+	for _, n := range nodes {
+		if f.Match(n) {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
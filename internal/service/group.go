@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// groupKeys lists the attributes nodes can be grouped by.
+var groupKeys = map[string]func(*models.CodeNode) string{
+	"service":  func(n *models.CodeNode) string { return metaString(n, "service") },
+	"module":   func(n *models.CodeNode) string { return metaString(n, "module") },
+	"file":     func(n *models.CodeNode) string { return n.FilePath },
+	"language": func(n *models.CodeNode) string { return n.Language },
+	"type":     func(n *models.CodeNode) string { return string(n.Type) },
+}
+
+// GroupKey returns the function computing the group of a node for one of
+// service, module, file, language or type. Nodes outside any service or
+// module fall in the "" group.
+func GroupKey(by string) (func(*models.CodeNode) string, error) {
+	key, ok := groupKeys[strings.ToLower(by)]
+	if !ok {
+		return nil, fmt.Errorf("cannot group by %q (want service, module, file, language or type)", by)
+	}
+	return key, nil
+}
+
+// GroupNodes partitions nodes by the attribute named by.
+func GroupNodes(nodes []*models.CodeNode, by string) (map[string][]*models.CodeNode, error) {
+	key, err := GroupKey(by)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]*models.CodeNode)
+	for _, n := range nodes {
+		k := key(n)
+		groups[k] = append(groups[k], n)
+	}
+	return groups, nil
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+)
+
+// JobStatus is the state of a background scan.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job kinds.
+const (
+	JobScanDir = "scan_dir"
+	JobScanGit = "scan_git"
+	JobUpload  = "upload"
+)
+
+const (
+	// maxJobErrors caps the file errors kept per job; ErrorCount has the total.
+	maxJobErrors = 100
+	// maxFinishedJobs is how many finished jobs are remembered.
+	maxFinishedJobs = 256
+)
+
+var (
+	// ErrJobNotFound is returned when a job ID is not known.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that already ended.
+	ErrJobFinished = errors.New("job already finished")
+	// ErrQueueFull is returned when no more jobs may wait for a worker.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrJobsClosed is returned when submitting after shutdown began.
+	ErrJobsClosed = errors.New("job manager is shut down")
+)
+
+// Job is a snapshot of a background scan.
+type Job struct {
+	ID             string    `json:"id"`
+	Project        string    `json:"project"`
+	Kind           string    `json:"kind"`
+	Target         string    `json:"target"` // scanned directory, repository@ref or uploaded archive
+	Status         JobStatus `json:"status"`
+	Phase          string    `json:"phase,omitempty"`
+	FilesTotal     int       `json:"files_total"`
+	FilesProcessed int       `json:"files_processed"`
+	NodesFound     int       `json:"nodes_found"`
+	EdgesFound     int       `json:"edges_found"`
+	// Errors lists files and linkers that failed, which the scan skipped.
+	Errors     []ScanError `json:"errors,omitempty"`
+	ErrorCount int         `json:"error_count"`
+	// Error tells why the job failed as a whole.
+	Error string `json:"error,omitempty"`
+	// Changes tells which files changed since the last scan, once it is done.
+	Changes *FileChanges `json:"changes,omitempty"`
+	// Commit is the SHA of the commit a git scan read, once it is done.
+	Commit     string     `json:"commit,omitempty"`
+	Timings    *Timings   `json:"timings,omitempty"` // set once the scan is done
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has ended, in whatever way.
+func (j Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// ScanFunc performs the work of a job, reporting its events as it goes.
+type ScanFunc func(ctx context.Context, events EventFunc) (*ScanResult, error)
+
+// JobManager runs scans in the background on a fixed number of workers, fed
+// by a bounded queue.
+type JobManager interface {
+	// Submit queues run; it fails with ErrQueueFull when the queue is full.
+	Submit(project, kind, target string, run ScanFunc) (Job, error)
+	Get(id string) (Job, error)
+	// Cancel stops a queued or running job through its context.
+	Cancel(id string) (Job, error)
+	// Events calls fn with the events of a job in order, starting at sequence
+	// number from, and waits for more until the job ends, fn returns false or
+	// ctx is done. Once a job has ended only its EventSummary is kept, so
+	// subscribers of a finished job receive just that.
+	Events(ctx context.Context, id string, from int, fn func(seq int, e ScanEvent) bool) error
+	// Close cancels every job and waits for the workers to stop.
+	Close()
+}
+
+type job struct {
+	mu     sync.Mutex
+	Job    Job
+	run    ScanFunc
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// events is the log of the job's events, replayed to every subscriber;
+	// first is the sequence number of events[0].
+	events []ScanEvent
+	first  int
+	// changed is closed, and replaced, whenever an event is logged.
+	changed chan struct{}
+}
+
+type jobManager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan *job
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	jobs     map[string]*job
+	finished []string // IDs of finished jobs, oldest first
+}
+
+func NewJobManager(cfg config.JobsConfig) JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &jobManager{
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *job, cfg.QueueSize),
+		jobs:   make(map[string]*job),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+func (m *jobManager) Submit(project, kind, target string, run ScanFunc) (Job, error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	j := &job{
+		Job: Job{
+			ID:        newJobID(),
+			Project:   project,
+			Kind:      kind,
+			Target:    target,
+			Status:    JobQueued,
+			CreatedAt: time.Now().UTC(),
+		},
+		run:     run,
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+
+	snapshot := j.Job // a worker may pick the job up as soon as it is queued
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		cancel()
+		return Job{}, ErrJobsClosed
+	}
+	select {
+	case m.queue <- j:
+	default:
+		cancel()
+		return Job{}, fmt.Errorf("%w (%d waiting)", ErrQueueFull, cap(m.queue))
+	}
+	m.jobs[snapshot.ID] = j
+	return snapshot, nil
+}
+
+func (m *jobManager) Get(id string) (Job, error) {
+	j, err := m.lookup(id)
+	if err != nil {
+		return Job{}, err
+	}
+	return j.snapshot(), nil
+}
+
+func (m *jobManager) Cancel(id string) (Job, error) {
+	j, err := m.lookup(id)
+	if err != nil {
+		return Job{}, err
+	}
+	j.mu.Lock()
+	switch {
+	case j.Job.Finished():
+		j.mu.Unlock()
+		return j.snapshot(), fmt.Errorf("%w: %s", ErrJobFinished, id)
+	case j.Job.Status == JobQueued:
+		// The worker that dequeues it will skip it.
+		j.end(JobCancelled, nil)
+		j.mu.Unlock()
+		m.retire(j)
+	default:
+		j.mu.Unlock()
+	}
+	j.cancel()
+	return j.snapshot(), nil
+}
+
+func (m *jobManager) Close() {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *jobManager) lookup(id string) (*job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return j, nil
+}
+
+func (m *jobManager) work() {
+	defer m.wg.Done()
+	for j := range m.queue {
+		m.runJob(j)
+	}
+}
+
+func (m *jobManager) runJob(j *job) {
+	j.mu.Lock()
+	if j.Job.Status != JobQueued {
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	j.Job.Status, j.Job.StartedAt = JobRunning, &now
+	j.mu.Unlock()
+
+	result, err := j.run(j.ctx, j.record)
+
+	j.mu.Lock()
+	switch {
+	case err == nil:
+		j.Job.NodesFound, j.Job.EdgesFound = len(result.Nodes), len(result.Edges)
+		j.Job.Changes, j.Job.Commit = result.Changes, result.Commit
+		j.end(JobSucceeded, nil)
+	case errors.Is(err, context.Canceled):
+		j.end(JobCancelled, nil)
+	default:
+		j.end(JobFailed, err)
+	}
+	status := j.Job.Status
+	j.mu.Unlock()
+	j.cancel()
+	m.retire(j)
+	slog.Info("job finished", "job", j.Job.ID, "project", j.Job.Project, "status", status, "error", err)
+}
+
+// retire records a finished job, forgetting the oldest ones beyond maxFinishedJobs.
+func (m *jobManager) retire(j *job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, j.Job.ID)
+	for len(m.finished) > maxFinishedJobs {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+func (m *jobManager) Events(ctx context.Context, id string, from int, fn func(seq int, e ScanEvent) bool) error {
+	j, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+	seq := from
+	for {
+		j.mu.Lock()
+		// Events dropped when the job ended are skipped.
+		seq = max(seq, j.first)
+		seq = min(seq, j.first+len(j.events))
+		batch := j.events[seq-j.first:]
+		finished, changed := j.Job.Finished(), j.changed
+		j.mu.Unlock()
+
+		for _, e := range batch {
+			if !fn(seq, e) {
+				return nil
+			}
+			seq++
+		}
+		if finished {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// record folds an event into the job and logs it for subscribers.
+func (j *job) record(e ScanEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := e.Progress
+	j.Job.Phase = p.Phase
+	j.Job.FilesTotal = p.FilesTotal
+	j.Job.FilesProcessed = p.FilesProcessed
+	j.Job.NodesFound = p.NodesFound
+	j.Job.EdgesFound = p.EdgesFound
+	j.Job.ErrorCount = p.Errors
+	if e.Timings != nil {
+		j.Job.Timings = e.Timings
+	}
+	if e.Failure != nil && len(j.Job.Errors) < maxJobErrors {
+		j.Job.Errors = append(j.Job.Errors, *e.Failure)
+	}
+	j.log(e)
+}
+
+func (j *job) log(e ScanEvent) {
+	j.events = append(j.events, e)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// end marks the job finished and closes its log with a summary, dropping the
+// events before it; j.mu must be held.
+func (j *job) end(status JobStatus, err error) {
+	now := time.Now().UTC()
+	j.Job.Status, j.Job.FinishedAt = status, &now
+	if err != nil {
+		j.Job.Error = err.Error()
+	}
+	j.first += len(j.events)
+	j.events = nil
+	j.log(ScanEvent{
+		Type:    EventSummary,
+		Status:  status,
+		Error:   j.Job.Error,
+		Timings: j.Job.Timings,
+		Progress: Progress{
+			Phase:          j.Job.Phase,
+			FilesTotal:     j.Job.FilesTotal,
+			FilesProcessed: j.Job.FilesProcessed,
+			NodesFound:     j.Job.NodesFound,
+			EdgesFound:     j.Job.EdgesFound,
+			Errors:         j.Job.ErrorCount,
+		},
+	})
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.Job
+	s.Errors = append([]ScanError(nil), j.Job.Errors...)
+	return s
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
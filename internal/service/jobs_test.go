@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+// waitJob polls a job until it has finished.
+func waitJob(t *testing.T, m JobManager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		j, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.Finished() {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", id, j.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       ScanFunc
+		cancel    bool
+		want      JobStatus
+		wantError string
+	}{
+		{
+			name: "succeeded",
+			run: func(ctx context.Context, events EventFunc) (*ScanResult, error) {
+				events(ScanEvent{Type: EventPhase, Progress: Progress{Phase: PhaseDone, NodesFound: 2}})
+				return &ScanResult{Nodes: []*models.CodeNode{{ID: "a"}, {ID: "b"}}}, nil
+			},
+			want: JobSucceeded,
+		},
+		{
+			name: "failed",
+			run: func(context.Context, EventFunc) (*ScanResult, error) {
+				return nil, errors.New("disk on fire")
+			},
+			want:      JobFailed,
+			wantError: "disk on fire",
+		},
+		{
+			name: "cancelled while running",
+			run: func(ctx context.Context, _ EventFunc) (*ScanResult, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			cancel: true,
+			want:   JobCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewJobManager(config.JobsConfig{Workers: 1, QueueSize: 1})
+			defer m.Close()
+			started := make(chan struct{})
+			job, err := m.Submit("p", JobScanDir, "/src", func(ctx context.Context, events EventFunc) (*ScanResult, error) {
+				close(started)
+				return tt.run(ctx, events)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != JobQueued {
+				t.Errorf("submitted job is %s, want queued", job.Status)
+			}
+			if tt.cancel {
+				<-started
+				if _, err := m.Cancel(job.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := waitJob(t, m, job.ID)
+			if got.Status != tt.want || got.Error != tt.wantError {
+				t.Errorf("job ended %s with error %q, want %s with %q", got.Status, got.Error, tt.want, tt.wantError)
+			}
+			if got.StartedAt == nil || got.FinishedAt == nil {
+				t.Error("start or finish time not recorded")
+			}
+			if _, err := m.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+				t.Errorf("Cancel of a finished job: error = %v, want ErrJobFinished", err)
+			}
+		})
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	m := NewJobManager(config.JobsConfig{Workers: 1, QueueSize: 1})
+	defer m.Close()
+	started, release := make(chan struct{}), make(chan struct{})
+	busy, err := m.Submit("p", JobScanDir, "/busy", func(context.Context, EventFunc) (*ScanResult, error) {
+		close(started)
+		<-release
+		return &ScanResult{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ran := false
+	queued, err := m.Submit("p", JobScanDir, "/queued", func(context.Context, EventFunc) (*ScanResult, error) {
+		ran = true
+		return &ScanResult{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit("p", JobScanDir, "/rejected", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit to a full queue: error = %v, want ErrQueueFull", err)
+	}
+	if j, err := m.Cancel(queued.ID); err != nil || j.Status != JobCancelled {
+		t.Errorf("Cancel of a queued job = %s, %v; want cancelled", j.Status, err)
+	}
+	close(release)
+	if j := waitJob(t, m, busy.ID); j.Status != JobSucceeded {
+		t.Errorf("busy job ended %s", j.Status)
+	}
+	m.Close()
+	if ran {
+		t.Error("cancelled job ran")
+	}
+	if _, err := m.Submit("p", JobScanDir, "/late", nil); !errors.Is(err, ErrJobsClosed) {
+		t.Errorf("Submit after Close: error = %v, want ErrJobsClosed", err)
+	}
+	if _, err := m.Get("unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get of an unknown job: error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestScansOfAProjectDoNotOverlap(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, testTree)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	ctx := context.Background()
+
+	// The first scan stops at its first event, holding the project.
+	entered, release := make(chan struct{}), make(chan struct{})
+	first := make(chan error)
+	go func() {
+		once := false
+		_, err := scans.ScanDirectory(ctx, dir, ScanOptions{}, func(ScanEvent) {
+			if !once {
+				once = true
+				close(entered)
+				<-release
+			}
+		})
+		first <- err
+	}()
+	<-entered
+
+	// A scan giving up while waiting stores nothing.
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := scans.ScanDirectory(waitCtx, dir, ScanOptions{Full: true}, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("scan waiting for another: error = %v, want DeadlineExceeded", err)
+	}
+
+	second := make(chan error)
+	secondStarted := make(chan struct{}, 1)
+	go func() {
+		_, err := scans.ScanDirectory(ctx, dir, ScanOptions{Full: true}, func(ScanEvent) {
+			select {
+			case secondStarted <- struct{}{}:
+			default:
+			}
+		})
+		second <- err
+	}()
+	select {
+	case <-secondStarted:
+		t.Fatal("second scan ran while the first held the project")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	for _, done := range []chan error{first, second} {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(scans.GetAllNodes()) == 0 {
+		t.Error("nothing stored")
+	}
+}
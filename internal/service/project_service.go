@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+// ProjectService manages projects and hands out the scan service of each, so
+// that every scan and query is confined to one project's graph.
+type ProjectService interface {
+	// CreateProject creates a project. id may be empty, in which case it is
+	// derived from name ("Order Service" gives "order-service").
+	CreateProject(id, name, description string) (*models.Project, error)
+	GetProject(id string) (*models.Project, error)
+	ListProjects() []*models.Project
+	DeleteProject(id string) error
+	// Scans returns the scan service operating on the graph of a project.
+	Scans(id string) (ScanService, error)
+}
+
+var (
+	// ErrProjectNotFound is returned when a project ID is not known.
+	ErrProjectNotFound = repository.ErrProjectNotFound
+	// ErrProjectExists is returned when creating a project whose ID is taken.
+	ErrProjectExists = repository.ErrProjectExists
+	// ErrInvalidProject is returned when a project's ID or name is unusable.
+	ErrInvalidProject = errors.New("invalid project")
+)
+
+// projectID is the form of project IDs, which appear in URLs.
+var projectID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type projectService struct {
+	repo repository.ProjectRepository
+	cfg  config.ScanConfig
+
+	mu    sync.Mutex
+	scans map[string]ScanService
+}
+
+func NewProjectService(repo repository.ProjectRepository, cfg config.ScanConfig) ProjectService {
+	return &projectService{repo: repo, cfg: cfg, scans: make(map[string]ScanService)}
+}
+
+func (s *projectService) CreateProject(id, name, description string) (*models.Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidProject)
+	}
+	if id == "" {
+		id = slug(name)
+	}
+	if !projectID.MatchString(id) {
+		return nil, fmt.Errorf("%w: id %q must be 1-64 lower-case letters, digits, '-' or '_'", ErrInvalidProject, id)
+	}
+	project := &models.Project{
+		ID:          id,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.repo.CreateProject(project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (s *projectService) GetProject(id string) (*models.Project, error) {
+	project, ok := s.repo.GetProject(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, id)
+	}
+	return project, nil
+}
+
+func (s *projectService) ListProjects() []*models.Project {
+	return s.repo.ListProjects()
+}
+
+func (s *projectService) DeleteProject(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repo.DeleteProject(id); err != nil {
+		return err
+	}
+	delete(s.scans, id)
+	return nil
+}
+
+func (s *projectService) Scans(id string) (ScanService, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scans, ok := s.scans[id]; ok {
+		return scans, nil
+	}
+	graph, ok := s.repo.Graph(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, id)
+	}
+	scans := NewScanService(graph, s.cfg)
+	s.scans[id] = scans
+	return scans, nil
+}
+
+// slug turns a name into a project ID: lower-case words joined by '-'.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+func TestCreateProject(t *testing.T) {
+	tests := []struct {
+		name, id, projectName string
+		wantID                string
+		wantErr               error
+	}{
+		{"derived ID", "", "  Order Service ", "order-service", nil},
+		{"derived from symbols", "", "Billing & Payments (v2)", "billing-payments-v2", nil},
+		{"explicit ID", "orders_2", "Orders", "orders_2", nil},
+		{"longest ID", strings.Repeat("a", 64), "A", strings.Repeat("a", 64), nil},
+		{"no name", "orders", " ", "", ErrInvalidProject},
+		{"name without letters", "", "???", "", ErrInvalidProject},
+		{"upper-case ID", "Orders", "Orders", "", ErrInvalidProject},
+		{"leading dash", "-orders", "Orders", "", ErrInvalidProject},
+		{"slash", "orders/v2", "Orders", "", ErrInvalidProject},
+		{"ID too long", strings.Repeat("a", 65), "A", "", ErrInvalidProject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects := NewProjectService(repository.NewInMemoryProjectRepository(), config.ScanConfig{})
+			p, err := projects.CreateProject(tt.id, tt.projectName, " about ")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateProject(%q, %q) error = %v, want %v", tt.id, tt.projectName, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.ID != tt.wantID || p.Name != strings.TrimSpace(tt.projectName) || p.Description != "about" || p.CreatedAt.IsZero() {
+				t.Errorf("project = %+v, want ID %q and trimmed fields", p, tt.wantID)
+			}
+			if got, err := projects.GetProject(p.ID); err != nil || got != p {
+				t.Errorf("GetProject(%s) = %v, %v", p.ID, got, err)
+			}
+		})
+	}
+}
+
+func TestProjects(t *testing.T) {
+	projects := NewProjectService(repository.NewInMemoryProjectRepository(), config.ScanConfig{})
+	for _, name := range []string{"Orders", "Users"} {
+		if _, err := projects.CreateProject("", name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := projects.CreateProject("", "ORDERS", ""); !errors.Is(err, ErrProjectExists) {
+		t.Errorf("duplicate CreateProject error = %v, want ErrProjectExists", err)
+	}
+	var ids []string
+	for _, p := range projects.ListProjects() {
+		ids = append(ids, p.ID)
+	}
+	if got := strings.Join(ids, " "); got != "orders users" {
+		t.Errorf("ListProjects() = %s, want orders users", got)
+	}
+
+	// The same file scanned into one project is not seen by the other.
+	scan := func(id, content string) ScanService {
+		t.Helper()
+		scans, err := projects.Scans(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := scans.ScanFile(context.Background(), "/src/main.go", []byte(content), ScanOptions{Strict: true}); err != nil {
+			t.Fatal(err)
+		}
+		return scans
+	}
+	orders := scan("orders", "package main\n\nfunc Order() {}\n")
+	users := scan("users", "package main\n\nfunc User() {}\n\nfunc Admin() {}\n")
+	if again, _ := projects.Scans("orders"); again != orders {
+		t.Error("Scans returned another scan service for the same project")
+	}
+	if n, m := len(orders.GetAllNodes()), len(users.GetAllNodes()); n != 1 || m != 2 {
+		t.Errorf("orders has %d nodes and users %d, want 1 and 2", n, m)
+	}
+
+	if err := projects.DeleteProject("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := projects.GetProject("orders"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("GetProject of a deleted project error = %v, want ErrProjectNotFound", err)
+	}
+	if _, err := projects.Scans("orders"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Scans of a deleted project error = %v, want ErrProjectNotFound", err)
+	}
+	if err := projects.DeleteProject("orders"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("second DeleteProject error = %v, want ErrProjectNotFound", err)
+	}
+	if len(users.GetAllNodes()) != 2 {
+		t.Error("deleting a project changed another")
+	}
+
+	// A project created again under the same ID starts empty.
+	if _, err := projects.CreateProject("orders", "Orders", ""); err != nil {
+		t.Fatal(err)
+	}
+	if scans, err := projects.Scans("orders"); err != nil || len(scans.GetAllNodes()) != 0 {
+		t.Errorf("recreated project: %v, err %v, want an empty graph", scans, err)
+	}
+}
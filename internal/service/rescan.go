@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner/services"
+)
+
+// fileState tells how a walked file compares with its manifest record.
+type fileState int
+
+const (
+	fileAdded fileState = iota
+	fileChanged
+	fileUnchanged
+)
+
+// examine compares f, a file of src, with its manifest record, trusting the
+// size and mtime before the hash unless f is hinted, and parses it unless it
+// is unchanged; with full every source file is parsed. started is called
+// right before parsing.
+func (s *scanService) examine(ctx context.Context, src source, f parsedFile, full bool, started func()) parsedFile {
+	switch {
+	case f.prev == nil:
+		f.state = fileAdded
+	case !full && !f.hinted && f.prev.Size == f.info.Size() && f.prev.ModTime.Equal(f.info.ModTime()):
+		f.state, f.record = fileUnchanged, f.prev
+		return f
+	default:
+		f.state = fileChanged
+	}
+
+	content, err := fs.ReadFile(src.tree.FS, f.name)
+	if err != nil {
+		f.err = err
+		return f
+	}
+	sum := sha256.Sum256(content)
+	record := &models.FileRecord{
+		Path:    f.path,
+		Size:    int64(len(content)),
+		ModTime: f.info.ModTime(),
+		Hash:    hex.EncodeToString(sum[:]),
+		Root:    src.tree.Root,
+	}
+	if f.prev != nil && f.prev.Hash == record.Hash {
+		// Touched but not modified
+		f.state, record.Nodes = fileUnchanged, f.prev.Nodes
+		if !full {
+			f.record, record.Root = record, f.prev.Root
+			return f
+		}
+	}
+	if f.source {
+		started()
+		nodes, err := s.parseFile(ctx, src, f.path, content)
+		if err != nil {
+			f.err = err
+			return f
+		}
+		f.parsed, f.nodes, record.Nodes = true, nodes, nil
+		for _, n := range nodes {
+			record.Nodes = append(record.Nodes, n.ID)
+		}
+	}
+	f.record = record
+	return f
+}
+
+// rescan is the plan of a directory scan: how the files changed since the
+// manifest was recorded, and which of them must be linked again.
+type rescan struct {
+	files   []parsedFile // in walk order
+	changes FileChanges
+	// changed lists the added, changed and removed files.
+	changed []string
+	// full relinks every file: a build manifest changed, the scan was asked
+	// to, or a linker could not tell what a change affects.
+	full bool
+	// relink holds the files whose nodes and relations are derived again: the
+	// added and changed files, the build manifests and the scopes of the
+	// scoped linkers, plus, once linked, the files holding nodes derived by
+	// linkers that are not scoped.
+	relink map[string]bool
+	// scopes holds the scope of each ScopedLinker, unless full is set.
+	scopes map[scanner.Linker]map[string]bool
+	// rewrite holds the files not relinked whose stored nodes must be saved
+	// again all the same, as they record another commit.
+	rewrite map[string]bool
+}
+
+// idle reports whether nothing changed, so that there is nothing to link.
+func (r *rescan) idle() bool {
+	return !r.full && len(r.changed) == 0
+}
+
+// planRescan compares the walked files with the manifest of the previous
+// scans; records of files under owned, the directory the scan replaces, that
+// were not walked are those of removed files.
+func (s *scanService) planRescan(ctx context.Context, tree scanner.Tree, owned string, manifest map[string]*models.FileRecord, files []parsedFile, full bool) (*rescan, error) {
+	r := &rescan{
+		files:   files,
+		full:    full,
+		relink:  make(map[string]bool),
+		scopes:  make(map[scanner.Linker]map[string]bool),
+		rewrite: make(map[string]bool),
+	}
+	r.changes.Added, r.changes.Changed, r.changes.Removed = []string{}, []string{}, []string{}
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.path] = true
+		if f.err != nil {
+			// Left as it was, to be retried by the next scan
+			continue
+		}
+		switch f.state {
+		case fileAdded:
+			r.changes.Added = append(r.changes.Added, f.path)
+		case fileChanged:
+			r.changes.Changed = append(r.changes.Changed, f.path)
+		default:
+			r.changes.Unchanged++
+			continue
+		}
+		r.changed = append(r.changed, f.path)
+		if f.build {
+			r.full = true
+		}
+	}
+	for path := range manifest {
+		if !seen[path] && within([]string{owned}, path) {
+			r.changes.Removed = append(r.changes.Removed, path)
+			if services.IsManifest(filepath.Base(path)) {
+				r.full = true
+			}
+		}
+	}
+	sort.Strings(r.changes.Removed)
+	r.changed = append(r.changed, r.changes.Removed...)
+	if r.idle() {
+		return r, nil
+	}
+
+	if !r.full {
+		for _, linker := range s.linkers {
+			scoped, ok := linker.(scanner.ScopedLinker)
+			if !ok {
+				continue
+			}
+			scope, err := scoped.Scope(ctx, tree, r.changed)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err != nil {
+				slog.Warn("scoping failed, relinking every file", "linker", fmt.Sprintf("%T", linker), "dir", tree.Root, "error", err)
+				r.full = true
+				break
+			}
+			r.scopes[linker] = scope
+			for path := range scope {
+				r.relink[path] = true
+			}
+		}
+	}
+	if r.full {
+		r.scopes = nil
+	}
+	for _, f := range files {
+		if r.full || f.build || (f.err == nil && f.state != fileUnchanged) {
+			r.relink[f.path] = true
+		}
+	}
+	return r, nil
+}
+
+// nodes returns the nodes to link: the scanned nodes of the files to relink
+// and, for the others, everything stored for them by earlier scans.
+func (s *scanService) nodes(r *rescan) []*models.CodeNode {
+	var stored map[string][]*models.CodeNode
+	if !r.full {
+		stored = make(map[string][]*models.CodeNode)
+		for _, n := range s.repo.GetAllNodes() {
+			stored[n.FilePath] = append(stored[n.FilePath], n)
+		}
+	}
+
+	var nodes []*models.CodeNode
+	for _, f := range r.files {
+		scanned := s.scannedNodes(f)
+		nodes = append(nodes, scanned...)
+		if r.relink[f.path] {
+			continue
+		}
+		// Nodes derived by the linkers, which stay as they are
+		own := make(map[string]bool, len(scanned))
+		for _, n := range scanned {
+			own[n.ID] = true
+		}
+		var derived []*models.CodeNode
+		for _, n := range stored[f.path] {
+			if !own[n.ID] {
+				derived = append(derived, n.Clone())
+			}
+		}
+		sort.Slice(derived, func(i, j int) bool { return derived[i].ID < derived[j].ID })
+		nodes = append(nodes, derived...)
+	}
+	return nodes
+}
+
+// scannedNodes returns the nodes the scanner finds in f: those just parsed,
+// or copies of those stored by the scan that recorded it.
+func (s *scanService) scannedNodes(f parsedFile) []*models.CodeNode {
+	if f.parsed {
+		return f.nodes
+	}
+	record := f.record
+	if record == nil {
+		record = f.prev
+	}
+	if record == nil {
+		return nil
+	}
+	nodes := make([]*models.CodeNode, 0, len(record.Nodes))
+	for _, id := range record.Nodes {
+		if n, ok := s.repo.GetNode(id); ok {
+			nodes = append(nodes, n.Clone())
+		}
+	}
+	return nodes
+}
+
+// link runs the linkers over nodes. A scoped linker only relinks its scope,
+// so its stored edges starting elsewhere are carried over into the graph; it
+// is not run at all when nothing in its scope changed.
+func (s *scanService) link(ctx context.Context, tree scanner.Tree, r *rescan, nodes []*models.CodeNode, result *ScanResult, p *Progress, events EventFunc) (*models.Graph, error) {
+	graph := &models.Graph{Nodes: nodes}
+	fileOf := make(map[string]string, len(nodes)) // node ID -> file
+	for _, n := range nodes {
+		fileOf[n.ID] = n.FilePath
+	}
+	var kept []*models.Edge
+	for _, linker := range s.linkers {
+		name := fmt.Sprintf("%T", linker)
+		t := tree
+		scope, scoped := r.scopes[linker]
+		if scoped {
+			kinds := linker.(scanner.ScopedLinker).EdgeKinds()
+			for _, n := range graph.Nodes {
+				if !scope[n.FilePath] {
+					kept = append(kept, s.repo.OutEdges(n.ID, kinds...)...)
+				}
+			}
+			if len(scope) == 0 {
+				continue
+			}
+			t.Scope = scope
+		}
+
+		linked, err := linker.Link(ctx, t, graph.Nodes)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			// A failed linker leaves relations incomplete but the nodes are still useful
+			slog.Warn("linking failed", "linker", name, "dir", tree.Root, "error", err)
+			p.Errors++
+			failure := ScanError{Linker: name, Kind: ErrorKindLink, Message: err.Error()}
+			result.Errors = append(result.Errors, failure)
+			events.emit(ScanEvent{Type: EventLinkFailed, Progress: *p, Linker: name, Failure: &failure})
+			continue
+		}
+		if linked == nil {
+			continue
+		}
+		if scoped {
+			linked = inScope(linked, scope, fileOf)
+		}
+		for _, n := range linked.Nodes {
+			fileOf[n.ID] = n.FilePath
+			r.relink[n.FilePath] = true
+		}
+		graph.Merge(linked)
+		p.NodesFound, p.EdgesFound = len(graph.Nodes), len(graph.Edges)
+		events.emit(ScanEvent{Type: EventLinked, Progress: *p, Linker: name, Nodes: cloneNodes(linked.Nodes), Edges: cloneEdges(linked.Edges)})
+	}
+	graph.Edges = append(graph.Edges, r.unlinked(kept, fileOf)...)
+	return graph, nil
+}
+
+// unlinked returns the edges of kept once each, leaving out those starting
+// in the scope of a linker adding edges of their kind: linkers may share
+// kinds, such as HANDLES_ROUTE, and each keeps the edges of the others.
+func (r *rescan) unlinked(kept []*models.Edge, fileOf map[string]string) []*models.Edge {
+	var edges []*models.Edge
+	seen := make(map[string]bool, len(kept))
+	for _, e := range kept {
+		if seen[e.ID] || r.relinks(e, fileOf[e.Source]) {
+			continue
+		}
+		seen[e.ID] = true
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// relinks reports whether a scoped linker relinked the edges like e starting
+// in file.
+func (r *rescan) relinks(e *models.Edge, file string) bool {
+	for linker, scope := range r.scopes {
+		if scope[file] && slices.Contains(linker.(scanner.ScopedLinker).EdgeKinds(), e.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// stampCommit records commit in the metadata of the nodes of graph, and marks
+// for rewriting the files whose stored nodes recorded another one.
+func (r *rescan) stampCommit(graph *models.Graph, commit string) {
+	for _, n := range graph.Nodes {
+		if n.Metadata["commit"] == commit {
+			continue
+		}
+		if n.Metadata == nil {
+			n.Metadata = make(map[string]interface{})
+		}
+		n.Metadata["commit"] = commit
+		if !r.relink[n.FilePath] {
+			r.rewrite[n.FilePath] = true
+		}
+	}
+}
+
+// inScope keeps the nodes of g located in scope and the edges starting there.
+func inScope(g *models.Graph, scope map[string]bool, fileOf map[string]string) *models.Graph {
+	out := &models.Graph{}
+	for _, n := range g.Nodes {
+		if scope[n.FilePath] {
+			fileOf[n.ID] = n.FilePath
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if scope[fileOf[e.Source]] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+// store writes what a rescan changed in a single batch: it removes the nodes
+// of removed files, replaces the nodes of the relinked and rewritten files,
+// brings the edges starting in the directory in line with graph and updates
+// the manifest.
+func (s *scanService) store(r *rescan, graph *models.Graph) error {
+	return s.repo.Batch(func(w repository.GraphWriter) error {
+		for _, path := range r.changes.Removed {
+			w.ReplaceFileNodes(path, nil)
+		}
+		w.DeleteFiles(r.changes.Removed...)
+
+		if !r.idle() || len(r.rewrite) > 0 {
+			replace := func(path string) bool { return r.relink[path] || r.rewrite[path] }
+			fileNodes := make(map[string][]*models.CodeNode, len(r.relink)+len(r.rewrite))
+			for _, n := range graph.Nodes {
+				if replace(n.FilePath) {
+					fileNodes[n.FilePath] = append(fileNodes[n.FilePath], n)
+				}
+			}
+			paths := make([]string, 0, len(r.relink)+len(r.rewrite))
+			for path := range r.relink {
+				paths = append(paths, path)
+			}
+			for path := range r.rewrite {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				w.ReplaceFileNodes(path, fileNodes[path])
+			}
+			syncEdges(w, graph)
+		}
+
+		var records []*models.FileRecord
+		for _, f := range r.files {
+			if f.record != nil && f.record != f.prev {
+				records = append(records, f.record)
+			}
+		}
+		w.SaveFiles(records...)
+		return nil
+	})
+}
+
+// syncEdges makes the stored edges starting at the nodes of graph those of
+// graph, leaving alone the edges that did not change.
+func syncEdges(w repository.GraphWriter, graph *models.Graph) {
+	want := make(map[string]*models.Edge, len(graph.Edges))
+	for _, e := range graph.Edges {
+		want[e.ID] = e
+	}
+	current := make(map[string]bool, len(graph.Edges))
+	for _, n := range graph.Nodes {
+		for _, e := range w.OutEdges(n.ID) {
+			wanted, ok := want[e.ID]
+			switch {
+			case !ok:
+				w.DeleteEdge(e.ID)
+			case reflect.DeepEqual(wanted.Attributes, e.Attributes):
+				current[e.ID] = true
+			}
+		}
+	}
+	var edges []*models.Edge
+	for _, e := range graph.Edges {
+		if !current[e.ID] {
+			edges = append(edges, e)
+		}
+	}
+	saveEdges(w, edges)
+}
+
+// removedNodes returns copies of the nodes the scanner had found in the
+// files removed since the manifest was recorded, by file.
+func (s *scanService) removedNodes(r *rescan, manifest map[string]*models.FileRecord) map[string][]*models.CodeNode {
+	removed := make(map[string][]*models.CodeNode, len(r.changes.Removed))
+	for _, path := range r.changes.Removed {
+		nodes := []*models.CodeNode{}
+		for _, id := range manifest[path].Nodes {
+			if n, ok := s.repo.GetNode(id); ok {
+				nodes = append(nodes, n.Clone())
+			}
+		}
+		removed[path] = nodes
+	}
+	return removed
+}
+
+// storedEdges returns the stored edges starting at nodes.
+func (s *scanService) storedEdges(nodes []*models.CodeNode) []*models.Edge {
+	var edges []*models.Edge
+	for _, n := range nodes {
+		edges = append(edges, s.repo.OutEdges(n.ID)...)
+	}
+	return edges
+}
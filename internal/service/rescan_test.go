@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+)
+
+// snapshot returns the stored graph in a canonical form for comparison.
+func snapshot(t *testing.T, scans ScanService) string {
+	t.Helper()
+	nodes, edges := scans.GetAllNodes(), scans.GetAllEdges()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	models.SortEdges(edges)
+	data, err := json.Marshal(models.Graph{Nodes: nodes, Edges: edges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRescanIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, testTree)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	ctx := context.Background()
+	if _, err := scans.ScanDirectory(ctx, dir, ScanOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := snapshot(t, scans)
+
+	tests := []struct {
+		name        string
+		scan        func() (*ScanResult, error)
+		wantScanned int
+	}{
+		{"unchanged", func() (*ScanResult, error) { return scans.ScanDirectory(ctx, dir, ScanOptions{}, nil) }, 0},
+		{"full", func() (*ScanResult, error) { return scans.ScanDirectory(ctx, dir, ScanOptions{Full: true}, nil) }, 4}, // the source files, go.mod left out
+		{"hinted", func() (*ScanResult, error) {
+			return scans.ScanDirectory(ctx, dir, ScanOptions{Hints: []string{filepath.Join(dir, "users", "main.go")}}, nil)
+		}, 0},
+		{"single file", func() (*ScanResult, error) {
+			path := filepath.Join(dir, "tools", "report.py")
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return scans.ScanFile(ctx, path, content, ScanOptions{})
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.FilesScanned != tt.wantScanned {
+				t.Errorf("FilesScanned = %d, want %d", result.FilesScanned, tt.wantScanned)
+			}
+			if result.Changes != nil && len(result.Changes.Added)+len(result.Changes.Changed)+len(result.Changes.Removed) > 0 {
+				t.Errorf("changes = %+v, want none", result.Changes)
+			}
+			if got := snapshot(t, scans); got != want {
+				t.Errorf("graph changed by a rescan of the same files:\n got %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRescanRenamesRoutesOfUnchangedFiles(t *testing.T) {
+	files := map[string]string{
+		"api/go.mod": "module example.com/api\n\ngo 1.23\n",
+		"api/main.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+func main() {
+	r := gin.Default()
+	registerUsers(r.Group("/v1/users"))
+}
+`,
+		"api/users.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+func listUsers(c *gin.Context) {}
+
+func registerUsers(g *gin.RouterGroup) {
+	g.GET("", listUsers)
+}
+`,
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	routes := func() []string {
+		t.Helper()
+		if _, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, n := range scans.GetAllNodes() {
+			if n.Type == models.NodeRoute {
+				names = append(names, n.Name)
+			}
+		}
+		return names
+	}
+
+	if got := routes(); len(got) != 1 || got[0] != "GET /v1/users" {
+		t.Fatalf("routes = %q, want [GET /v1/users]", got)
+	}
+	// Only main.go changes: the route declared in users.go moves all the same.
+	writeTree(t, dir, map[string]string{"api/main.go": `package main
+
+import "github.com/gin-gonic/gin"
+
+func main() {
+	r := gin.Default()
+	registerUsers(r.Group("/api/v2/users"))
+}
+`})
+	if got := routes(); len(got) != 1 || got[0] != "GET /api/v2/users" {
+		t.Errorf("routes after rescan = %q, want [GET /api/v2/users]", got)
+	}
+}
+
+func TestRescanChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, testTree)
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	ctx := context.Background()
+	if _, err := scans.ScanDirectory(ctx, dir, ScanOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// users/main.go serves the route orders/main.go calls; removing it must
+	// take the cross-service edge along.
+	if err := os.Remove(filepath.Join(dir, "users", "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "billing")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{
+		"tools/report.py": "def report():\n    pass\n",
+		"users/api.go":    "package main\n\nfunc listAccounts() {}\n",
+	})
+	result, err := scans.ScanDirectory(ctx, dir, ScanOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := func(paths []string) string {
+		for i, p := range paths {
+			paths[i], _ = filepath.Rel(dir, p)
+		}
+		return strings.Join(paths, " ")
+	}
+	c := result.Changes
+	for _, tt := range []struct{ name, got, want string }{
+		{"added", rel(c.Added), filepath.Join("users", "api.go")},
+		{"changed", rel(c.Changed), filepath.Join("tools", "report.py")},
+		{"removed", rel(c.Removed), filepath.Join("billing", "src", "main", "java", "com", "example", "Billing.java") + " " + filepath.Join("users", "main.go")},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s files = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if c.Unchanged != 3 { // orders/main.go and the go.mod files
+		t.Errorf("unchanged files = %d, want 3", c.Unchanged)
+	}
+	if result.FilesScanned != 2 {
+		t.Errorf("FilesScanned = %d, want 2", result.FilesScanned)
+	}
+
+	ids := make(map[string]bool)
+	for _, n := range scans.GetAllNodes() {
+		ids[n.ID] = true
+		if n.FilePath == filepath.Join(dir, "users", "main.go") || n.Name == "listUsers" || strings.Contains(n.Name, "Billing") {
+			t.Errorf("node %s %s of the removed file is still stored", n.Type, n.Name)
+		}
+	}
+	for _, e := range scans.GetAllEdges() {
+		if !ids[e.Source] || !ids[e.Target] {
+			t.Errorf("edge %s %s -> %s dangles after the removal", e.Kind, e.Source, e.Target)
+		}
+	}
+
+	// The incremental result matches a full scan of the same files.
+	got := snapshot(t, scans)
+	if _, err := scans.ScanDirectory(ctx, dir, ScanOptions{Full: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := snapshot(t, scans); got != want {
+		t.Errorf("incremental rescan:\n got %s\nfull rescan:\n%s", got, want)
+	}
+}
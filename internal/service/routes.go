@@ -0,0 +1,79 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/utils"
+)
+
+// RouteMatch is a ROUTE node together with the functions that handle it.
+type RouteMatch struct {
+	Route    *models.CodeNode   `json:"route"`
+	Handlers []*models.CodeNode `json:"handlers"`
+}
+
+// MatchRoutes answers "which function serves METHOD path" over graph. An empty
+// method or path matches every route; routes registered for any method match
+// every method. The most specific routes come first.
+func MatchRoutes(graph *models.Graph, method, path string) []RouteMatch {
+	byID := make(map[string]*models.CodeNode, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		byID[n.ID] = n
+	}
+	handlers := make(map[string][]*models.CodeNode)
+	for _, edge := range graph.Edges {
+		if edge.Kind != models.EdgeHandlesRoute {
+			continue
+		}
+		if h, ok := byID[edge.Source]; ok {
+			handlers[edge.Target] = append(handlers[edge.Target], h)
+		}
+	}
+
+	var matches []RouteMatch
+	for _, n := range graph.Nodes {
+		if n.Type != models.NodeRoute || !routeMatches(n, method, path) {
+			continue
+		}
+		hs := handlers[n.ID]
+		sort.Slice(hs, func(i, j int) bool { return hs[i].Name < hs[j].Name })
+		matches = append(matches, RouteMatch{Route: n, Handlers: hs})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i].Route, matches[j].Route
+		sa, sb := utils.RouteSpecificity(routePath(a)), utils.RouteSpecificity(routePath(b))
+		if sa != sb {
+			return sa > sb
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.FilePath < b.FilePath
+	})
+	return matches
+}
+
+func routeMatches(n *models.CodeNode, method, path string) bool {
+	if method != "" {
+		m, _ := n.Metadata["method"].(string)
+		if m != "ANY" && !strings.EqualFold(m, method) {
+			return false
+		}
+	}
+	return path == "" || utils.MatchRoutePath(routePath(n), path)
+}
+
+func routePath(n *models.CodeNode) string {
+	p, _ := n.Metadata["path"].(string)
+	return p
+}
+
+func (s *scanService) FindRoutes(method, path string) []RouteMatch {
+	graph := &models.Graph{
+		Nodes: s.repo.GetAllNodes(),
+		Edges: s.repo.GetAllEdges(models.EdgeHandlesRoute),
+	}
+	return MatchRoutes(graph, method, path)
+}
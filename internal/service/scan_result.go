@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+)
+
+// ScanOptions tunes a single scan.
+type ScanOptions struct {
+	// Strict fails the scan, storing nothing, when any file cannot be scanned.
+	Strict bool
+	// Full re-parses and relinks every file of a directory, even those the
+	// manifest says did not change.
+	Full bool
+	// Hints lists files of a directory known to have changed, such as those
+	// reported by a file watcher: they are compared with the manifest by
+	// content even when their size and mtime did not change.
+	Hints []string
+}
+
+// Kinds of ScanError.
+const (
+	ErrorKindRead  = "read"  // the file could not be read
+	ErrorKindParse = "parse" // the file is not valid source code
+	ErrorKindScan  = "scan"  // the scanner failed for another reason
+	ErrorKindLink  = "link"  // a linker failed; File is empty
+)
+
+// ScanError describes a file, or a linker, that a scan had to skip.
+type ScanError struct {
+	File     string `json:"file,omitempty"`
+	Language string `json:"language,omitempty"`
+	Linker   string `json:"linker,omitempty"`
+	Kind     string `json:"kind"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (e ScanError) String() string {
+	switch {
+	case e.Linker != "":
+		return fmt.Sprintf("%s: %s", e.Linker, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// ScanResult is the outcome of a scan: the graph found and an account of the
+// files that did not make it into it.
+type ScanResult struct {
+	Nodes []*models.CodeNode `json:"nodes"`
+	Edges []*models.Edge     `json:"edges"`
+	Count int                `json:"count"` // number of nodes
+	// FilesScanned counts the files parsed successfully; a rescan does not
+	// parse the files that did not change.
+	FilesScanned int `json:"files_scanned"`
+	// FilesFailed counts the files listed in Errors.
+	FilesFailed int `json:"files_failed"`
+	// FilesSkipped counts the files larger than the configured maximum.
+	FilesSkipped int `json:"files_skipped"`
+	// FilesUnsupported counts the files no scanner handles.
+	FilesUnsupported int `json:"files_unsupported"`
+	// Errors lists the files and linkers that failed, files first in walk order.
+	Errors []ScanError `json:"errors"`
+	// Changes tells what changed since the directory was last scanned; it is
+	// not set for single files.
+	Changes *FileChanges `json:"changes,omitempty"`
+	// Commit is the SHA of the scanned commit, for scans of git repositories.
+	Commit  string   `json:"commit,omitempty"`
+	Timings *Timings `json:"timings,omitempty"`
+}
+
+// FileChanges compares the files of a directory with the project's manifest,
+// which records the size, mtime and content hash of every scanned file.
+type FileChanges struct {
+	Added     []string `json:"added"`
+	Changed   []string `json:"changed"`
+	Removed   []string `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+	// Relinked counts the files whose cross-file relations were resolved
+	// again; none are when nothing changed.
+	Relinked int `json:"relinked"`
+}
+
+func newScanResult() *ScanResult {
+	return &ScanResult{Nodes: []*models.CodeNode{}, Edges: []*models.Edge{}, Errors: []ScanError{}}
+}
@@ -1,158 +1,769 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/gitfs"
 	"github.com/chinmay-sawant/gosourcemapper/internal/models"
 	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
 	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
 	"github.com/chinmay-sawant/gosourcemapper/internal/scanner/golang"
 	"github.com/chinmay-sawant/gosourcemapper/internal/scanner/java"
 	"github.com/chinmay-sawant/gosourcemapper/internal/scanner/python"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner/services"
 	"github.com/chinmay-sawant/gosourcemapper/internal/utils"
 )
 
+// ScanService scans source code into a project's graph. Files that cannot be
+// scanned are listed in the ScanResult and skipped, unless ScanOptions.Strict
+// is set: then the scan fails with ErrStrictScan, storing nothing, and the
+// result returned alongside the error lists the files at fault.
+//
+// Scans writing to the graph run one at a time, whether they come from jobs,
+// requests or watch mode; the others wait for their turn.
 type ScanService interface {
-	ScanFile(filePath string, content []byte) ([]*models.CodeNode, error)
-	ScanDirectory(dirPath string) ([]*models.CodeNode, error)
-	ProcessZipUpload(file *multipart.FileHeader, destRoot string) ([]*models.CodeNode, error)
+	// ScanFile scans a single file, replacing what earlier scans found in it.
+	ScanFile(ctx context.Context, filePath string, content []byte, opts ScanOptions) (*ScanResult, error)
+	// ScanDirectory scans every supported file under dirPath and links them.
+	// Rescans only parse the files that changed; see ScanOptions.Full.
+	// Nothing is stored when ctx is cancelled before linking is done.
+	ScanDirectory(ctx context.Context, dirPath string, opts ScanOptions, events EventFunc) (*ScanResult, error)
+	// ScanGit scans the files of a git repository, a path or a file:// URL,
+	// at ref: a branch, a tag or a commit, HEAD when empty. They are read from
+	// the object database, so nothing is checked out, and every node records
+	// the commit SHA in its "commit" metadata. Like directory rescans, a scan
+	// of another commit only parses the files that differ.
+	ScanGit(ctx context.Context, repo, ref string, opts ScanOptions, events EventFunc) (*ScanResult, error)
+	// SaveUpload stores and extracts an uploaded zip archive under destRoot,
+	// returning the directory holding its contents.
+	SaveUpload(file *multipart.FileHeader, destRoot string) (string, error)
+	// ScanUpload scans a directory returned by SaveUpload, replacing what
+	// earlier uploads to destRoot stored.
+	ScanUpload(ctx context.Context, dir string, opts ScanOptions, events EventFunc) (*ScanResult, error)
+	// ProcessZipUpload saves, extracts and scans an uploaded archive.
+	ProcessZipUpload(ctx context.Context, file *multipart.FileHeader, destRoot string, opts ScanOptions, events EventFunc) (*ScanResult, error)
 	GetAllNodes() []*models.CodeNode
+	GetAllEdges(kinds ...models.EdgeKind) []*models.Edge
+	GetNodeEdges(nodeID string, direction Direction, kinds ...models.EdgeKind) ([]*models.Edge, error)
+	// FindRoutes returns the routes serving method and path with their handlers.
+	FindRoutes(method, path string) []RouteMatch
 }
 
+// Direction selects which edges of a node are returned.
+type Direction string
+
+const (
+	DirectionOut  Direction = "out"
+	DirectionIn   Direction = "in"
+	DirectionBoth Direction = "both"
+)
+
+var (
+	// ErrPathNotAllowed is returned when a directory scan targets a path outside
+	// the configured allowed roots.
+	ErrPathNotAllowed = errors.New("path is outside the allowed scan roots")
+	// ErrNodeNotFound is returned when a node ID is not in the repository.
+	ErrNodeNotFound = errors.New("node not found")
+	// ErrNotUploaded is returned when ScanUpload is given a directory outside
+	// the temp directory holding uploads.
+	ErrNotUploaded = errors.New("directory is not an extracted upload")
+	// ErrUnsupportedFile is returned when no scanner handles a file's extension.
+	ErrUnsupportedFile = errors.New("unsupported file extension")
+	// ErrStrictScan is returned by a strict scan when some files failed.
+	ErrStrictScan = errors.New("strict scan failed")
+	// ErrNotRepository is returned by ScanGit when the path holds no git
+	// repository.
+	ErrNotRepository = gitfs.ErrNotRepository
+	// ErrUnknownRevision is returned by ScanGit when the ref names no commit.
+	ErrUnknownRevision = gitfs.ErrUnknownRevision
+)
+
 type scanService struct {
-	repo     repository.GraphRepository
-	scanners map[string]scanner.Scanner
+	repo      repository.GraphRepository
+	scanners  map[string]scanner.Scanner
+	languages map[string]string // extension -> language of its scanner
+	linkers   []scanner.Linker
+	cfg       config.ScanConfig
+
+	// busy is held by the scan writing to the project's graph. A scan plans
+	// its changes from the manifest and stores them at the end, so scans of
+	// one project must not overlap: the one storing last would undo the
+	// other's changes.
+	busy chan struct{}
 }
 
-func NewScanService(repo repository.GraphRepository) ScanService {
+func NewScanService(repo repository.GraphRepository, cfg config.ScanConfig) ScanService {
 	// Register scanners
 	scanners := make(map[string]scanner.Scanner)
 	scanners[".go"] = golang.NewGoScanner()
 	scanners[".java"] = java.NewJavaScanner()
 	scanners[".py"] = python.NewPythonScanner()
+	languages := map[string]string{".go": "go", ".java": "java", ".py": "python"}
+
+	// Register linkers, run in order once a whole directory has been scanned
+	linkers := []scanner.Linker{
+		golang.NewGoLinker(),
+		java.NewJavaLinker(),
+		services.NewBoundaryLinker(), // tags the nodes found so far with their service
+		services.NewServiceLinker(),  // needs the routes, calls and env vars found above
+	}
 
 	return &scanService{
-		repo:     repo,
-		scanners: scanners,
+		repo:      repo,
+		scanners:  scanners,
+		languages: languages,
+		linkers:   linkers,
+		cfg:       cfg,
+		busy:      make(chan struct{}, 1),
+	}
+}
+
+// lock waits for the other scans of the project to end, or for ctx to be
+// done, and returns the function ending this one.
+func (s *scanService) lock(ctx context.Context) (unlock func(), err error) {
+	select {
+	case s.busy <- struct{}{}:
+		return func() { <-s.busy }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *scanService) ScanFile(ctx context.Context, filePath string, content []byte, opts ScanOptions) (*ScanResult, error) {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	result := newScanResult()
+	// A file of a scanned directory keeps the IDs the directory scan gave
+	// its nodes, derived relative to the root of that scan.
+	var src source
+	record, recorded := s.repo.GetFile(filePath)
+	if recorded {
+		src.tree.Root = record.Root
+	}
+	nodes, err := s.parseFile(ctx, src, filePath, content)
+	switch {
+	case errors.Is(err, ErrUnsupportedFile), ctx.Err() != nil:
+		return nil, err
+	case err != nil:
+		result.FilesFailed = 1
+		result.Errors = append(result.Errors, s.scanError(filePath, err))
+		if opts.Strict {
+			return result, fmt.Errorf("%w: %s", ErrStrictScan, result.Errors[0])
+		}
+		return result, nil
+	}
+
+	// Save to Repo, replacing whatever an earlier scan found in this file.
+	// Its manifest record no longer describes what is stored, so it is
+	// emptied for the next directory scan to parse the file again.
+	err = s.repo.Batch(func(w repository.GraphWriter) error {
+		w.ReplaceFileNodes(filePath, nodes)
+		if recorded {
+			w.SaveFiles(&models.FileRecord{Path: filePath, Root: record.Root})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store graph: %w", err)
 	}
+
+	result.Nodes, result.Count, result.FilesScanned = nodes, len(nodes), 1
+	return result, nil
 }
 
-func (s *scanService) ScanFile(filePath string, content []byte) ([]*models.CodeNode, error) {
+// parseFile runs the scanner registered for the file extension without
+// touching the repository. A file of a commit is parsed against the other
+// files of the commit rather than those on disk, which lets scanners look
+// above the root of a directory. Node IDs are derived from the path of the
+// file relative to the root of src, or from filePath as given when src is
+// the zero source of a single file. A scanner that panics fails the file
+// rather than the process.
+func (s *scanService) parseFile(ctx context.Context, src source, filePath string, content []byte) (nodes []*models.CodeNode, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("scanner panicked", "file", filePath, "panic", r, "stack", string(debug.Stack()))
+			nodes, err = nil, fmt.Errorf("scanner panicked: %v", r)
+		}
+	}()
 	ext := strings.ToLower(filepath.Ext(filePath))
 	scn, ok := s.scanners[ext]
 	if !ok {
-		return nil, fmt.Errorf("unsupported file extension: %s", ext)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, ext)
+	}
+	if ts, ok := scn.(scanner.TreeScanner); ok && src.commit != "" {
+		return ts.ScanTree(ctx, src.tree, filePath, content)
 	}
+	return scn.Scan(ctx, src.tree.Root, filePath, content)
+}
+
+// scanError describes why path could not be scanned.
+func (s *scanService) scanError(path string, err error) ScanError {
+	e := ScanError{
+		File:     path,
+		Language: s.languages[strings.ToLower(filepath.Ext(path))],
+		Kind:     ErrorKindScan,
+		Message:  err.Error(),
+	}
+	var parseErr *scanner.ParseError
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &parseErr):
+		e.Kind, e.Line, e.Column, e.Message = ErrorKindParse, parseErr.Line, parseErr.Column, parseErr.Msg
+	case errors.As(err, &pathErr):
+		e.Kind = ErrorKindRead
+	}
+	return e
+}
 
-	nodes, err := scn.Scan(filePath, content)
+func (s *scanService) ScanDirectory(ctx context.Context, dirPath string, opts ScanOptions, events EventFunc) (*ScanResult, error) {
+	if err := s.checkAllowed(dirPath); err != nil {
+		return nil, err
+	}
+	src, err := dirSource(dirPath)
 	if err != nil {
 		return nil, err
 	}
+	return s.scanDirectory(ctx, src, opts, events)
+}
 
-	// Save to Repo
-	for _, node := range nodes {
-		s.repo.SaveNode(node)
+func (s *scanService) ScanGit(ctx context.Context, repo, ref string, opts ScanOptions, events EventFunc) (*ScanResult, error) {
+	dir, err := gitfs.Dir(repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAllowed(dir); err != nil {
+		return nil, err
 	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	fsys, err := gitfs.Open(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	src := source{tree: scanner.Tree{FS: fsys, Root: root}, commit: fsys.Commit()}
+	return s.scanDirectory(ctx, src, opts, events)
+}
+
+// source is a file tree to scan: a directory on disk or a git commit.
+type source struct {
+	// tree.Root is the directory on disk, or the repository of the commit.
+	tree scanner.Tree
+	// commit is the SHA of the scanned commit, empty for a directory.
+	commit string
+	// owns, when set, is the directory whose files the scan replaces in
+	// place of tree.Root: the manifest records under it that the walk did
+	// not meet are those of removed files.
+	owns string
+}
+
+// owned is the directory whose files the scan of src replaces.
+func (src source) owned() string {
+	if src.owns != "" {
+		return src.owns
+	}
+	return src.tree.Root
+}
 
-	return nodes, nil
+// uploadSource is the source of an extracted upload. Each upload is extracted
+// into a directory of its own, so an upload replaces the files of the
+// project's earlier uploads, stored under the parent directory: node IDs do
+// not depend on the directory, so the symbols they share are kept.
+func uploadSource(dir string) (source, error) {
+	src, err := dirSource(dir)
+	if err != nil {
+		return source{}, err
+	}
+	src.owns = filepath.Dir(src.tree.Root)
+	return src, nil
 }
 
-func (s *scanService) ScanDirectory(dirPath string) ([]*models.CodeNode, error) {
-	var allNodes []*models.CodeNode
+// dirSource is the source of the directory at dirPath, rooted at its
+// absolute path so that FilePaths do not depend on the working directory.
+func dirSource(dirPath string) (source, error) {
+	root, err := filepath.Abs(dirPath)
+	if err != nil {
+		return source{}, err
+	}
+	return source{tree: scanner.Tree{FS: os.DirFS(root), Root: root}}, nil
+}
 
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+// scanDirectory walks src without the allowed-roots check, which does not
+// apply to archives extracted into the temp directory. It waits for the other
+// scans of the project to end first.
+//
+// Files are compared with the project's manifest: only those added or changed
+// since the last scan are parsed, the nodes of removed ones are dropped, and
+// the linkers able to do so relink only the files a change may affect.
+func (s *scanService) scanDirectory(ctx context.Context, src source, opts ScanOptions, events EventFunc) (*ScanResult, error) {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	dirPath := src.tree.Root
+	var p Progress
+	var timings Timings
+	phase := func(name string) {
+		p.Phase = name
+		events.emit(ScanEvent{Type: EventPhase, Progress: p})
+	}
+
+	phase(PhaseScanning)
+	start := time.Now()
+	result := newScanResult()
+	manifest := s.repo.Files()
+	files, err := s.parseDirectory(ctx, src, manifest, opts, result, &p, &timings, events)
+	if err != nil {
+		return nil, err
+	}
+	timings.Parse = time.Since(start).Milliseconds()
+	if opts.Strict && result.FilesFailed > 0 {
+		result.Timings = &timings
+		return result, fmt.Errorf("%w: %d of %d files could not be scanned", ErrStrictScan, result.FilesFailed, p.FilesTotal)
+	}
+
+	// Relations spanning files are resolved before anything is stored, so
+	// readers never observe a half-linked graph.
+	phase(PhaseLinking)
+	start = time.Now()
+	tree := src.tree
+	plan, err := s.planRescan(ctx, tree, src.owned(), manifest, files, opts.Full)
+	if err != nil {
+		return nil, err
+	}
+	nodes := s.nodes(plan)
+	graph := &models.Graph{Nodes: nodes}
+	if plan.idle() {
+		graph.Edges = s.storedEdges(nodes)
+	} else if graph, err = s.link(ctx, tree, plan, nodes, result, &p, events); err != nil {
+		return nil, err
+	}
+	if src.commit != "" {
+		plan.stampCommit(graph, src.commit)
+	}
+	timings.Link = time.Since(start).Milliseconds()
+
+	// Once saving starts it runs to the end: stopping half way would leave
+	// the graph half replaced.
+	phase(PhaseSaving)
+	start = time.Now()
+	removed := s.removedNodes(plan, manifest)
+	if err := s.store(plan, graph); err != nil {
+		return nil, fmt.Errorf("store graph: %w", err)
+	}
+	for _, path := range plan.changes.Removed {
+		events.emit(ScanEvent{Type: EventFileRemoved, Progress: p, File: path, Nodes: removed[path]})
+	}
+	timings.Store = time.Since(start).Milliseconds()
+
+	p.Phase = PhaseDone
+	p.NodesFound, p.EdgesFound = len(graph.Nodes), len(graph.Edges)
+	events.emit(ScanEvent{Type: EventPhase, Progress: p, Timings: &timings})
+	changes := &plan.changes
+	changes.Relinked = len(plan.relink)
+	slog.Info("directory scanned", "dir", dirPath, "commit", src.commit, "files", p.FilesProcessed, "added", len(changes.Added),
+		"changed", len(changes.Changed), "removed", len(changes.Removed), "relinked", changes.Relinked,
+		"nodes", p.NodesFound, "edges", p.EdgesFound, "errors", p.Errors, "workers", s.concurrency(),
+		"walk_ms", timings.Walk, "parse_ms", timings.Parse, "link_ms", timings.Link, "store_ms", timings.Store)
+	result.Nodes = append(result.Nodes, graph.Nodes...)
+	result.Edges = append(result.Edges, graph.Edges...)
+	result.Count = len(result.Nodes)
+	result.Changes = changes
+	result.Commit = src.commit
+	result.Timings = &timings
+	return result, nil
+}
+
+// parsedFile is a file met during a directory scan.
+type parsedFile struct {
+	index  int    // position in walk order
+	name   string // slash-separated path in the scanned tree
+	path   string // path as the FilePath of nodes
+	info   fs.FileInfo
+	source bool               // a scanner handles the file
+	build  bool               // a build manifest, read by the linkers
+	prev   *models.FileRecord // manifest record of the previous scan, if any
+	hinted bool               // listed in ScanOptions.Hints
+	state  fileState
+	// record is the manifest record to keep, nil when the file failed.
+	record  *models.FileRecord
+	parsed  bool // nodes were just parsed
+	nodes   []*models.CodeNode
+	err     error
+	started bool // the file was just picked up by a worker; nothing else is set
+}
+
+// parseDirectory examines the files of src on a pool of workers fed by
+// a walker, parsing those that changed since their manifest record and
+// reporting each file as it is picked up and parsed. Files that fail are
+// recorded in result and skipped: the rest of the tree is still worth
+// mapping. The files are returned in walk order, whatever order they were
+// examined in, so that the graph built from them does not depend on
+// scheduling.
+func (s *scanService) parseDirectory(ctx context.Context, src source, manifest map[string]*models.FileRecord, opts ScanOptions, result *ScanResult, p *Progress, timings *Timings, events EventFunc) ([]parsedFile, error) {
+	hinted := make(map[string]bool, len(opts.Hints))
+	for _, path := range opts.Hints {
+		if abs, err := filepath.Abs(path); err == nil {
+			hinted[abs] = true
+		}
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+
+	type walk struct {
+		files int
+		stats walkStats
+		err   error
+	}
+	paths := make(chan parsedFile)
+	walked := make(chan walk, 1)
+	go func() {
+		defer close(paths)
+		n, sources := 0, 0
+		stats, err := s.walkFiles(ctx, src.tree, func(f parsedFile) error {
+			f.index = n
+			select {
+			case paths <- f:
+				n++
+				if f.source {
+					sources++
+				}
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		walked <- walk{files: sources, stats: stats, err: err}
+	}()
+
+	results := make(chan parsedFile)
+	var wg sync.WaitGroup
+	for range s.concurrency() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range paths {
+				f.prev, f.hinted = manifest[f.path], hinted[f.path]
+				started := parsedFile{index: f.index, path: f.path, started: true}
+				results <- s.examine(ctx, src, f, opts.Full, func() { results <- started })
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Every result is drained, even after a failure, so no worker is left blocked.
+	var (
+		files   []parsedFile
+		walkErr error
+	)
+	for results != nil || walked != nil {
+		select {
+		case w := <-walked:
+			walked = nil
+			timings.Walk = time.Since(start).Milliseconds()
+			if w.err != nil {
+				walkErr = w.err
+				cancel()
+				continue
+			}
+			p.FilesTotal = w.files
+			result.FilesSkipped, result.FilesUnsupported = w.stats.skipped, w.stats.unsupported
+		case f, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			if f.started {
+				events.emit(ScanEvent{Type: EventFileStarted, Progress: *p, File: f.path})
+				continue
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			files = append(files, f)
+			if f.source {
+				p.FilesProcessed++
+			}
+			switch {
+			case f.err != nil:
+				p.Errors++
+				failure := s.scanError(f.path, f.err)
+				events.emit(ScanEvent{Type: EventFileFailed, Progress: *p, File: f.path, Failure: &failure})
+			case f.parsed:
+				p.NodesFound += len(f.nodes)
+				events.emit(ScanEvent{Type: EventFileScanned, Progress: *p, File: f.path, Nodes: cloneNodes(f.nodes)})
+			}
+		}
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].index < files[j].index })
+	for _, f := range files {
+		switch {
+		case f.err != nil:
+			result.FilesFailed++
+			result.Errors = append(result.Errors, s.scanError(f.path, f.err))
+		case f.parsed:
+			result.FilesScanned++
+		}
+	}
+	return files, nil
+}
+
+// concurrency is the number of files examined at once.
+func (s *scanService) concurrency() int {
+	if s.cfg.Concurrency > 0 {
+		return s.cfg.Concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// walkStats counts the files a walk left out.
+type walkStats struct {
+	skipped     int // larger than the maximum file size
+	unsupported int // no scanner for the extension, nor a build manifest
+}
+
+// walkFiles calls fn with each file of tree that a scanner supports or that is
+// a build manifest, in walk order, leaving out the directories of
+// scanner.SkipDir and oversized files.
+func (s *scanService) walkFiles(ctx context.Context, tree scanner.Tree, fn func(f parsedFile) error) (walkStats, error) {
+	var stats walkStats
+	err := fs.WalkDir(tree.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		path := filepath.Join(tree.Root, filepath.FromSlash(name))
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			// Named by its path rather than its name in tree.FS
+			return &fs.PathError{Op: pathErr.Op, Path: path, Err: pathErr.Err}
+		}
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			// Skip hidden directories (like .git)
-			if strings.HasPrefix(info.Name(), ".") && info.Name() != "." && info.Name() != ".temp" { // Allow .temp itself if recursing?
-				// Actually standard excludes: .git, .idea via logic
-				if info.Name() == ".git" || info.Name() == ".idea" {
-					return filepath.SkipDir
-				}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && scanner.SkipDir(d.Name()) {
+				return fs.SkipDir
 			}
 			return nil
 		}
 
 		// Check extension support
-		ext := strings.ToLower(filepath.Ext(path))
-		if _, ok := s.scanners[ext]; !ok {
+		_, source := s.scanners[strings.ToLower(filepath.Ext(name))]
+		build := services.IsManifest(d.Name())
+		if !source && !build {
+			stats.unsupported++
 			return nil
 		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
+		info, err := d.Info()
+		if d.Type()&fs.ModeSymlink != 0 {
+			// A link inside the tree may lead out of the allowed roots
+			if err := s.checkAllowed(path); err != nil {
+				slog.Warn("symlink skipped", "path", path, "error", err)
+				return nil
+			}
+			if target, err := fs.Stat(tree.FS, name); err == nil {
+				info = target
+			} // else reading the dangling link fails, and is reported as such
 		}
-
-		nodes, err := s.ScanFile(path, content)
 		if err != nil {
-			// Log error but continue? Or fail? Let's log/continue logic basically by collecting err
-			// For now, strict fail or ignore?
-			// Ignoring error for individual files to allow partial success is better for "mapper"
+			return &fs.PathError{Op: "stat", Path: path, Err: err}
+		}
+		if info.IsDir() {
+			// A link to a directory, which the walk does not enter
 			return nil
 		}
-		allNodes = append(allNodes, nodes...)
-		return nil
+		if s.cfg.MaxFileSize > 0 && info.Size() > int64(s.cfg.MaxFileSize) {
+			stats.skipped++
+			return nil
+		}
+		return fn(parsedFile{name: name, path: path, info: info, source: source, build: build})
 	})
+	return stats, err
+}
 
+// saveEdges saves edges through w, dropping those whose endpoints are not stored.
+func saveEdges(w repository.GraphWriter, edges []*models.Edge) {
+	for _, edge := range edges {
+		if err := w.SaveEdge(edge); err != nil {
+			slog.Debug("edge dropped", "edge", edge.ID, "error", err)
+		}
+	}
+}
+
+// checkAllowed rejects paths outside the configured allowed roots. Symlinks
+// are resolved in the path and in the roots before they are compared, so that
+// neither a linked path nor a link inside a root leads a scan out of them.
+func (s *scanService) checkAllowed(path string) error {
+	if len(s.cfg.AllowedRoots) == 0 {
+		return nil
+	}
+	ok, err := resolvedWithin(s.cfg.AllowedRoots, path)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPathNotAllowed, path)
 	}
-	return allNodes, nil
+	return nil
 }
 
-func (s *scanService) ProcessZipUpload(file *multipart.FileHeader, destRoot string) ([]*models.CodeNode, error) {
-	// Make sure .temp exists
-	if _, err := os.Stat(destRoot); os.IsNotExist(err) {
-		if err := os.Mkdir(destRoot, os.ModePerm); err != nil {
-			return nil, err
+// resolvedWithin reports whether path lies inside one of roots once the
+// symlinks in both are resolved. A path that cannot be resolved, such as one
+// that does not exist, is compared as it is, and the error returned when it
+// lies inside a root.
+func resolvedWithin(roots []string, path string) (bool, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !within(roots, path) {
+			return false, nil
+		}
+		return false, err
+	}
+	resolved := make([]string, len(roots))
+	for i, root := range roots {
+		resolved[i] = root
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			resolved[i] = real
 		}
 	}
+	return within(resolved, real), nil
+}
 
-	// Create a unique directory for this upload
-	// "temp directory won't be deleted by default"
-	folderName := fmt.Sprintf("%s_%d", file.Filename, time.Now().Unix())
-	targetDir := filepath.Join(destRoot, folderName)
+// within reports whether path lies inside one of roots, compared as written.
+func within(roots []string, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
 
-	if err := os.Mkdir(targetDir, os.ModePerm); err != nil {
+func (s *scanService) ProcessZipUpload(ctx context.Context, file *multipart.FileHeader, destRoot string, opts ScanOptions, events EventFunc) (*ScanResult, error) {
+	targetDir, err := s.SaveUpload(file, destRoot)
+	if err != nil {
+		return nil, err
+	}
+	src, err := uploadSource(targetDir)
+	if err != nil {
 		return nil, err
 	}
+	return s.scanDirectory(ctx, src, opts, events)
+}
+
+func (s *scanService) ScanUpload(ctx context.Context, dir string, opts ScanOptions, events EventFunc) (*ScanResult, error) {
+	ok, err := resolvedWithin([]string{s.cfg.TempDir}, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotUploaded, dir)
+	}
+	src, err := uploadSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanDirectory(ctx, src, opts, events)
+}
+
+func (s *scanService) SaveUpload(file *multipart.FileHeader, destRoot string) (string, error) {
+	// Make sure the temp dir exists
+	if err := os.MkdirAll(destRoot, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	// Create a unique directory for this upload; uploads of the same archive
+	// may run concurrently as jobs.
+	// "temp directory won't be deleted by default"
+	targetDir, err := os.MkdirTemp(destRoot, fmt.Sprintf("%s_%d_*", file.Filename, time.Now().Unix()))
+	if err != nil {
+		return "", err
+	}
 
 	// Save zip file
 	zipPath := filepath.Join(targetDir, file.Filename)
 	src, err := file.Open()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer src.Close()
 
 	dst, err := os.Create(zipPath)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer dst.Close()
 
 	if _, err = io.Copy(dst, src); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Unzip
 	if err := utils.Unzip(zipPath, targetDir); err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// Scan
-	return s.ScanDirectory(targetDir)
+	return targetDir, nil
 }
 
 func (s *scanService) GetAllNodes() []*models.CodeNode {
 	return s.repo.GetAllNodes()
 }
+
+func (s *scanService) GetAllEdges(kinds ...models.EdgeKind) []*models.Edge {
+	return s.repo.GetAllEdges(kinds...)
+}
+
+func (s *scanService) GetNodeEdges(nodeID string, direction Direction, kinds ...models.EdgeKind) ([]*models.Edge, error) {
+	if _, ok := s.repo.GetNode(nodeID); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+	}
+	var edges []*models.Edge
+	if direction == DirectionOut || direction == DirectionBoth {
+		edges = append(edges, s.repo.OutEdges(nodeID, kinds...)...)
+	}
+	if direction == DirectionIn || direction == DirectionBoth {
+		edges = append(edges, s.repo.InEdges(nodeID, kinds...)...)
+	}
+	return edges, nil
+}
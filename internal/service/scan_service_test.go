@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/models"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestScanDirectoryAllowedRoots(t *testing.T) {
+	base := t.TempDir()
+	allowed := filepath.Join(base, "allowed")
+	outside := filepath.Join(base, "outside")
+	writeTree(t, allowed, map[string]string{"app/main.go": "package main\n\nfunc main() {}\n"})
+	writeTree(t, outside, map[string]string{"secret/secret.go": "package secret\n\nfunc Secret() {}\n"})
+	for link, target := range map[string]string{
+		filepath.Join(allowed, "escape"):                outside,
+		filepath.Join(allowed, "app", "linked.go"):      filepath.Join(outside, "secret", "secret.go"),
+		filepath.Join(base, "alias"):                    allowed,
+		filepath.Join(allowed, "app", "inside_link.go"): filepath.Join(allowed, "app", "main.go"),
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr error
+	}{
+		{"root", allowed, nil},
+		{"subdirectory", filepath.Join(allowed, "app"), nil},
+		{"linked root", filepath.Join(base, "alias"), nil},
+		{"outside", outside, ErrPathNotAllowed},
+		{"link out of root", filepath.Join(allowed, "escape"), ErrPathNotAllowed},
+		{"dot-dot", filepath.Join(allowed, "..", "outside"), ErrPathNotAllowed},
+		{"missing", filepath.Join(allowed, "missing"), os.ErrNotExist},
+		{"missing outside", filepath.Join(outside, "missing"), ErrPathNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{AllowedRoots: []string{allowed}})
+			_, err := scans.ScanDirectory(context.Background(), tt.dir, ScanOptions{}, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ScanDirectory(%s) error = %v, want %v", tt.dir, err, tt.wantErr)
+			}
+			for _, n := range scans.GetAllNodes() {
+				if n.Name == "Secret" {
+					t.Errorf("node read through a link out of the roots: %s", n.FilePath)
+				}
+			}
+		})
+	}
+}
+
+// panicScanner stands for a scanner with a bug.
+type panicScanner struct{}
+
+func (panicScanner) Scan(ctx context.Context, root, filePath string, content []byte) ([]*models.CodeNode, error) {
+	panic("index out of range")
+}
+
+func TestScanSurvivesPanickingScanner(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"bad.py":  "x = 1\n",
+		"good.go": "package good\n\nfunc Good() {}\n",
+	})
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	scans.(*scanService).scanners[".py"] = panicScanner{}
+
+	result, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FilesFailed != 1 || len(result.Errors) != 1 {
+		t.Fatalf("%d files failed, errors %v; want bad.py", result.FilesFailed, result.Errors)
+	}
+	if e := result.Errors[0]; e.File != filepath.Join(dir, "bad.py") || e.Kind != ErrorKindScan || !strings.Contains(e.Message, "panicked") {
+		t.Errorf("error = %+v, want a scan error of bad.py", e)
+	}
+	if result.FilesScanned != 1 {
+		t.Errorf("%d files scanned, want good.go", result.FilesScanned)
+	}
+
+	result, err = scans.ScanFile(context.Background(), filepath.Join(dir, "bad.py"), []byte("x = 1\n"), ScanOptions{})
+	if err != nil || result.FilesFailed != 1 {
+		t.Errorf("ScanFile = %+v, %v; want bad.py failed", result, err)
+	}
+}
+
+// gitCommit writes files, keyed by slash-separated path, into the worktree of
+// r at dir and commits them.
+func gitCommit(t *testing.T, r *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	writeTree(t, dir, files)
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig := &object.Signature{Name: "Dev", Email: "dev@example.com", When: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	hash, err := w.Commit("change", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestScanGit(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := gitCommit(t, r, dir, map[string]string{"api/api.go": "package api\n\nfunc Get() {}\n"})
+	if _, err := r.CreateTag("v1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := gitCommit(t, r, dir, map[string]string{"api/api.go": "package api\n\nfunc Get() {}\n\nfunc Put() {}\n", "db/db.go": "package db\n\nfunc Open() {}\n"})
+	// Uncommitted changes are not scanned.
+	writeTree(t, dir, map[string]string{"api/api.go": "package api\n\nfunc Draft() {}\n"})
+
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	tests := []struct {
+		ref     string
+		commit  plumbing.Hash
+		funcs   string
+		added   int
+		changed int
+	}{
+		{"v1", first, "Get", 1, 0},
+		{second.String()[:8], second, "Get Open Put", 1, 1},
+		{"HEAD~1", first, "Get", 0, 1},
+	}
+	for _, tt := range tests {
+		result, err := scans.ScanGit(context.Background(), "file://"+dir, tt.ref, ScanOptions{}, nil)
+		if err != nil {
+			t.Fatalf("ScanGit(%s): %v", tt.ref, err)
+		}
+		if result.Commit != tt.commit.String() {
+			t.Errorf("ScanGit(%s) commit = %s, want %s", tt.ref, result.Commit, tt.commit)
+		}
+		if len(result.Changes.Added) != tt.added || len(result.Changes.Changed) != tt.changed {
+			t.Errorf("ScanGit(%s) changes = %+v, want %d added and %d changed", tt.ref, result.Changes, tt.added, tt.changed)
+		}
+		var funcs []string
+		for _, n := range scans.GetAllNodes() {
+			if n.Metadata["commit"] != tt.commit.String() {
+				t.Errorf("ScanGit(%s): %s %s has commit %v", tt.ref, n.Type, n.Name, n.Metadata["commit"])
+			}
+			if !strings.HasPrefix(n.FilePath, dir+string(filepath.Separator)) {
+				t.Errorf("ScanGit(%s): %s is outside the repository", tt.ref, n.FilePath)
+			}
+			if n.Type == models.NodeFunction {
+				funcs = append(funcs, n.Name)
+			}
+		}
+		sort.Strings(funcs)
+		if got := strings.Join(funcs, " "); got != tt.funcs {
+			t.Errorf("ScanGit(%s) functions = %q, want %q", tt.ref, got, tt.funcs)
+		}
+	}
+
+	if _, err := scans.ScanGit(context.Background(), dir, "no-such-ref", ScanOptions{}, nil); !errors.Is(err, ErrUnknownRevision) {
+		t.Errorf("ScanGit(no-such-ref) error = %v, want ErrUnknownRevision", err)
+	}
+	if _, err := scans.ScanGit(context.Background(), t.TempDir(), "", ScanOptions{}, nil); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("ScanGit of a plain directory error = %v, want ErrNotRepository", err)
+	}
+}
+
+// failingLinker fails every link.
+type failingLinker struct{}
+
+func (failingLinker) Link(context.Context, scanner.Tree, []*models.CodeNode) (*models.Graph, error) {
+	return nil, errors.New("no links today")
+}
+
+func TestScanResultAccounting(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"good.go":     "package x\n\nfunc Good() {}\n",
+		"bad.go":      "package x\n\nfunc Bad() {\n\treturn )\n}\n",
+		"bad.py":      "def ok():\n    pass\n\ndef broken(:\n    pass\n",
+		"big.py":      "# " + strings.Repeat("x", 200) + "\n",
+		"README.md":   "# x\n",
+		"data/a.json": "{}\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "gone.py"), filepath.Join(dir, "dangling.py")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{MaxFileSize: 100})
+	scans.(*scanService).linkers = append(scans.(*scanService).linkers, failingLinker{})
+
+	result, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := []struct {
+		name      string
+		got, want int
+	}{
+		{"FilesScanned", result.FilesScanned, 1},
+		{"FilesFailed", result.FilesFailed, 3},
+		{"FilesSkipped", result.FilesSkipped, 1},
+		{"FilesUnsupported", result.FilesUnsupported, 2},
+	}
+	for _, c := range counts {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+	var got []string
+	for _, e := range result.Errors {
+		if e.File != "" {
+			e.File, _ = filepath.Rel(dir, e.File)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %d:%d", e.Kind, e.File, e.Language, e.Line, e.Column))
+	}
+	want := []string{
+		"parse bad.go go 4:9",
+		"parse bad.py python 4:11",
+		"read dangling.py python 0:0",
+		"link   0:0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got, want := result.Errors[1].String(), filepath.Join(dir, "bad.py")+`:4:11: "(" was never closed`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := result.Errors[3].String(), "service.failingLinker: no links today"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if n := len(scans.GetAllNodes()); n != 1 { // Good: failed files add nothing
+		t.Errorf("%d nodes stored, want 1", n)
+	}
+}
+
+func TestScanStrict(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"good.go": "package x\n\nfunc Good() {}\n"})
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	ctx := context.Background()
+	if _, err := scans.ScanDirectory(ctx, dir, ScanOptions{Strict: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := snapshot(t, scans)
+
+	writeTree(t, dir, map[string]string{
+		"bad.go":  "package x\n\nfunc (\n",
+		"more.go": "package x\n\nfunc More() {}\n",
+	})
+	result, err := scans.ScanDirectory(ctx, dir, ScanOptions{Strict: true}, nil)
+	if !errors.Is(err, ErrStrictScan) {
+		t.Fatalf("strict ScanDirectory error = %v, want ErrStrictScan", err)
+	}
+	if result == nil || result.FilesFailed != 1 || len(result.Errors) != 1 || result.Errors[0].Kind != ErrorKindParse {
+		t.Errorf("strict ScanDirectory result = %+v, want the failed file", result)
+	}
+	if got := snapshot(t, scans); got != want {
+		t.Errorf("a failed strict scan changed the graph:\n got %s\nwant %s", got, want)
+	}
+
+	bad := filepath.Join(dir, "bad.go")
+	content := []byte("package x\n\nfunc (\n")
+	if _, err := scans.ScanFile(ctx, bad, content, ScanOptions{Strict: true}); !errors.Is(err, ErrStrictScan) {
+		t.Errorf("strict ScanFile error = %v, want ErrStrictScan", err)
+	}
+	result, err = scans.ScanFile(ctx, bad, content, ScanOptions{})
+	if err != nil || result.FilesFailed != 1 {
+		t.Errorf("ScanFile = %+v, %v, want the file listed as failed", result, err)
+	}
+}
+
+func TestScanConcurrencyDeterministic(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range testTree {
+		files[name] = content
+	}
+	for i := 0; i < 24; i++ {
+		files[fmt.Sprintf("orders/pkg%d/pkg.go", i%4)] = "package pkg\n\nfunc Shared() {}\n"
+		files[fmt.Sprintf("orders/pkg%d/f%d.go", i%4, i)] = fmt.Sprintf("package pkg\n\nfunc F%d() { Shared() }\n\nfunc (s *S%d) M() { F%d() }\n\ntype S%d struct{}\n", i, i, i, i)
+		files[fmt.Sprintf("tools/t%d.py", i)] = fmt.Sprintf("import requests\n\nclass C%d:\n    def get(self):\n        return requests.get('http://orders/orders')\n", i)
+	}
+	files["orders/pkg0/bad.go"] = "package pkg\n\nfunc (\n"
+	files["tools/bad.py"] = "def f(:\n"
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+
+	type scan struct {
+		graph, errors string
+		scanned       int
+		phases        []string
+		timings       *Timings
+	}
+	run := func(concurrency int) scan {
+		t.Helper()
+		scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{Concurrency: concurrency})
+		var s scan
+		var done *Timings
+		result, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, func(e ScanEvent) {
+			if e.Type == EventPhase {
+				s.phases = append(s.phases, e.Progress.Phase)
+				if e.Progress.Phase == PhaseDone {
+					done = e.Timings
+				}
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs []string
+		for _, e := range result.Errors {
+			errs = append(errs, e.String())
+		}
+		s.graph, s.errors, s.scanned, s.timings = snapshot(t, scans), strings.Join(errs, "\n"), result.FilesScanned, result.Timings
+		if done == nil || result.Timings == nil || *done != *result.Timings {
+			t.Errorf("concurrency %d: timings %v in the result, %v in the done event", concurrency, result.Timings, done)
+		}
+		return s
+	}
+
+	want := run(1)
+	if want.scanned != 4+4+24*2 || !strings.Contains(want.errors, "bad.go") || !strings.Contains(want.errors, "bad.py") {
+		t.Fatalf("sequential scan: %d files, errors:\n%s", want.scanned, want.errors)
+	}
+	if got := strings.Join(want.phases, " "); got != "scanning linking saving done" {
+		t.Errorf("phases = %s", got)
+	}
+	for _, concurrency := range []int{2, 8, 0} {
+		for i := 0; i < 3; i++ {
+			got := run(concurrency)
+			if got.graph != want.graph {
+				t.Fatalf("concurrency %d gives another graph than concurrency 1:\n got %s\nwant %s", concurrency, got.graph, want.graph)
+			}
+			if got.errors != want.errors || got.scanned != want.scanned {
+				t.Errorf("concurrency %d: %d files, errors:\n%s\nwant %d files, errors:\n%s", concurrency, got.scanned, got.errors, want.scanned, want.errors)
+			}
+			if tm := got.timings; tm.Walk < 0 || tm.Parse < tm.Walk || tm.Link < 0 || tm.Store < 0 {
+				t.Errorf("concurrency %d: timings %+v", concurrency, *tm)
+			}
+		}
+	}
+}
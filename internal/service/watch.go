@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/scanner"
+)
+
+// maxWatchEvents is how many watch events are remembered for subscribers.
+const maxWatchEvents = 256
+
+// ErrNotWatching is returned when watch mode is off.
+var ErrNotWatching = errors.New("watch mode is off")
+
+// Watcher keeps the graph of a project in step with a directory on disk: it
+// scans the directory, then rescans the files changed in it once they have
+// been left alone for the debounce time.
+type Watcher interface {
+	// Run scans the directory and applies its changes until ctx is done.
+	Run(ctx context.Context) error
+	// Status describes what is watched.
+	Status() WatchStatus
+	// Events calls fn with the watch events in order, starting at sequence
+	// number from, and waits for more until fn returns false or ctx is done.
+	// Only the latest maxWatchEvents are kept; older ones are skipped.
+	Events(ctx context.Context, from int, fn func(seq int, e WatchEvent) bool) error
+}
+
+// WatchStatus describes a Watcher.
+type WatchStatus struct {
+	Dir      string `json:"dir"`
+	Project  string `json:"project"`
+	Debounce string `json:"debounce"`
+	// Ready is set once the directory has been scanned and is watched.
+	Ready bool `json:"ready"`
+}
+
+// WatchEvent is a change applied to the graph by watch mode: its Type is
+// EventFileScanned, EventFileFailed, EventFileRemoved or EventDirScanned.
+type WatchEvent struct {
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	File    string `json:"file,omitempty"`
+	// Nodes counts the nodes found in the file, removed with it, or, after a
+	// directory scan, found in the whole directory.
+	Nodes   int          `json:"nodes"`
+	Edges   int          `json:"edges,omitempty"`
+	Changes *FileChanges `json:"changes,omitempty"`
+	Failure *ScanError   `json:"failure,omitempty"`
+	Time    time.Time    `json:"time"`
+}
+
+type watcher struct {
+	projects ProjectService
+	cfg      config.WatchConfig
+	project  string
+
+	mu    sync.Mutex
+	ready bool
+	// events is the log of the latest events; first is the sequence number
+	// of events[0].
+	events []WatchEvent
+	first  int
+	// changed is closed, and replaced, whenever an event is logged.
+	changed chan struct{}
+}
+
+// NewWatcher returns a Watcher of cfg.Dir, storing its graph in the project
+// named by cfg.Project, or after the directory.
+func NewWatcher(projects ProjectService, cfg config.WatchConfig) Watcher {
+	project := cfg.Project
+	if project == "" {
+		project = slug(filepath.Base(cfg.Dir))
+	}
+	return &watcher{
+		projects: projects,
+		cfg:      cfg,
+		project:  project,
+		changed:  make(chan struct{}),
+	}
+}
+
+func (w *watcher) Run(ctx context.Context) error {
+	scans, err := w.scans()
+	if err != nil {
+		return err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+
+	// The tree is watched before it is scanned, so that no change made during
+	// the scan is missed.
+	if err := w.watchTree(fsw, w.cfg.Dir, nil); err != nil {
+		return err
+	}
+	if err := w.rescan(ctx, scans, nil); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.ready = true
+	w.mu.Unlock()
+
+	pending := make(map[string]bool)
+	var quiet <-chan time.Time // fires once no change came for the debounce time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if e.Op == fsnotify.Chmod {
+				continue
+			}
+			pending[e.Name] = true
+			if e.Has(fsnotify.Create) {
+				// Files may be created in a new directory before it is watched
+				if info, err := os.Stat(e.Name); err == nil && info.IsDir() {
+					if err := w.watchTree(fsw, e.Name, pending); err != nil {
+						slog.Warn("watch failed", "dir", e.Name, "error", err)
+					}
+				}
+			}
+			quiet = time.After(time.Duration(w.cfg.Debounce))
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been lost: the directory scan that follows the
+			// next batch finds whatever changed.
+			slog.Warn("watch error", "dir", w.cfg.Dir, "error", err)
+			quiet = time.After(time.Duration(w.cfg.Debounce))
+		case <-quiet:
+			quiet = nil
+			w.apply(ctx, scans, pending)
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// scans returns the scan service of the watched project, creating the
+// project when it does not exist.
+func (w *watcher) scans() (ScanService, error) {
+	scans, err := w.projects.Scans(w.project)
+	if !errors.Is(err, ErrProjectNotFound) {
+		return scans, err
+	}
+	_, err = w.projects.CreateProject(w.project, filepath.Base(w.cfg.Dir), "Watched directory "+w.cfg.Dir)
+	if err != nil && !errors.Is(err, ErrProjectExists) {
+		return nil, err
+	}
+	return w.projects.Scans(w.project)
+}
+
+// watchTree watches dir and the directories under it, adding the files found
+// to pending when it is not nil.
+func (w *watcher) watchTree(fsw *fsnotify.Watcher, dir string, pending map[string]bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if pending != nil {
+				pending[path] = true
+			}
+			return nil
+		}
+		if path != dir && scanner.SkipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		return fsw.Add(path)
+	})
+}
+
+// apply rescans the directory once the changes of a batch are made, telling
+// the scan which files changed. The scan only parses those that did and
+// relinks what they affect, storing the outcome at once, so readers never
+// see the changed files unlinked.
+func (w *watcher) apply(ctx context.Context, scans ScanService, pending map[string]bool) {
+	if err := w.rescan(ctx, scans, slices.Sorted(maps.Keys(pending))); err != nil && ctx.Err() == nil {
+		slog.Warn("watch rescan failed", "dir", w.cfg.Dir, "error", err)
+	}
+}
+
+// rescan scans the directory, which only parses what changed since the last
+// scan, or is listed in hints, and relinks what the changes affect. Once the
+// scan is stored, the files it parsed, failed on or found removed are
+// published, then the outcome of the scan; the files are left out of the
+// first scan.
+func (w *watcher) rescan(ctx context.Context, scans ScanService, hints []string) error {
+	var files []WatchEvent
+	record := func(e ScanEvent) {
+		switch e.Type {
+		case EventFileScanned, EventFileRemoved:
+			files = append(files, WatchEvent{Type: e.Type, File: e.File, Nodes: len(e.Nodes)})
+		case EventFileFailed:
+			files = append(files, WatchEvent{Type: e.Type, File: e.File, Failure: e.Failure})
+		}
+	}
+	if hints == nil {
+		record = nil
+	}
+	result, err := scans.ScanDirectory(ctx, w.cfg.Dir, ScanOptions{Hints: hints}, record)
+	if err != nil {
+		return fmt.Errorf("scan %s: %w", w.cfg.Dir, err)
+	}
+	for _, e := range files {
+		w.publish(e)
+	}
+	w.publish(WatchEvent{Type: EventDirScanned, Nodes: result.Count, Edges: len(result.Edges), Changes: result.Changes})
+	return nil
+}
+
+func (w *watcher) publish(e WatchEvent) {
+	e.Project, e.Time = w.project, time.Now().UTC()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = append(w.events, e)
+	if drop := len(w.events) - maxWatchEvents; drop > 0 {
+		w.events = slices.Clone(w.events[drop:])
+		w.first += drop
+	}
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+func (w *watcher) Status() WatchStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return WatchStatus{
+		Dir:      w.cfg.Dir,
+		Project:  w.project,
+		Debounce: w.cfg.Debounce.String(),
+		Ready:    w.ready,
+	}
+}
+
+func (w *watcher) Events(ctx context.Context, from int, fn func(seq int, e WatchEvent) bool) error {
+	seq := from
+	for {
+		w.mu.Lock()
+		// Events dropped from the log are skipped.
+		seq = max(seq, w.first)
+		seq = min(seq, w.first+len(w.events))
+		batch := w.events[seq-w.first:]
+		changed := w.changed
+		w.mu.Unlock()
+
+		for _, e := range batch {
+			if !fn(seq, e) {
+				return nil
+			}
+			seq++
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chinmay-sawant/gosourcemapper/internal/config"
+	"github.com/chinmay-sawant/gosourcemapper/internal/repository"
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatcherAppliesChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, testTree)
+	projects := NewProjectService(repository.NewInMemoryProjectRepository(), config.ScanConfig{})
+	w := NewWatcher(projects, config.WatchConfig{Dir: dir, Project: "watched", Debounce: config.Duration(50 * time.Millisecond)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done // before the directory is removed
+	}()
+
+	events := make(chan WatchEvent)
+	go w.Events(ctx, 0, func(_ int, e WatchEvent) bool {
+		select {
+		case events <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	// next returns the next event of type typ, skipping the others.
+	next := func(typ string) WatchEvent {
+		t.Helper()
+		for {
+			select {
+			case e := <-events:
+				if e.Type == typ {
+					return e
+				}
+			case <-ctx.Done():
+				t.Fatalf("no %s event", typ)
+			}
+		}
+	}
+
+	if e := next(EventDirScanned); e.Nodes == 0 {
+		t.Fatal("first scan found no nodes")
+	}
+
+	changed := filepath.Join(dir, "users", "main.go")
+	writeTree(t, dir, map[string]string{"users/main.go": testTree["users/main.go"] + "\nfunc extra() {}\n"})
+	if e := next(EventFileScanned); e.File != changed {
+		t.Errorf("scanned %s, want %s", e.File, changed)
+	}
+	e := next(EventDirScanned)
+	if e.Changes == nil || len(e.Changes.Changed) != 1 || e.Changes.Changed[0] != changed {
+		t.Errorf("changes = %+v, want %s changed", e.Changes, changed)
+	}
+	scans, _ := projects.Scans("watched")
+	if !hasNode(scans, "extra") {
+		t.Error("node of the added function not stored")
+	}
+
+	removed := filepath.Join(dir, "tools", "report.py")
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(EventFileRemoved); e.File != removed || e.Nodes != 2 {
+		t.Errorf("removed %s with %d nodes, want %s with 2", e.File, e.Nodes, removed)
+	}
+	next(EventDirScanned)
+	if hasNode(scans, "report") {
+		t.Error("node of the removed file still stored")
+	}
+}
+
+func hasNode(scans ScanService, name string) bool {
+	for _, n := range scans.GetAllNodes() {
+		if n.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWatchAndScanSkipTheSameDirs(t *testing.T) {
+	// A hidden root is still watched and scanned.
+	dir := filepath.Join(t.TempDir(), ".checkout")
+	writeTree(t, dir, map[string]string{
+		"main.go":                  "package main\n\nfunc main() {}\n",
+		"pkg/a.go":                 "package pkg\n\nfunc A() {}\n",
+		"pkg/__pycache__/a.py":     "def cached():\n    pass\n",
+		"vendor/dep/dep.go":        "package dep\n\nfunc Dep() {}\n",
+		"node_modules/m/m.py":      "def m():\n    pass\n",
+		".git/hooks/hook.py":       "def hook():\n    pass\n",
+		"_build/gen.go":            "package gen\n\nfunc Gen() {}\n",
+		"pkg/testdata/bad/main.go": "package main\n\nfunc (\n",
+	})
+	want := []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "pkg", "a.go")}
+
+	scans := NewScanService(repository.NewInMemoryGraphRepository(), config.ScanConfig{})
+	result, err := scans.ScanDirectory(context.Background(), dir, ScanOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Errorf("errors: %v", result.Errors)
+	}
+	if got := result.Changes.Added; !slices.Equal(got, want) {
+		t.Errorf("scanned %q, want %q", got, want)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsw.Close()
+	pending := make(map[string]bool)
+	w := NewWatcher(nil, config.WatchConfig{Dir: dir}).(*watcher)
+	if err := w.watchTree(fsw, dir, pending); err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(maps.Keys(pending)); !slices.Equal(got, want) {
+		t.Errorf("watched files %q, want %q", got, want)
+	}
+	watched := fsw.WatchList()
+	slices.Sort(watched)
+	if wantDirs := []string{dir, filepath.Join(dir, "pkg")}; !slices.Equal(watched, wantDirs) {
+		t.Errorf("watched directories %q, want %q", watched, wantDirs)
+	}
+}
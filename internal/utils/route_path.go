@@ -0,0 +1,77 @@
+package utils
+
+import "strings"
+
+// MatchRoutePath reports whether path is served by the route template tmpl.
+// Templates use the parameter syntax of the common routers: ":id" (Gin, Echo),
+// "{id}" and "{id:[0-9]+}" (chi, net/http, Spring), "*rest" and "{rest...}"
+// (catch-all). Either side may itself be a template, so two templates match
+// when they could serve the same request. Query strings and trailing slashes
+// are ignored.
+func MatchRoutePath(tmpl, path string) bool {
+	ts, ps := routeSegments(tmpl), routeSegments(path)
+	for i, t := range ts {
+		if isCatchAll(t) {
+			return true
+		}
+		if i >= len(ps) {
+			return false
+		}
+		if isCatchAll(ps[i]) {
+			return true
+		}
+		if !isParam(t) && !isParam(ps[i]) && t != ps[i] {
+			return false
+		}
+	}
+	return len(ts) == len(ps)
+}
+
+// RouteSpecificity counts the literal segments of a route template, so that
+// "/orders/new" can be preferred over "/orders/:id" when both match.
+func RouteSpecificity(tmpl string) int {
+	n := 0
+	for _, s := range routeSegments(tmpl) {
+		if !isParam(s) && !isCatchAll(s) {
+			n++
+		}
+	}
+	return n
+}
+
+// NormalizeRoutePath rewrites the parameters of a route template as "{}" so
+// that templates of different routers can be compared: "/users/:id" and
+// "/users/{userId}" both become "/users/{}".
+func NormalizeRoutePath(tmpl string) string {
+	segs := routeSegments(tmpl)
+	for i, s := range segs {
+		switch {
+		case isCatchAll(s):
+			segs[i] = "*"
+		case isParam(s):
+			segs[i] = "{}"
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+func routeSegments(p string) []string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	var segs []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+func isParam(seg string) bool {
+	return strings.HasPrefix(seg, ":") || (strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"))
+}
+
+func isCatchAll(seg string) bool {
+	return strings.HasPrefix(seg, "*") || (strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "...}"))
+}